@@ -0,0 +1,254 @@
+// Package ac3 implements Dolby Digital (AC-3) and Dolby Digital Plus
+// (E-AC-3) elementary streams.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package ac3
+
+import (
+	"github.com/audioid/audioid/errors"
+)
+
+// HeaderSize is the number of bytes of sync frame,
+// which hold sync information and the fields of bit stream information,
+// parsed by ParseHeader.
+const HeaderSize = 8
+
+var (
+	// ErrInvalidHeader is returned when bytes are not a valid sync frame header.
+	ErrInvalidHeader = errors.New("invalid ac-3 sync frame header")
+)
+
+// ACMod is an audio coding mode: arrangement of full bandwidth channels.
+type ACMod uint8
+
+const (
+	ACModDualMono ACMod = 0
+	ACModMono     ACMod = 1
+	ACModStereo   ACMod = 2
+	ACMod3_0      ACMod = 3
+	ACMod2_1      ACMod = 4
+	ACMod3_1      ACMod = 5
+	ACMod2_2      ACMod = 6
+	ACMod3_2      ACMod = 7
+)
+
+// acmodChannels are the numbers of full bandwidth channels.
+var acmodChannels = [8]uint8{2, 1, 2, 3, 3, 4, 4, 5}
+
+func (mode ACMod) String() string {
+	return [8]string{"1+1", "1/0", "2/0", "3/0", "2/1", "3/1", "2/2", "3/2"}[mode&0x7]
+}
+
+// StreamType of E-AC-3 substream.
+type StreamType uint8
+
+const (
+	StreamIndependent StreamType = 0
+	StreamDependent   StreamType = 1
+	// StreamAC3Converted is an independent stream converted from AC-3.
+	StreamAC3Converted StreamType = 2
+)
+
+// Header is parsed sync information and bit stream information (BSI)
+// of AC-3 or E-AC-3 sync frame.
+//
+// ref: https://www.atsc.org/wp-content/uploads/2015/03/A52-201212-17.pdf
+type Header struct {
+	// BSID is 8 or lower for AC-3, 9 and 10 for its low sample rate
+	// variants, and 16 for E-AC-3.
+	BSID uint8
+	// StreamType and SubstreamID are only set for E-AC-3.
+	StreamType  StreamType
+	SubstreamID uint8
+	SampleRate  uint32
+	// FrameSize is the size of the sync frame in bytes.
+	FrameSize uint32
+	// Bitrate of AC-3 in bits per second. E-AC-3 bitrate is calculated
+	// from the frame size.
+	Bitrate uint32
+	// BSMod is a bit stream mode, e.g. main audio or commentary.
+	BSMod uint8
+	ACMod ACMod
+	LFE   bool
+	// Dialnorm is dialogue level in dB from -1 to -31.
+	Dialnorm int8
+	// Blocks is the number of 256-sample audio blocks in the frame.
+	Blocks uint8
+}
+
+// bitrates of AC-3 in kbps, indexed by frame size code / 2.
+var bitrates = [19]uint32{
+	32, 40, 48, 56, 64, 80, 96, 112, 128, 160,
+	192, 224, 256, 320, 384, 448, 512, 576, 640,
+}
+
+var sampleRates = [3]uint32{48000, 44100, 32000}
+
+// eac3ReducedSampleRates are used, when E-AC-3 sample rate code is 3.
+var eac3ReducedSampleRates = [3]uint32{24000, 22050, 16000}
+
+var eac3Blocks = [4]uint8{1, 2, 3, 6}
+
+// ParseHeader parses the beginning of AC-3 or E-AC-3 sync frame.
+func ParseHeader(b []byte) (*Header, error) {
+	if len(b) < HeaderSize || b[0] != 0x0B || b[1] != 0x77 {
+		return nil, ErrInvalidHeader
+	}
+	// BSID is at the same position in both syntaxes
+	switch bsid := b[5] >> 3; {
+	case bsid <= 10:
+		return parseAC3(b)
+	case bsid <= 16:
+		return parseEAC3(b)
+	}
+	return nil, ErrInvalidHeader
+}
+
+// parseAC3 parses CRC, sample rate code, frame size code and BSI
+// up to dialnorm. Mixing levels are only present for some modes.
+func parseAC3(b []byte) (*Header, error) {
+	r := &bitReader{b: b[4:]}
+	fscod := r.read(2)
+	frmsizecod := r.read(6)
+	h := &Header{
+		BSID:   uint8(r.read(5)),
+		BSMod:  uint8(r.read(3)),
+		ACMod:  ACMod(r.read(3)),
+		Blocks: 6,
+	}
+	if fscod == 3 || frmsizecod >= 38 {
+		return nil, ErrInvalidHeader
+	}
+	if h.ACMod&0x1 != 0 && h.ACMod != ACModMono {
+		r.read(2) // cmixlev
+	}
+	if h.ACMod&0x4 != 0 {
+		r.read(2) // surmixlev
+	}
+	if h.ACMod == ACModStereo {
+		r.read(2) // dsurmod
+	}
+	h.LFE = r.read(1) == 1
+	h.Dialnorm = dialnorm(r.read(5))
+
+	kbps := bitrates[frmsizecod>>1]
+	switch fscod {
+	case 0:
+		h.FrameSize = kbps * 4
+	case 1:
+		// Frames of 44.1 kHz are padded by a word to keep the bitrate
+		h.FrameSize = (kbps*320/147 + frmsizecod&0x1) * 2
+	case 2:
+		h.FrameSize = kbps * 6
+	}
+
+	// Low sample rate variants halve the rate per version
+	shift := uint(0)
+	if h.BSID > 8 {
+		shift = uint(h.BSID - 8)
+	}
+	h.SampleRate = sampleRates[fscod] >> shift
+	h.Bitrate = kbps * 1000 >> shift
+	return h, nil
+}
+
+// parseEAC3 parses stream type, substream ID, frame size,
+// sample rate, number of blocks and BSI up to dialnorm.
+func parseEAC3(b []byte) (*Header, error) {
+	r := &bitReader{b: b[2:]}
+	h := &Header{
+		StreamType:  StreamType(r.read(2)),
+		SubstreamID: uint8(r.read(3)),
+		FrameSize:   (r.read(11) + 1) * 2,
+	}
+	if h.StreamType == 3 {
+		return nil, ErrInvalidHeader
+	}
+
+	if fscod := r.read(2); fscod == 3 {
+		fscod2 := r.read(2)
+		if fscod2 == 3 {
+			return nil, ErrInvalidHeader
+		}
+		h.SampleRate = eac3ReducedSampleRates[fscod2]
+		h.Blocks = 6
+	} else {
+		h.SampleRate = sampleRates[fscod]
+		h.Blocks = eac3Blocks[r.read(2)]
+	}
+	h.ACMod = ACMod(r.read(3))
+	h.LFE = r.read(1) == 1
+	h.BSID = uint8(r.read(5))
+	h.Dialnorm = dialnorm(r.read(5))
+	h.Bitrate = uint32(uint64(h.FrameSize) * 8 * uint64(h.SampleRate) / uint64(h.SamplesPerFrame()))
+	return h, nil
+}
+
+// dialnorm converts 5-bit code to dB, where 0 is reserved and means -31 dB.
+func dialnorm(code uint32) int8 {
+	if code == 0 {
+		return -31
+	}
+	return -int8(code)
+}
+
+// IsEnhanced reports whether the frame is E-AC-3.
+func (h *Header) IsEnhanced() bool {
+	return h.BSID > 10
+}
+
+// Codec returns "AC-3" or "E-AC-3".
+func (h *Header) Codec() string {
+	if h.IsEnhanced() {
+		return "E-AC-3"
+	}
+	return "AC-3"
+}
+
+// Channels returns the number of channels including LFE.
+func (h *Header) Channels() uint8 {
+	channels := acmodChannels[h.ACMod&0x7]
+	if h.LFE {
+		channels++
+	}
+	return channels
+}
+
+// SamplesPerFrame returns the number of samples per channel in a frame.
+func (h *Header) SamplesPerFrame() uint32 {
+	return 256 * uint32(h.Blocks)
+}
+
+// isIndependent reports whether the frame carries its own samples,
+// rather than extra channels of the preceding independent frame.
+func (h *Header) isIndependent() bool {
+	return h.StreamType != StreamDependent && h.SubstreamID == 0
+}
+
+// matches reports whether other frame header belongs to the same stream.
+func (h *Header) matches(other *Header) bool {
+	return h.IsEnhanced() == other.IsEnhanced() && h.SampleRate == other.SampleRate
+}
+
+// bitReader reads big-endian bit fields.
+type bitReader struct {
+	b   []byte
+	pos uint
+}
+
+// read returns the next n bits, or zero bits past the end of b.
+func (r *bitReader) read(n uint) uint32 {
+	var x uint32
+	for i := uint(0); i < n; i++ {
+		x <<= 1
+		if byteIndex := r.pos / 8; byteIndex < uint(len(r.b)) {
+			x |= uint32(r.b[byteIndex]>>(7-r.pos%8)) & 0x1
+		}
+		r.pos++
+	}
+	return x
+}
@@ -0,0 +1,120 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ac3
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var id3 = []byte("ID3\x03\x00\x00\x00\x00\x00\x12TIT2\x00\x00\x00\x08\x00\x00\x00ID3 Tit")
+
+// pack packs pairs of bit width and value into a frame of given size.
+func pack(size int, fields ...uint32) []byte {
+	b := make([]byte, size)
+	pos := 0
+	for i := 0; i+1 < len(fields); i += 2 {
+		width, value := int(fields[i]), fields[i+1]
+		for j := width - 1; j >= 0; j-- {
+			if value>>uint(j)&0x1 == 1 {
+				b[pos/8] |= 0x80 >> uint(pos%8)
+			}
+			pos++
+		}
+	}
+	return b
+}
+
+// ac3Frame builds 3/2 AC-3 frame at 48 kHz and 192 kbps with LFE.
+func ac3Frame() []byte {
+	return pack(768,
+		16, 0x0B77, 16, 0, // sync word and CRC
+		2, 0, 6, 20, // fscod and frmsizecod
+		5, 8, 3, 0, 3, uint32(ACMod3_2), // bsid, bsmod and acmod
+		2, 0, 2, 0, // cmixlev and surmixlev
+		1, 1, 5, 27) // lfeon and dialnorm
+}
+
+func eac3Frame(streamType StreamType, acmod ACMod) []byte {
+	return pack(1536,
+		16, 0x0B77,
+		2, uint32(streamType), 3, 0, 11, 1536/2-1, // strmtyp, substreamid and frmsiz
+		2, 0, 2, 3, // fscod and numblkscod
+		3, uint32(acmod), 1, 0, 5, 16, 5, 31) // acmod, lfeon, bsid and dialnorm
+}
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader(ac3Frame())
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if h.Codec() != "AC-3" || h.SampleRate != 48000 || h.Bitrate != 192000 || h.FrameSize != 768 {
+		t.Errorf("expected AC-3 at 48 kHz and 192 kbps, but got %+v", h)
+	}
+	if h.ACMod.String() != "3/2" || !h.LFE || h.Channels() != 6 || h.Dialnorm != -27 {
+		t.Errorf("expected 5.1 channels at -27 dB dialnorm, but got %+v", h)
+	}
+
+	// 44.1 kHz frames with odd frame size code are padded by a word
+	b := ac3Frame()
+	b[4] = 1<<6 | 21
+	if h, err := ParseHeader(b); err != nil || h.FrameSize != 836 {
+		t.Errorf("expected 836 bytes frame at 44.1 kHz, but got %+v, %v", h, err)
+	}
+
+	h, err = ParseHeader(eac3Frame(StreamIndependent, ACModStereo))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if h.Codec() != "E-AC-3" || h.Blocks != 6 || h.Channels() != 2 || h.Bitrate != 384000 || h.Dialnorm != -31 {
+		t.Errorf("expected stereo E-AC-3 at 384 kbps, but got %+v", h)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var frames [][]byte
+	for i := 0; i < 125; i++ {
+		frames = append(frames, ac3Frame())
+	}
+	file := append(id3, bytes.Join(frames, nil)...)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "ID3 Tit" {
+		t.Errorf(`expected Title from ID3 to be "ID3 Tit", but got %q`, track.Title)
+	}
+	if track.Duration != 4*time.Second {
+		t.Errorf("expected duration 4s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "AC-3" || p.Channels != 6 || p.Bitrate != 192000 || p.TotalSamples != 192000 {
+		t.Errorf("expected 4 seconds of 5.1 AC-3 at 192 kbps, but got %+v", p)
+	}
+	if h, ok := track.Extra["bsi"].(*Header); !ok || h.Dialnorm != -27 {
+		t.Errorf("expected header in Extra, but got %+v", track.Extra["bsi"])
+	}
+}
+
+func TestDecodeDependentSubstream(t *testing.T) {
+	var frames [][]byte
+	for i := 0; i < 125; i++ {
+		frames = append(frames, eac3Frame(StreamIndependent, ACMod3_2), eac3Frame(StreamDependent, ACMod2_2))
+	}
+	track, err := Decode(bytes.NewReader(bytes.Join(frames, nil)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Duration != 4*time.Second {
+		t.Errorf("expected duration 4s, but got %s", track.Duration)
+	}
+	if p := track.Properties; p.Codec != "E-AC-3" || p.Bitrate != 768000 {
+		t.Errorf("expected E-AC-3 at 768 kbps, but got %+v", p)
+	}
+}
@@ -0,0 +1,134 @@
+// Package adts implements raw AAC streams in
+// Audio Data Transport Stream (ADTS) frames.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package adts
+
+import (
+	"github.com/audioid/audioid/errors"
+)
+
+// HeaderSize is the size of ADTS header without CRC.
+const HeaderSize = 7
+
+var (
+	// ErrInvalidHeader is returned when 7 bytes are not a valid ADTS header.
+	ErrInvalidHeader = errors.New("invalid adts header")
+)
+
+// Profile is an MPEG-4 audio object type minus one.
+type Profile uint8
+
+const (
+	ProfileMain Profile = 0
+	ProfileLC   Profile = 1
+	ProfileSSR  Profile = 2
+	ProfileLTP  Profile = 3
+)
+
+func (p Profile) String() string {
+	switch p {
+	case ProfileMain:
+		return "Main"
+	case ProfileLC:
+		return "LC"
+	case ProfileSSR:
+		return "SSR"
+	case ProfileLTP:
+		return "LTP"
+	}
+	return "unknown"
+}
+
+// sampleRates are indexed by sampling frequency index.
+var sampleRates = [...]uint32{
+	96000, 88200, 64000, 48000, 44100, 32000,
+	24000, 22050, 16000, 12000, 11025, 8000, 7350,
+}
+
+// Header is a parsed ADTS frame header.
+//
+// ref: https://wiki.multimedia.cx/index.php/ADTS
+type Header struct {
+	// MPEGVersion is 4 or 2.
+	MPEGVersion uint8
+	// Protected is true when the header is followed by 16-bit CRC.
+	Protected       bool
+	Profile         Profile
+	SampleRateIndex uint8
+	SampleRate      uint32
+	Private         bool
+	// ChannelConfig is 0, when channels are defined
+	// by program config element in the stream.
+	ChannelConfig uint8
+	Original      bool
+	Home          bool
+	// FrameLength is the size of the frame including header.
+	FrameLength    uint16
+	BufferFullness uint16
+	// RawBlocks is the number of AAC frames in ADTS frame minus one.
+	RawBlocks uint8
+}
+
+// ParseHeader parses 7 bytes of b as ADTS header.
+func ParseHeader(b []byte) (*Header, error) {
+	if len(b) < HeaderSize || b[0] != 0xFF || b[1]&0xF6 != 0xF0 {
+		return nil, ErrInvalidHeader
+	}
+
+	h := &Header{
+		MPEGVersion:     4,
+		Protected:       b[1]&0x1 == 0,
+		Profile:         Profile(b[2] >> 6),
+		SampleRateIndex: b[2] >> 2 & 0xF,
+		Private:         b[2]>>1&0x1 == 1,
+		ChannelConfig:   b[2]&0x1<<2 | b[3]>>6,
+		Original:        b[3]>>5&0x1 == 1,
+		Home:            b[3]>>4&0x1 == 1,
+		FrameLength:     uint16(b[3]&0x3)<<11 | uint16(b[4])<<3 | uint16(b[5]>>5),
+		BufferFullness:  uint16(b[5]&0x1F)<<6 | uint16(b[6]>>2),
+		RawBlocks:       b[6] & 0x3,
+	}
+	if b[1]>>3&0x1 == 1 {
+		h.MPEGVersion = 2
+	}
+	if int(h.SampleRateIndex) >= len(sampleRates) {
+		return nil, ErrInvalidHeader
+	}
+	h.SampleRate = sampleRates[h.SampleRateIndex]
+
+	minLength := uint16(HeaderSize)
+	if h.Protected {
+		minLength += 2
+	}
+	if h.FrameLength < minLength {
+		return nil, ErrInvalidHeader
+	}
+	return h, nil
+}
+
+// Channels returns the number of channels of channel configuration.
+// It is 0, when channels are defined in the stream.
+func (h *Header) Channels() uint8 {
+	if h.ChannelConfig == 7 {
+		return 8
+	}
+	return h.ChannelConfig
+}
+
+// SamplesPerFrame returns the number of samples per channel in a frame.
+func (h *Header) SamplesPerFrame() uint32 {
+	return 1024 * (uint32(h.RawBlocks) + 1)
+}
+
+// matches reports whether other frame header belongs to the same stream.
+func (h *Header) matches(other *Header) bool {
+	return h.MPEGVersion == other.MPEGVersion &&
+		h.Profile == other.Profile &&
+		h.SampleRateIndex == other.SampleRateIndex &&
+		h.ChannelConfig == other.ChannelConfig
+}
@@ -0,0 +1,153 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package adts
+
+import (
+	"io"
+
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+var (
+	// ErrNoFrames is returned when no ADTS frame was found.
+	ErrNoFrames = errors.New("no adts frames found")
+)
+
+// Stream describes an ADTS stream.
+type Stream struct {
+	// Header of the first frame.
+	Header *Header
+	// Offset of the first frame from the start of file.
+	Offset int64
+	// Frames is the number of ADTS frames.
+	Frames uint32
+	// Bytes is the size of ADTS frames.
+	Bytes int64
+	// TotalSamples is the number of samples per channel.
+	TotalSamples uint64
+	// Bitrate in bits per second, averaged over all frames.
+	Bitrate uint32
+}
+
+// Decode reads ADTS stream properties, ID3v2 and ID3v1 tags
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	stream, err := ReadStream(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode adts", err)
+	}
+
+	t := &metadata.Track{}
+	stream.Apply(t)
+
+	if stream.Offset != 0 {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to id3v2 tag", err)
+		}
+		tag, err := id3v2.Read(f)
+		if err == nil {
+			tag.Apply(t)
+		} else if err != id3v2.ErrNoTag {
+			return nil, errors.Wrap("could not read id3v2 tag", err)
+		}
+	}
+
+	tag, err := id3v1.Read(f)
+	if err == nil {
+		tag.Apply(t)
+	} else if err != id3v1.ErrNoTag {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ReadStream finds the first frame of the stream and counts frames
+// up to the end of file, as ADTS has no header with stream length.
+// f must be positioned at the start of the file.
+func ReadStream(f io.ReadSeeker) (*Stream, error) {
+	start, err := id3v2.Skip(f)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+	if tag, err := id3v1.Read(f); err == nil {
+		end -= tag.Size()
+	} else if err != id3v1.ErrNoTag {
+		return nil, err
+	}
+	buf, err := frames.ReadWindow(f, start, end)
+	if err != nil {
+		return nil, err
+	}
+	i, header := frames.Find(buf, end-start)
+	if header == nil {
+		return nil, ErrNoFrames
+	}
+	h := header.(*Header)
+	stream := &Stream{
+		Header: h,
+		Offset: start + int64(i),
+	}
+	if err := stream.scan(f, end); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// frames finds and counts ADTS frames.
+var frames = &utils.FrameScanner{
+	Sync:       0xFF,
+	HeaderSize: HeaderSize,
+	Parse: func(b []byte) (interface{}, int64, error) {
+		h, err := ParseHeader(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		return h, int64(h.FrameLength), nil
+	},
+	Matches: func(first, header interface{}) bool {
+		return first.(*Header).matches(header.(*Header))
+	},
+}
+
+// scan counts frames from the first one up to end.
+func (stream *Stream) scan(f io.ReadSeeker, end int64) error {
+	pos, err := frames.Scan(f, stream.Offset, end, stream.Header, func(header interface{}) {
+		stream.TotalSamples += uint64(header.(*Header).SamplesPerFrame())
+		stream.Frames++
+	})
+	if err != nil {
+		return err
+	}
+
+	stream.Bytes = pos - stream.Offset
+	if stream.TotalSamples != 0 {
+		stream.Bitrate = uint32(uint64(stream.Bytes) * 8 * uint64(stream.Header.SampleRate) / stream.TotalSamples)
+	}
+	return nil
+}
+
+// Apply stream properties to the track.
+func (stream *Stream) Apply(t *metadata.Track) {
+	h := stream.Header
+	t.Properties = metadata.Properties{
+		Codec:        "AAC",
+		SampleRate:   h.SampleRate,
+		Channels:     h.Channels(),
+		Bitrate:      stream.Bitrate,
+		TotalSamples: stream.TotalSamples,
+	}
+	t.Duration = metadata.SamplesDuration(stream.TotalSamples, h.SampleRate)
+}
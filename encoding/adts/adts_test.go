@@ -0,0 +1,70 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package adts
+
+import (
+	"bytes"
+	"testing"
+)
+
+var id3 = []byte("ID3\x03\x00\x00\x00\x00\x00\x12TIT2\x00\x00\x00\x08\x00\x00\x00ID3 Tit")
+
+func mkframe(profile Profile, rateIndex, channels uint8, length uint16) []byte {
+	b := make([]byte, length)
+	b[0] = 0xFF
+	b[1] = 0xF1
+	b[2] = byte(profile)<<6 | rateIndex<<2 | channels>>2
+	b[3] = channels<<6 | byte(length>>11)&0x3
+	b[4] = byte(length >> 3)
+	b[5] = byte(length)<<5 | 0x1F
+	b[6] = 0xFC
+	return b
+}
+
+func TestParseHeader(t *testing.T) {
+	h, err := ParseHeader(mkframe(ProfileLC, 3, 6, 1000))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if h.MPEGVersion != 4 || h.Protected || h.Profile != ProfileLC || h.SampleRate != 48000 || h.Channels() != 6 || h.FrameLength != 1000 || h.BufferFullness != 0x7FF {
+		t.Errorf("expected 5.1 AAC LC frame at 48 kHz, but got %+v", h)
+	}
+	if _, err := ParseHeader([]byte{0xFF, 0xFB, 0x90, 0x00, 0x00, 0x00, 0x00}); err != ErrInvalidHeader {
+		t.Errorf("expected ErrInvalidHeader for MP3 frame, but got %v", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var frames [][]byte
+	for i := 0; i < 10; i++ {
+		frames = append(frames, mkframe(ProfileLC, 4, 2, 200))
+	}
+	audio := bytes.Join(frames, nil)
+	// False sync word before the first frame
+	file := bytes.Join([][]byte{id3, {0xFF, 0xF1, 0x50}, audio}, nil)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "ID3 Tit" {
+		t.Errorf(`expected Title from ID3 to be "ID3 Tit", but got %q`, track.Title)
+	}
+	p := track.Properties
+	if p.Codec != "AAC" || p.SampleRate != 44100 || p.Channels != 2 || p.TotalSamples != 10240 {
+		t.Errorf("expected 10 frames of stereo AAC at 44.1 kHz, but got %+v", p)
+	}
+	if p.Bitrate != uint32(len(audio)*8*44100/10240) {
+		t.Errorf("expected bitrate %d, but got %d", len(audio)*8*44100/10240, p.Bitrate)
+	}
+}
+
+func TestReadStreamNoFrames(t *testing.T) {
+	if _, err := ReadStream(bytes.NewReader(make([]byte, 100))); err != ErrNoFrames {
+		t.Errorf("expected ErrNoFrames, but got %v", err)
+	}
+}
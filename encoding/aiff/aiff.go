@@ -0,0 +1,146 @@
+// Package aiff implements Audio Interchange File Format
+// and its compressed variant AIFF-C.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package aiff
+
+import (
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+)
+
+// ChunkHeaderSize is the size of chunk identifier and size.
+const ChunkHeaderSize = 8
+
+var (
+	// ErrNotAIFF is returned when file is not an AIFF or AIFF-C file.
+	ErrNotAIFF = errors.New("not an aiff file")
+	// ErrNoCommon is returned when file has no COMM chunk.
+	ErrNoCommon = errors.New("no aiff common chunk")
+)
+
+// Compression is a compression type of AIFF-C.
+// Plain AIFF files always have CompressionNone.
+//
+// ref: http://www-mmsp.ece.mcgill.ca/Documents/AudioFormats/AIFF/AIFF.html
+type Compression string
+
+const (
+	CompressionNone         Compression = "NONE"
+	CompressionTwos         Compression = "twos"
+	CompressionLittleEndian Compression = "sowt"
+	CompressionFloat32      Compression = "fl32"
+	CompressionFloat64      Compression = "fl64"
+	CompressionALaw         Compression = "alaw"
+	CompressionMULaw        Compression = "ulaw"
+	CompressionIMA4         Compression = "ima4"
+)
+
+// Codec returns a short codec name, e.g. "PCM" or "IEEE float".
+func (c Compression) Codec() string {
+	switch c {
+	case CompressionNone, CompressionTwos, CompressionLittleEndian:
+		return "PCM"
+	case CompressionFloat32, "FL32", CompressionFloat64, "FL64":
+		return "IEEE float"
+	case CompressionALaw, "ALAW":
+		return "A-law"
+	case CompressionMULaw, "ULAW":
+		return "µ-law"
+	case CompressionIMA4:
+		return "IMA ADPCM"
+	}
+	return string(c)
+}
+
+// IsPCM reports whether samples are stored uncompressed.
+func (c Compression) IsPCM() bool {
+	switch c.Codec() {
+	case "PCM", "IEEE float":
+		return true
+	}
+	return false
+}
+
+// Common is a content of COMM chunk.
+type Common struct {
+	Channels uint16
+	// SampleFrames is the number of inter-channel samples.
+	SampleFrames uint32
+	SampleSize   uint16
+	// SampleRate is decoded from 80-bit IEEE 754 extended precision number.
+	SampleRate float64
+	// Compression and CompressionName are only present in AIFF-C.
+	Compression     Compression
+	CompressionName string
+}
+
+// Chunk is a chunk header of IFF file.
+type Chunk struct {
+	ID string
+	// Offset of the chunk data from the start of file.
+	Offset int64
+	// Size of the chunk data without header and padding byte.
+	Size int64
+}
+
+// Marker is a position in sound data of MARK chunk.
+type Marker struct {
+	ID int16
+	// Position is a sample frame number.
+	Position uint32
+	Name     string
+}
+
+// Loop play modes of Instrument loops.
+const (
+	LoopOff             = 0
+	LoopForward         = 1
+	LoopForwardBackward = 2
+)
+
+// Loop refers to markers, which begin and end a loop.
+type Loop struct {
+	PlayMode uint16
+	Begin    int16
+	End      int16
+}
+
+// Instrument is a content of INST chunk.
+type Instrument struct {
+	// Notes are MIDI note numbers.
+	BaseNote     uint8
+	Detune       int8
+	LowNote      uint8
+	HighNote     uint8
+	LowVelocity  uint8
+	HighVelocity uint8
+	// Gain in decibels.
+	Gain        int16
+	SustainLoop Loop
+	ReleaseLoop Loop
+}
+
+// File describes the parts of AIFF file relevant to metadata.
+type File struct {
+	// Form is either "AIFF" or "AIFC".
+	Form   string
+	Common *Common
+	// Chunks are all top-level chunks in the order of the file.
+	Chunks []*Chunk
+	// SoundSize is the size of sound data in SSND chunk.
+	SoundSize int64
+	// Text chunks.
+	Name        string
+	Author      string
+	Copyright   string
+	Annotations []string
+	Markers     []Marker
+	Instrument  *Instrument
+	// ID3 is a tag of "ID3 " chunk.
+	ID3 *id3v2.Tag
+}
@@ -0,0 +1,340 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package aiff
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// maxChunkSize is the largest COMM, text, MARK or ID3 chunk, which ReadFile loads.
+const maxChunkSize = 64 << 20
+
+// Decode reads COMM, text and ID3 chunks
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode aiff", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+	return t, nil
+}
+
+// ReadFile walks chunks of AIFF or AIFF-C file.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return nil, errors.Wrap("could not read form header", err)
+	}
+	if string(header[:4]) != "FORM" {
+		return nil, ErrNotAIFF
+	}
+	file := &File{Form: string(header[8:12])}
+	if file.Form != "AIFF" && file.Form != "AIFC" {
+		return nil, ErrNotAIFF
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+	// Trust the file size, if FORM size is broken
+	if formEnd := int64(binary.BigEndian.Uint32(header[4:8])) + 8; formEnd < end && formEnd > 12 {
+		end = formEnd
+	}
+
+	for offset := int64(12); offset+ChunkHeaderSize <= end; {
+		chunk, err := readChunkHeader(f, offset, end)
+		if err != nil {
+			return nil, err
+		}
+
+		file.Chunks = append(file.Chunks, chunk)
+		if err := file.readChunk(f, chunk); err != nil {
+			return nil, err
+		}
+
+		offset = chunk.Offset + chunk.Size + chunk.Size&1
+	}
+
+	if file.Common == nil {
+		return nil, ErrNoCommon
+	}
+	return file, nil
+}
+
+// readChunkHeader reads chunk header at given offset.
+// Size of the last chunk is truncated to the end of file.
+func readChunkHeader(f io.ReadSeeker, offset, end int64) (*Chunk, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk", err)
+	}
+	var b [ChunkHeaderSize]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return nil, errors.Wrap("could not read chunk header", err)
+	}
+	chunk := &Chunk{
+		ID:     string(b[:4]),
+		Offset: offset + ChunkHeaderSize,
+		Size:   int64(binary.BigEndian.Uint32(b[4:8])),
+	}
+	if chunk.Offset+chunk.Size > end {
+		chunk.Size = end - chunk.Offset
+	}
+	return chunk, nil
+}
+
+func (file *File) readChunk(f io.ReadSeeker, chunk *Chunk) error {
+	switch chunk.ID {
+	case "COMM":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		file.Common, err = parseCommon(b, file.Form == "AIFC")
+		return err
+	case "SSND":
+		// Sound data is preceded by offset and block size
+		var b [8]byte
+		if _, err := f.Seek(chunk.Offset, io.SeekStart); err != nil {
+			return errors.Wrap("could not seek to sound data", err)
+		}
+		if _, err := io.ReadFull(f, b[:]); err != nil {
+			return errors.Wrap("could not read sound data header", err)
+		}
+		file.SoundSize = chunk.Size - 8 - int64(binary.BigEndian.Uint32(b[0:4]))
+		if file.SoundSize < 0 {
+			file.SoundSize = 0
+		}
+	case "NAME", "AUTH", "(c) ", "ANNO":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		text := decodeText(b)
+		switch chunk.ID {
+		case "NAME":
+			file.Name = text
+		case "AUTH":
+			file.Author = text
+		case "(c) ":
+			file.Copyright = text
+		case "ANNO":
+			file.Annotations = append(file.Annotations, text)
+		}
+	case "MARK":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		file.Markers = parseMarkers(b)
+	case "INST":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		file.Instrument, err = parseInstrument(b)
+		return err
+	case "ID3 ", "id3 ":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		// A broken tag is kept as unknown chunk, COMM and text chunks are still useful
+		if tag, err := id3v2.Parse(b); err == nil {
+			file.ID3 = tag
+		}
+	}
+	return nil
+}
+
+func readChunkData(f io.ReadSeeker, chunk *Chunk) ([]byte, error) {
+	if chunk.Size > maxChunkSize {
+		return nil, errors.New("aiff chunk " + chunk.ID + " is too large")
+	}
+	if _, err := f.Seek(chunk.Offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk "+chunk.ID, err)
+	}
+	b := make([]byte, chunk.Size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read chunk "+chunk.ID, err)
+	}
+	return b, nil
+}
+
+// parseCommon parses COMM chunk. AIFF-C extends it by compression type.
+func parseCommon(b []byte, compressed bool) (*Common, error) {
+	if len(b) < 18 {
+		return nil, errors.New("aiff common chunk is too short")
+	}
+	common := &Common{
+		Channels:     binary.BigEndian.Uint16(b[0:2]),
+		SampleFrames: binary.BigEndian.Uint32(b[2:6]),
+		SampleSize:   binary.BigEndian.Uint16(b[6:8]),
+		SampleRate:   parseExtended(b[8:18]),
+		Compression:  CompressionNone,
+	}
+	if compressed && len(b) >= 22 {
+		common.Compression = Compression(b[18:22])
+		common.CompressionName, _ = parsePascalString(b[22:])
+	}
+	return common, nil
+}
+
+// parseExtended decodes 80-bit IEEE 754 extended precision number:
+// sign bit, 15-bit exponent and 64-bit mantissa with explicit integer bit.
+func parseExtended(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]))
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	sign := exponent & 0x8000
+	exponent &= 0x7FFF
+	if exponent == 0x7FFF {
+		return math.NaN()
+	}
+	value := math.Ldexp(float64(mantissa), exponent-16383-63)
+	if sign != 0 {
+		value = -value
+	}
+	return value
+}
+
+// parsePascalString parses a count byte followed by text,
+// padded to even total length. It returns the remaining bytes.
+func parsePascalString(b []byte) (string, []byte) {
+	if len(b) == 0 {
+		return "", nil
+	}
+	n := int(b[0])
+	if n+1 > len(b) {
+		n = len(b) - 1
+	}
+	s := decodeText(b[1 : n+1])
+	size := n + 1 + (n+1)&1
+	if size > len(b) {
+		size = len(b)
+	}
+	return s, b[size:]
+}
+
+// parseMarkers parses MARK chunk.
+func parseMarkers(b []byte) []Marker {
+	if len(b) < 2 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint16(b[0:2]))
+	b = b[2:]
+	markers := make([]Marker, 0, count)
+	for i := 0; i < count && len(b) >= 6; i++ {
+		marker := Marker{
+			ID:       int16(binary.BigEndian.Uint16(b[0:2])),
+			Position: binary.BigEndian.Uint32(b[2:6]),
+		}
+		marker.Name, b = parsePascalString(b[6:])
+		markers = append(markers, marker)
+	}
+	return markers
+}
+
+// parseInstrument parses INST chunk.
+func parseInstrument(b []byte) (*Instrument, error) {
+	if len(b) < 20 {
+		return nil, errors.New("aiff instrument chunk is too short")
+	}
+	return &Instrument{
+		BaseNote:     b[0],
+		Detune:       int8(b[1]),
+		LowNote:      b[2],
+		HighNote:     b[3],
+		LowVelocity:  b[4],
+		HighVelocity: b[5],
+		Gain:         int16(binary.BigEndian.Uint16(b[6:8])),
+		SustainLoop:  parseLoop(b[8:14]),
+		ReleaseLoop:  parseLoop(b[14:20]),
+	}, nil
+}
+
+func parseLoop(b []byte) Loop {
+	return Loop{
+		PlayMode: binary.BigEndian.Uint16(b[0:2]),
+		Begin:    int16(binary.BigEndian.Uint16(b[2:4])),
+		End:      int16(binary.BigEndian.Uint16(b[4:6])),
+	}
+}
+
+// decodeText decodes text chunks, which are meant to be ASCII,
+// but often carry UTF-8 or Latin-1.
+func decodeText(b []byte) string {
+	if utf8.Valid(b) {
+		return utils.TrimFixed(string(b))
+	}
+	return utils.TrimFixed(utils.DecodeLatin1(b))
+}
+
+// Apply common properties, text chunks and ID3 tag to the track.
+// ID3 is richer than text chunks, so it overrides the same fields.
+// Markers and instrument are stored in Track.Extra by their chunk identifiers.
+func (file *File) Apply(t *metadata.Track) {
+	common := file.Common
+	sampleRate := uint32(math.Round(common.SampleRate))
+	if common.SampleRate < 0 || common.SampleRate > math.MaxUint32 || math.IsNaN(common.SampleRate) {
+		sampleRate = 0
+	}
+	t.Properties = metadata.Properties{
+		Codec:        common.Compression.Codec(),
+		SampleRate:   sampleRate,
+		Channels:     uint8(common.Channels),
+		TotalSamples: uint64(common.SampleFrames),
+	}
+	t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, sampleRate)
+	if common.Compression.IsPCM() {
+		t.Properties.BitsPerSample = uint8(common.SampleSize)
+		t.Properties.Bitrate = sampleRate * uint32(common.Channels) * uint32(common.SampleSize)
+	} else if common.SampleFrames != 0 {
+		// Compressed formats have no bit depth, so bitrate is averaged over sound data
+		t.Properties.Bitrate = uint32(uint64(file.SoundSize) * 8 * uint64(sampleRate) / uint64(common.SampleFrames))
+	}
+
+	comments := map[string]string{}
+	for key, value := range map[string]string{
+		"title":     file.Name,
+		"artist":    file.Author,
+		"copyright": file.Copyright,
+		"comment":   strings.Join(file.Annotations, "\n"),
+	} {
+		if value != "" {
+			comments[key] = value
+		}
+	}
+	if len(comments) != 0 {
+		comment := &flac.VorbisComment{Comments: comments}
+		comment.Apply(t)
+	}
+
+	if file.ID3 != nil {
+		file.ID3.Apply(t)
+	}
+
+	if len(file.Markers) != 0 {
+		t.SetExtra("MARK", file.Markers)
+	}
+	if file.Instrument != nil {
+		t.SetExtra("INST", file.Instrument)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package aiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/audioid/audioid/metadata"
+)
+
+func mkchunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, ChunkHeaderSize, ChunkHeaderSize+len(body)+1)
+	copy(b, id)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(body)))
+	b = append(b, body...)
+	if len(body)&1 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func mkform(form string, chunks ...[]byte) []byte {
+	return mkchunk("FORM", []byte(form), bytes.Join(chunks, nil))
+}
+
+// common builds COMM chunk data at 44.1 kHz.
+// AIFF-C compression type and name are appended, unless empty.
+func common(channels uint16, frames uint32, sampleSize uint16, compression, name string) []byte {
+	b := make([]byte, 18)
+	binary.BigEndian.PutUint16(b[0:], channels)
+	binary.BigEndian.PutUint32(b[2:], frames)
+	binary.BigEndian.PutUint16(b[6:], sampleSize)
+	// 44100 is 0xAC44 at the top of mantissa with exponent 15
+	binary.BigEndian.PutUint16(b[8:], 16383+15)
+	binary.BigEndian.PutUint64(b[10:], 0xAC44<<48)
+	if compression != "" {
+		b = append(b, compression...)
+		b = append(b, byte(len(name)))
+		b = append(b, name...)
+		if len(name)&1 == 0 {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
+
+func TestParseExtended(t *testing.T) {
+	for _, test := range []struct {
+		b     []byte
+		value float64
+	}{
+		{[]byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0}, 44100},
+		{[]byte{0x40, 0x0F, 0xBB, 0x80, 0, 0, 0, 0, 0, 0}, 96000},
+		{[]byte{0x40, 0x0B, 0xFA, 0x00, 0, 0, 0, 0, 0, 0}, 8000},
+		{[]byte{0xC0, 0x00, 0x80, 0x00, 0, 0, 0, 0, 0, 0}, -2},
+		{make([]byte, 10), 0},
+	} {
+		if value := parseExtended(test.b); value != test.value {
+			t.Errorf("expected %x to be %v, but got %v", test.b, test.value, value)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	id3 := []byte("ID3\x03\x00\x00\x00\x00\x00\x12TIT2\x00\x00\x00\x08\x00\x00\x00ID3 Tit")
+	marks := []byte{0, 2, 0, 1, 0, 0, 0, 0, 5, 'S', 't', 'a', 'r', 't', 0, 2, 0, 0, 0x10, 0, 3, 'E', 'n', 'd'}
+	inst := []byte{60, 0xFB, 0, 127, 1, 127, 0xFF, 0xFA, 0, 1, 0, 1, 0, 2, 0, 0, 0, 0, 0, 0}
+	file := mkform("AIFF",
+		mkchunk("COMM", common(2, 44100, 16, "", "")),
+		mkchunk("NAME", []byte("Name Title")),
+		mkchunk("AUTH", []byte("Author")),
+		mkchunk("(c) ", []byte("2019 Label")),
+		mkchunk("ANNO", []byte("First")),
+		mkchunk("ANNO", []byte("Second")),
+		mkchunk("MARK", marks),
+		mkchunk("INST", inst),
+		mkchunk("SSND", make([]byte, 8+44100*4)),
+		mkchunk("ID3 ", id3),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if track.Title != "ID3 Tit" {
+		t.Errorf(`expected Title from ID3 to be "ID3 Tit", but got %q`, track.Title)
+	}
+	if track.Artist != "Author" || track.Copyright != "2019 Label" {
+		t.Errorf("expected Artist and Copyright from text chunks, but got %q and %q", track.Artist, track.Copyright)
+	}
+	if track.Comments["comment"] != "First\nSecond" {
+		t.Errorf(`expected annotations to be joined, but got %q`, track.Comments["comment"])
+	}
+	if track.Duration != time.Second {
+		t.Errorf("expected duration 1s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "PCM" || p.SampleRate != 44100 || p.BitsPerSample != 16 || p.Bitrate != 1411200 {
+		t.Errorf("expected 16-bit PCM at 44.1 kHz, but got %+v", p)
+	}
+
+	markers := track.Extra["MARK"].([]Marker)
+	if len(markers) != 2 || markers[0].Name != "Start" || markers[1].Name != "End" || markers[1].Position != 0x1000 {
+		t.Errorf("expected Start and End markers, but got %+v", markers)
+	}
+	instrument := track.Extra["INST"].(*Instrument)
+	if instrument.BaseNote != 60 || instrument.Detune != -5 || instrument.Gain != -6 || instrument.SustainLoop.End != 2 {
+		t.Errorf("expected middle C instrument with sustain loop, but got %+v", instrument)
+	}
+}
+
+func TestDecodeInvalidID3(t *testing.T) {
+	file := mkform("AIFF",
+		mkchunk("COMM", common(2, 44100, 16, "", "")),
+		mkchunk("NAME", []byte("Name Title")),
+		mkchunk("SSND", make([]byte, 8+44100*4)),
+		mkchunk("ID3 ", []byte("ID3\x09broken")),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Name Title" {
+		t.Errorf(`expected Title to be "Name Title", but got %q`, track.Title)
+	}
+	if track.Duration != time.Second {
+		t.Errorf("expected duration 1s, but got %s", track.Duration)
+	}
+}
+
+func TestDecodeCompressed(t *testing.T) {
+	file := mkform("AIFC",
+		mkchunk("FVER", []byte{0xA2, 0x80, 0x51, 0x40}),
+		mkchunk("COMM", common(1, 44100*2, 16, "ulaw", "u-law")),
+		mkchunk("SSND", make([]byte, 8+44100*2)),
+	)
+
+	f, err := ReadFile(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if f.Common.Compression != CompressionMULaw || f.Common.CompressionName != "u-law" {
+		t.Errorf("expected ulaw compression, but got %+v", f.Common)
+	}
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	p := track.Properties
+	if p.Codec != "µ-law" || p.BitsPerSample != 0 || p.Bitrate != 44100*8 || track.Duration != 2*time.Second {
+		t.Errorf("expected 2s of µ-law at 352.8 kbps, but got %+v and %s", p, track.Duration)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aiff")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.aiff")
+
+	sound := mkchunk("SSND", make([]byte, 8+44100*4))
+	file := mkform("AIFF",
+		mkchunk("COMM", common(2, 44100, 16, "", "")),
+		mkchunk("NAME", []byte("Old")),
+		sound,
+		mkchunk("ANNO", []byte("First")),
+		mkchunk("ANNO", []byte("Second")),
+		mkchunk("APPL", []byte("unknown")),
+	)
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	tags, err := NewTags(&metadata.Track{Title: "New title", Artist: "Artist", Album: "Album"})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := WriteFile(path, tags, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	written, err := ReadFile(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	var ids []string
+	for _, chunk := range written.Chunks {
+		ids = append(ids, chunk.ID)
+	}
+	// The name grows, so sound data moves
+	if x := fmt.Sprint(ids); x != "[COMM NAME SSND APPL AUTH ID3 ]" {
+		t.Errorf("expected annotations to be dropped and other chunks kept, but got %s", x)
+	}
+	if !bytes.Contains(b, sound) {
+		t.Error("expected sound data to be kept")
+	}
+
+	track, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "New title" || track.Artist != "Artist" || track.Album != "Album" || track.Comments["comment"] != "" {
+		t.Errorf("expected new text chunks and ID3 tag, but got %+v", track)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package aiff
+
+import (
+	"encoding/binary"
+	"os"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/encoding/iff"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// textChunks are identifiers of text chunks in the order they are appended.
+var textChunks = []string{"NAME", "AUTH", "(c) ", "ANNO"}
+
+// Tags are metadata chunks written by WriteFile.
+// Nil fields keep the chunks of the file as is.
+type Tags struct {
+	// Text holds values of text chunks by their identifiers:
+	// "NAME", "AUTH", "(c) " and "ANNO". Missing or empty values
+	// remove the chunks.
+	Text map[string]string
+	// ID3 is written as "ID3 " chunk.
+	ID3 *id3v2.Tag
+}
+
+// NewTags converts the track into text chunks and ID3v2.3 tag.
+// Fields without text chunks are only written to ID3.
+func NewTags(t *metadata.Track) (*Tags, error) {
+	comments := flac.TrackComments(t)
+	tags := &Tags{Text: map[string]string{
+		"NAME": comments["title"],
+		"AUTH": comments["artist"],
+		"(c) ": comments["copyright"],
+		"ANNO": comments["comment"],
+	}}
+
+	var err error
+	tags.ID3, err = id3v2.NewTag(t, 3, id3v2.EncodingLatin1)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// WriteFile replaces text and "ID3 " chunks of AIFF or AIFF-C file.
+// The first chunk of each kind is replaced in place and duplicates are dropped,
+// missing chunks are appended. Other chunks keep their order.
+// SSND chunk is not rewritten, unless preceding chunks grow, see iff.WriteFile.
+// opts may be nil.
+func WriteFile(path string, tags *Tags, opts *utils.WriteOptions) error {
+	// The chunks are read before iff.WriteFile, so the file is recovered first
+	if err := utils.RecoverFile(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	file, err := ReadFile(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]*iff.Chunk{}
+	if tags.Text != nil {
+		for _, id := range textChunks {
+			updates[id] = nil
+			if value := tags.Text[id]; value != "" {
+				updates[id] = iff.New(id, []byte(value))
+			}
+		}
+	}
+	if tags.ID3 != nil {
+		b, err := tags.ID3.Encode(0)
+		if err != nil {
+			return err
+		}
+		updates["ID3 "] = iff.New("ID3 ", b)
+	}
+
+	chunks := make([]*iff.Chunk, len(file.Chunks))
+	kinds := make([]string, len(file.Chunks))
+	for i, c := range file.Chunks {
+		chunks[i] = &iff.Chunk{ID: c.ID, Offset: c.Offset, Size: c.Size}
+		switch c.ID {
+		case "NAME", "AUTH", "(c) ", "ANNO":
+			kinds[i] = c.ID
+		case "ID3 ", "id3 ":
+			kinds[i] = "ID3 "
+		}
+	}
+
+	form := &iff.Form{ID: "FORM", Type: file.Form, Order: binary.BigEndian, Filler: "FLLR"}
+	chunks = iff.Replace(chunks, kinds, []string{"NAME", "AUTH", "(c) ", "ANNO", "ID3 "}, updates)
+	return iff.WriteFile(path, form, chunks, "SSND", opts)
+}
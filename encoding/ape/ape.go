@@ -0,0 +1,114 @@
+// Package ape implements Monkey's Audio file headers.
+// Tags of Monkey's Audio files are read by package apetag.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package ape
+
+import (
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/errors"
+)
+
+const (
+	// DescriptorSize is the size of descriptor of files since version 3.98.
+	DescriptorSize = 52
+	// HeaderSize is the size of header, which follows the descriptor.
+	HeaderSize = 24
+	// OldHeaderSize is the size of header of files before version 3.98.
+	OldHeaderSize = 32
+	// DescriptorVersion is the first version with the descriptor.
+	DescriptorVersion = 3980
+)
+
+var (
+	// ErrNotAPE is returned when file is not a Monkey's Audio file.
+	ErrNotAPE = errors.New("not a monkey's audio file")
+)
+
+// CompressionLevel of Monkey's Audio encoder.
+type CompressionLevel uint16
+
+const (
+	CompressionFast      CompressionLevel = 1000
+	CompressionNormal    CompressionLevel = 2000
+	CompressionHigh      CompressionLevel = 3000
+	CompressionExtraHigh CompressionLevel = 4000
+	CompressionInsane    CompressionLevel = 5000
+)
+
+func (level CompressionLevel) String() string {
+	switch level {
+	case CompressionFast:
+		return "fast"
+	case CompressionNormal:
+		return "normal"
+	case CompressionHigh:
+		return "high"
+	case CompressionExtraHigh:
+		return "extra high"
+	case CompressionInsane:
+		return "insane"
+	}
+	return "unknown"
+}
+
+// Format flags of the header.
+const (
+	Flag8Bit            = 1 << 0
+	FlagCRC             = 1 << 1
+	FlagHasPeakLevel    = 1 << 2
+	Flag24Bit           = 1 << 3
+	FlagHasSeekElements = 1 << 4
+	FlagCreateWAVHeader = 1 << 5
+)
+
+// Descriptor describes the layout of files since version 3.98.
+type Descriptor struct {
+	Version          uint16
+	DescriptorBytes  uint32
+	HeaderBytes      uint32
+	SeekTableBytes   uint32
+	HeaderDataBytes  uint32
+	FrameDataBytes   uint64
+	TerminatingBytes uint32
+	// MD5 of WAV header, seek table, audio frames and header.
+	// It is zero for files, which were encoded without it.
+	MD5 [16]byte
+}
+
+// Header is a normalized header of any version.
+//
+// ref: https://www.monkeysaudio.com/developers.html
+type Header struct {
+	Version          uint16
+	CompressionLevel CompressionLevel
+	FormatFlags      uint16
+	BlocksPerFrame   uint32
+	FinalFrameBlocks uint32
+	TotalFrames      uint32
+	BitsPerSample    uint16
+	Channels         uint16
+	SampleRate       uint32
+}
+
+// File describes the parts of Monkey's Audio file relevant to metadata.
+type File struct {
+	// Descriptor is nil for files before version 3.98.
+	Descriptor *Descriptor
+	Header     Header
+	// AudioSize is the size of the file without tags.
+	AudioSize int64
+	APE       *apetag.Tag
+}
+
+// TotalBlocks is the number of inter-channel samples.
+func (h *Header) TotalBlocks() uint64 {
+	if h.TotalFrames == 0 {
+		return 0
+	}
+	return uint64(h.TotalFrames-1)*uint64(h.BlocksPerFrame) + uint64(h.FinalFrameBlocks)
+}
@@ -0,0 +1,178 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ape
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// Decode reads Monkey's Audio stream properties, APE and ID3v1 tags
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode monkey's audio", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+
+	tag, err := id3v1.Read(f)
+	if err == nil {
+		tag.Apply(t)
+	} else if err != id3v1.ErrNoTag {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ReadFile reads descriptor and header of Monkey's Audio file and its APE tag.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+
+	var b [DescriptorSize + HeaderSize]byte
+	if _, err := io.ReadFull(f, b[:OldHeaderSize]); err != nil {
+		return nil, errors.Wrap("could not read header", err)
+	}
+	if string(b[:4]) != "MAC " {
+		return nil, ErrNotAPE
+	}
+
+	file := &File{}
+	version := binary.LittleEndian.Uint16(b[4:6])
+	if version >= DescriptorVersion {
+		if _, err := io.ReadFull(f, b[OldHeaderSize:DescriptorSize]); err != nil {
+			return nil, errors.Wrap("could not read descriptor", err)
+		}
+		file.Descriptor = parseDescriptor(b[:DescriptorSize])
+
+		// Descriptor may grow in future versions
+		if _, err := f.Seek(start+int64(file.Descriptor.DescriptorBytes), io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to header", err)
+		}
+		if _, err := io.ReadFull(f, b[DescriptorSize:]); err != nil {
+			return nil, errors.Wrap("could not read header", err)
+		}
+		file.Header = parseHeader(version, b[DescriptorSize:])
+	} else {
+		file.Header = parseOldHeader(b[:OldHeaderSize])
+	}
+
+	if file.Header.Channels == 0 || file.Header.SampleRate == 0 || file.Header.BlocksPerFrame == 0 {
+		return nil, errors.New("invalid monkey's audio header")
+	}
+
+	ape, end, err := apetag.ReadTrailing(f)
+	if err != nil {
+		return nil, err
+	}
+	file.APE = ape
+	file.AudioSize = end - start
+	return file, nil
+}
+
+func parseDescriptor(b []byte) *Descriptor {
+	d := &Descriptor{
+		Version:         binary.LittleEndian.Uint16(b[4:6]),
+		DescriptorBytes: binary.LittleEndian.Uint32(b[8:12]),
+		HeaderBytes:     binary.LittleEndian.Uint32(b[12:16]),
+		SeekTableBytes:  binary.LittleEndian.Uint32(b[16:20]),
+		HeaderDataBytes: binary.LittleEndian.Uint32(b[20:24]),
+		FrameDataBytes: uint64(binary.LittleEndian.Uint32(b[24:28])) |
+			uint64(binary.LittleEndian.Uint32(b[28:32]))<<32,
+		TerminatingBytes: binary.LittleEndian.Uint32(b[32:36]),
+	}
+	copy(d.MD5[:], b[36:52])
+	if d.DescriptorBytes < DescriptorSize {
+		d.DescriptorBytes = DescriptorSize
+	}
+	return d
+}
+
+// parseHeader parses header, which follows the descriptor.
+func parseHeader(version uint16, b []byte) Header {
+	return Header{
+		Version:          version,
+		CompressionLevel: CompressionLevel(binary.LittleEndian.Uint16(b[0:2])),
+		FormatFlags:      binary.LittleEndian.Uint16(b[2:4]),
+		BlocksPerFrame:   binary.LittleEndian.Uint32(b[4:8]),
+		FinalFrameBlocks: binary.LittleEndian.Uint32(b[8:12]),
+		TotalFrames:      binary.LittleEndian.Uint32(b[12:16]),
+		BitsPerSample:    binary.LittleEndian.Uint16(b[16:18]),
+		Channels:         binary.LittleEndian.Uint16(b[18:20]),
+		SampleRate:       binary.LittleEndian.Uint32(b[20:24]),
+	}
+}
+
+// parseOldHeader parses header of files before version 3.98,
+// which has neither bit depth nor frame size.
+func parseOldHeader(b []byte) Header {
+	h := Header{
+		Version:          binary.LittleEndian.Uint16(b[4:6]),
+		CompressionLevel: CompressionLevel(binary.LittleEndian.Uint16(b[6:8])),
+		FormatFlags:      binary.LittleEndian.Uint16(b[8:10]),
+		Channels:         binary.LittleEndian.Uint16(b[10:12]),
+		SampleRate:       binary.LittleEndian.Uint32(b[12:16]),
+		TotalFrames:      binary.LittleEndian.Uint32(b[24:28]),
+		FinalFrameBlocks: binary.LittleEndian.Uint32(b[28:32]),
+		BitsPerSample:    16,
+	}
+
+	switch {
+	case h.FormatFlags&Flag8Bit != 0:
+		h.BitsPerSample = 8
+	case h.FormatFlags&Flag24Bit != 0:
+		h.BitsPerSample = 24
+	}
+
+	switch {
+	case h.Version >= 3950:
+		h.BlocksPerFrame = 73728 * 4
+	case h.Version >= 3900 || (h.Version >= 3800 && h.CompressionLevel == CompressionExtraHigh):
+		h.BlocksPerFrame = 73728
+	default:
+		h.BlocksPerFrame = 9216
+	}
+	return h
+}
+
+// Apply stream properties and APE tag to the track.
+func (file *File) Apply(t *metadata.Track) {
+	h := file.Header
+	t.Properties = metadata.Properties{
+		Codec:         "Monkey's Audio",
+		SampleRate:    h.SampleRate,
+		Channels:      uint8(h.Channels),
+		BitsPerSample: uint8(h.BitsPerSample),
+		TotalSamples:  h.TotalBlocks(),
+	}
+	t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, h.SampleRate)
+	if t.Properties.TotalSamples != 0 {
+		t.Properties.Bitrate = uint32(uint64(file.AudioSize) * 8 * uint64(h.SampleRate) / t.Properties.TotalSamples)
+	}
+
+	// The hash covers sections of the file, not decoded audio like MD5 of FLAC,
+	// so it is not comparable with Checksum of other formats
+	if d := file.Descriptor; d != nil && d.MD5 != [16]byte{} {
+		t.SetExtra("ape_file_md5", fmt.Sprintf("%x", d.MD5))
+	}
+
+	if file.APE != nil {
+		file.APE.Apply(t)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ape
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/audioid/audioid/metadata"
+)
+
+func mkdescriptor(md5 string) []byte {
+	b := make([]byte, DescriptorSize+HeaderSize)
+	copy(b, "MAC ")
+	binary.LittleEndian.PutUint16(b[4:], 3990)
+	binary.LittleEndian.PutUint32(b[8:], DescriptorSize)
+	binary.LittleEndian.PutUint32(b[12:], HeaderSize)
+	copy(b[36:], md5)
+
+	h := b[DescriptorSize:]
+	binary.LittleEndian.PutUint16(h[0:], uint16(CompressionHigh))
+	binary.LittleEndian.PutUint32(h[4:], 73728*4)
+	binary.LittleEndian.PutUint32(h[8:], 44100*3)
+	binary.LittleEndian.PutUint32(h[12:], 1)
+	binary.LittleEndian.PutUint16(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[18:], 2)
+	binary.LittleEndian.PutUint32(h[20:], 44100)
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	item := append([]byte{6, 0, 0, 0, 0, 0, 0, 0}, "Artist\x00Monkey"...)
+	footer := make([]byte, 32)
+	copy(footer, "APETAGEX")
+	binary.LittleEndian.PutUint32(footer[8:], 2000)
+	binary.LittleEndian.PutUint32(footer[12:], uint32(len(item)+32))
+	binary.LittleEndian.PutUint32(footer[16:], 1)
+
+	audio := append(mkdescriptor("0123456789abcdef"), make([]byte, 1000)...)
+	file := bytes.Join([][]byte{audio, item, footer}, nil)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Artist != "Monkey" {
+		t.Errorf(`expected Artist from APE tag to be "Monkey", but got %q`, track.Artist)
+	}
+	if track.Duration != 3*time.Second {
+		t.Errorf("expected duration 3s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.SampleRate != 44100 || p.Channels != 2 || p.BitsPerSample != 16 || p.TotalSamples != 44100*3 {
+		t.Errorf("expected 3 seconds of 16-bit stereo at 44.1 kHz, but got %+v", p)
+	}
+	if p.Bitrate != uint32(len(audio)*8/3) {
+		t.Errorf("expected bitrate of %d bytes per 3 seconds, but got %d", len(audio), p.Bitrate)
+	}
+	if track.Checksum.Algorithm != metadata.AlgoUnknown {
+		t.Errorf("expected no audio checksum, but got %+v", track.Checksum)
+	}
+	if sum := track.Extra["ape_file_md5"]; sum != "30313233343536373839616263646566" {
+		t.Errorf("expected file md5 from descriptor, but got %v", sum)
+	}
+}
+
+func TestReadFileOld(t *testing.T) {
+	b := make([]byte, OldHeaderSize+100)
+	copy(b, "MAC ")
+	binary.LittleEndian.PutUint16(b[4:], 3800)
+	binary.LittleEndian.PutUint16(b[6:], uint16(CompressionExtraHigh))
+	binary.LittleEndian.PutUint16(b[8:], Flag24Bit)
+	binary.LittleEndian.PutUint16(b[10:], 1)
+	binary.LittleEndian.PutUint32(b[12:], 48000)
+	binary.LittleEndian.PutUint32(b[24:], 3)
+	binary.LittleEndian.PutUint32(b[28:], 1000)
+
+	file, err := ReadFile(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	h := file.Header
+	if file.Descriptor != nil || h.BitsPerSample != 24 || h.BlocksPerFrame != 73728 || h.TotalBlocks() != 2*73728+1000 {
+		t.Errorf("expected 24-bit extra high 3.80 header, but got %+v", h)
+	}
+	if h.CompressionLevel.String() != "extra high" {
+		t.Errorf(`expected compression level "extra high", but got %q`, h.CompressionLevel)
+	}
+
+	if _, err := ReadFile(bytes.NewReader(make([]byte, 64))); err != ErrNotAPE {
+		t.Errorf("expected ErrNotAPE, but got %v", err)
+	}
+}
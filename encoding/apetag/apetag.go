@@ -0,0 +1,134 @@
+// Package apetag implements APEv1 and APEv2 tags.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package apetag
+
+import (
+	"strings"
+
+	"github.com/audioid/audioid/errors"
+)
+
+const (
+	// FooterSize is the size of APE tag header and footer.
+	FooterSize = 32
+	// Preamble starts APE tag header and footer.
+	Preamble = "APETAGEX"
+)
+
+// Versions of APE tag.
+const (
+	Version1 = 1000
+	Version2 = 2000
+)
+
+// Flags of APE tag header and footer.
+const (
+	FlagHasHeader = 1 << 31
+	FlagNoFooter  = 1 << 30
+	FlagIsHeader  = 1 << 29
+)
+
+var (
+	// ErrNoTag is returned when file has no APE tag.
+	ErrNoTag = errors.New("no ape tag")
+	// ErrInvalidTag is returned when APE tag is malformed.
+	ErrInvalidTag = errors.New("invalid ape tag")
+)
+
+// ItemType is a type of item value stored in bits 1-2 of item flags.
+type ItemType uint8
+
+const (
+	// ItemText is UTF-8 text. Multiple values are separated by NUL.
+	ItemText ItemType = 0
+	// ItemBinary is binary data, e.g. cover art.
+	ItemBinary ItemType = 1
+	// ItemLink is UTF-8 link to external information.
+	ItemLink ItemType = 2
+)
+
+func (typ ItemType) String() string {
+	switch typ {
+	case ItemText:
+		return "text"
+	case ItemBinary:
+		return "binary"
+	case ItemLink:
+		return "link"
+	}
+	return "reserved"
+}
+
+// ItemReadOnly flag marks items, which should not be modified.
+const ItemReadOnly = 1
+
+// Footer is APE tag header or footer, which have the same layout.
+type Footer struct {
+	Version uint32
+	// Size of items and footer, but not header.
+	Size      uint32
+	ItemCount uint32
+	Flags     uint32
+}
+
+// Item is a key and value of APE tag.
+type Item struct {
+	// Key is case-insensitive ASCII.
+	Key   string
+	Flags uint32
+	Value []byte
+}
+
+// Tag is APEv1 or APEv2 tag.
+//
+// ref: https://wiki.hydrogenaud.io/index.php?title=APEv2_specification
+type Tag struct {
+	Footer
+	// Offset of the tag including its header from the start of file.
+	Offset int64
+	Items  []*Item
+}
+
+// Type of the item value. APEv1 only has text items.
+func (item *Item) Type() ItemType {
+	return ItemType(item.Flags >> 1 & 3)
+}
+
+// IsReadOnly reports whether the item should not be modified.
+func (item *Item) IsReadOnly() bool {
+	return item.Flags&ItemReadOnly != 0
+}
+
+// Strings returns multiple text values, which are separated by NUL.
+func (item *Item) Strings() []string {
+	return strings.Split(strings.TrimRight(string(item.Value), "\x00"), "\x00")
+}
+
+// String returns text value. Multiple values are joined with "; ".
+func (item *Item) String() string {
+	return strings.Join(item.Strings(), "; ")
+}
+
+// TotalSize is the size of the tag including its header.
+func (tag *Tag) TotalSize() int64 {
+	size := int64(tag.Size)
+	if tag.Flags&FlagHasHeader != 0 {
+		size += FooterSize
+	}
+	return size
+}
+
+// Item returns the first item with given case-insensitive key.
+func (tag *Tag) Item(key string) *Item {
+	for _, item := range tag.Items {
+		if strings.EqualFold(item.Key, key) {
+			return item
+		}
+	}
+	return nil
+}
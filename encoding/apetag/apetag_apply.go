@@ -0,0 +1,141 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package apetag
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// itemKeys maps lowercased APE item keys to Vorbis comment keys,
+// when they differ. Other text items keep their lowercased keys.
+//
+// ref: https://wiki.hydrogenaud.io/index.php?title=APE_key
+var itemKeys = map[string]string{
+	"year":            "date",
+	"album artist":    "albumartist",
+	"publisher":       "organization",
+	"label":           "organization",
+	"record location": "location",
+	"debut album":     "originalalbum",
+	"mixartist":       "remixer",
+}
+
+// coverPrefix starts keys of binary cover art items, e.g. "Cover Art (Front)".
+const coverPrefix = "cover art ("
+
+// Comments converts text items into Vorbis comment keys and values.
+// Multiple values are joined with "; ".
+// Binary items and external links are skipped, see Picture and Links.
+func (tag *Tag) Comments() map[string]string {
+	comments := map[string]string{}
+	for _, item := range tag.Items {
+		if item.Type() != ItemText {
+			continue
+		}
+		value := item.String()
+		if value == "" {
+			continue
+		}
+
+		key := strings.ToLower(item.Key)
+		switch key {
+		case "track":
+			utils.SetPair(comments, "tracknumber", "tracktotal", value)
+		case "disc":
+			utils.SetPair(comments, "discnumber", "disctotal", value)
+		default:
+			if mapped, ok := itemKeys[key]; ok {
+				key = mapped
+			}
+			comments[key] = value
+		}
+	}
+	return comments
+}
+
+// Picture converts cover art item into *metadata.Picture.
+// Binary cover art is a file name terminated by NUL followed by image data,
+// and linked cover art is a URL. Other items return nil.
+func (item *Item) Picture() *metadata.Picture {
+	key := strings.ToLower(item.Key)
+	if !strings.HasPrefix(key, coverPrefix) {
+		return nil
+	}
+	description := strings.TrimSuffix(item.Key[len(coverPrefix):], ")")
+
+	switch item.Type() {
+	case ItemBinary:
+		data := item.Value
+		if n := bytes.IndexByte(data, 0); n >= 0 {
+			description = string(data[:n])
+			data = data[n+1:]
+		}
+		return &metadata.Picture{
+			MIME:        detectMIME(data),
+			Description: description,
+			Data:        data,
+		}
+	case ItemLink:
+		return &metadata.Picture{
+			MIME:          "-->",
+			Description:   description,
+			Data:          item.Value,
+			IsPictureLink: true,
+		}
+	}
+	return nil
+}
+
+// detectMIME returns MIME type of cover art by its magic bytes,
+// as binary items do not store it.
+func detectMIME(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xFF\xD8\xFF")):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1A\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return "image/gif"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return "image/bmp"
+	}
+	return "application/octet-stream"
+}
+
+// Links returns external-link items, which are not cover art,
+// e.g. "Related" or "Buy URL". Items keep their keys and flags.
+func (tag *Tag) Links() []*Item {
+	var links []*Item
+	for _, item := range tag.Items {
+		if item.Type() == ItemLink && !strings.HasPrefix(strings.ToLower(item.Key), coverPrefix) {
+			links = append(links, item)
+		}
+	}
+	return links
+}
+
+// Apply current Tag to the track.
+// External links, which are not cover art, are stored
+// in Track.Extra under "links" key as []*Item.
+func (tag *Tag) Apply(t *metadata.Track) {
+	comment := &flac.VorbisComment{Comments: tag.Comments()}
+	comment.Apply(t)
+
+	for _, item := range tag.Items {
+		if pic := item.Picture(); pic != nil {
+			t.Pictures = append(t.Pictures, *pic)
+		}
+	}
+	if links := tag.Links(); len(links) != 0 {
+		t.SetExtra("links", links)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package apetag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// maxTagSize limits the size of APE tag, which is read into memory.
+const maxTagSize = 64 << 20
+
+// Decode reads APE tag of f into *metadata.Track.
+// Duration is unknown, because APE tag does not describe audio.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	tag, err := Read(f)
+	if err != nil {
+		return nil, err
+	}
+	t := &metadata.Track{Duration: -1}
+	tag.Apply(t)
+	return t, nil
+}
+
+// Read finds APE tag by its footer at the end of f, which may be followed
+// by ID3v1 tag with optional "TAG+" tag, or by its header at the start of f.
+// ErrNoTag is returned if f has no APE tag.
+// Position of f is restored after reading.
+func Read(f io.ReadSeeker) (*Tag, error) {
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+	defer f.Seek(pos, io.SeekStart)
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+
+	if tag, err := ReadAt(f, end); err != ErrNoTag {
+		return tag, err
+	}
+
+	if v1, err := id3v1.Read(f); err == nil {
+		if tag, err := ReadAt(f, end-v1.Size()); err != ErrNoTag {
+			return tag, err
+		}
+	}
+
+	return readHeader(f, 0)
+}
+
+// ReadAt reads APE tag, which footer ends at given offset.
+// ErrNoTag is returned if there is no APE tag footer.
+// Unlike Read, it leaves f positioned after the tag items.
+func ReadAt(f io.ReadSeeker, end int64) (*Tag, error) {
+	footer, err := readFooter(f, end-FooterSize)
+	if err != nil {
+		return nil, err
+	}
+	if footer.Flags&FlagIsHeader != 0 || int64(footer.Size) > end || footer.Size < FooterSize {
+		return nil, ErrInvalidTag
+	}
+
+	tag := &Tag{Footer: *footer, Offset: end - int64(footer.Size)}
+	if footer.Flags&FlagHasHeader != 0 {
+		tag.Offset -= FooterSize
+	}
+	if err := tag.readItems(f, end-int64(footer.Size), int64(footer.Size)-FooterSize); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// ReadTrailing reads APE tag at the end of f, which may be followed
+// by ID3v1 tag, and returns it with the offset, where audio data ends,
+// i.e. where the trailing tags start. The tag is nil, if there is none,
+// or if it is broken, so that decoders still read the audio properties.
+func ReadTrailing(f io.ReadSeeker) (*Tag, int64, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, errors.Wrap("could not seek to the end", err)
+	}
+	if v1, err := id3v1.Read(f); err == nil {
+		end -= v1.Size()
+	} else if err != id3v1.ErrNoTag {
+		return nil, 0, err
+	}
+
+	tag, err := ReadAt(f, end)
+	switch err {
+	case nil:
+		return tag, tag.Offset, nil
+	case ErrNoTag, ErrInvalidTag:
+		return nil, end, nil
+	}
+	return nil, 0, errors.Wrap("could not read ape tag", err)
+}
+
+// readHeader reads APEv2 tag, which starts with a header at given offset.
+func readHeader(f io.ReadSeeker, offset int64) (*Tag, error) {
+	header, err := readFooter(f, offset)
+	if err != nil {
+		return nil, err
+	}
+	if header.Flags&FlagIsHeader == 0 {
+		return nil, ErrNoTag
+	}
+
+	tag := &Tag{Footer: *header, Offset: offset}
+	itemsSize := int64(header.Size)
+	if header.Flags&FlagNoFooter == 0 {
+		if itemsSize < FooterSize {
+			return nil, ErrInvalidTag
+		}
+		itemsSize -= FooterSize
+	}
+	if err := tag.readItems(f, offset+FooterSize, itemsSize); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// readFooter reads and validates APE tag header or footer at given offset.
+func readFooter(f io.ReadSeeker, offset int64) (*Footer, error) {
+	if offset < 0 {
+		return nil, ErrNoTag
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to ape tag", err)
+	}
+	var b [FooterSize]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNoTag
+		}
+		return nil, errors.Wrap("could not read ape tag", err)
+	}
+	if string(b[:8]) != Preamble {
+		return nil, ErrNoTag
+	}
+	footer := &Footer{
+		Version:   binary.LittleEndian.Uint32(b[8:12]),
+		Size:      binary.LittleEndian.Uint32(b[12:16]),
+		ItemCount: binary.LittleEndian.Uint32(b[16:20]),
+		Flags:     binary.LittleEndian.Uint32(b[20:24]),
+	}
+	// APEv1 has neither flags nor header
+	if footer.Version < Version2 {
+		footer.Flags = 0
+	}
+	return footer, nil
+}
+
+func (tag *Tag) readItems(f io.ReadSeeker, offset, size int64) error {
+	if size > maxTagSize {
+		return errors.New("ape tag is too large")
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return errors.Wrap("could not seek to ape tag items", err)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return errors.Wrap("could not read ape tag items", err)
+	}
+
+	items, err := parseItems(b, int(tag.ItemCount))
+	if err != nil {
+		return err
+	}
+	if tag.Version < Version2 {
+		// APEv1 items are text only
+		for _, item := range items {
+			item.Flags = 0
+		}
+	}
+	tag.Items = items
+	return nil
+}
+
+// parseItems parses items: value size, flags, NUL-terminated key and value.
+func parseItems(b []byte, count int) ([]*Item, error) {
+	if count > len(b)/9 {
+		return nil, ErrInvalidTag
+	}
+	items := make([]*Item, 0, count)
+	for i := 0; i < count; i++ {
+		if len(b) < 9 {
+			return nil, ErrInvalidTag
+		}
+		size := binary.LittleEndian.Uint32(b[0:4])
+		item := &Item{Flags: binary.LittleEndian.Uint32(b[4:8])}
+		b = b[8:]
+
+		n := bytes.IndexByte(b, 0)
+		if n < 0 || uint64(n)+1+uint64(size) > uint64(len(b)) {
+			return nil, ErrInvalidTag
+		}
+		item.Key = string(b[:n])
+		b = b[n+1:]
+		item.Value = b[:size:size]
+		b = b[size:]
+		items = append(items, item)
+	}
+	return items, nil
+}
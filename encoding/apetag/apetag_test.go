@@ -0,0 +1,207 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package apetag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func mkitem(key string, flags uint32, value string) []byte {
+	b := make([]byte, 8, 8+len(key)+1+len(value))
+	binary.LittleEndian.PutUint32(b[0:], uint32(len(value)))
+	binary.LittleEndian.PutUint32(b[4:], flags)
+	b = append(b, key...)
+	b = append(b, 0)
+	return append(b, value...)
+}
+
+func mkfooter(version, size, count, flags uint32) []byte {
+	b := make([]byte, FooterSize)
+	copy(b, Preamble)
+	binary.LittleEndian.PutUint32(b[8:], version)
+	binary.LittleEndian.PutUint32(b[12:], size)
+	binary.LittleEndian.PutUint32(b[16:], count)
+	binary.LittleEndian.PutUint32(b[20:], flags)
+	return b
+}
+
+// mktag builds APEv2 tag with header and footer.
+func mktag(items ...[]byte) []byte {
+	body := bytes.Join(items, nil)
+	size := uint32(len(body) + FooterSize)
+	count := uint32(len(items))
+	return bytes.Join([][]byte{
+		mkfooter(Version2, size, count, FlagHasHeader|FlagIsHeader),
+		body,
+		mkfooter(Version2, size, count, FlagHasHeader),
+	}, nil)
+}
+
+var jpeg = "\xff\xd8\xff\xe0\x00\x10JFIF\x00"
+
+func TestReadWithID3v1(t *testing.T) {
+	tag := mktag(
+		mkitem("Title", 0, "Title"),
+		mkitem("ARTIST", 0, "One\x00Two"),
+		mkitem("Track", 0, "3/12"),
+		mkitem("Year", ItemReadOnly, "2001"),
+		mkitem("REPLAYGAIN_TRACK_GAIN", 0, "-6.50 dB"),
+		mkitem("Cover Art (Front)", 1<<1, "cover.jpg\x00"+jpeg),
+		mkitem("Cover Art (Back)", 2<<1, "http://example.com/back.png"),
+		mkitem("Buy URL", 2<<1|ItemReadOnly, "http://example.com/buy"),
+	)
+	id3 := make([]byte, 128)
+	copy(id3, "TAG")
+	file := bytes.Join([][]byte{[]byte("audio"), tag, id3}, nil)
+
+	f := bytes.NewReader(file)
+	f.Seek(2, 0)
+	ape, err := Read(f)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if pos, _ := f.Seek(0, 1); pos != 2 {
+		t.Errorf("expected position to be restored, but got %d", pos)
+	}
+	if ape.Offset != 5 || ape.TotalSize() != int64(len(tag)) {
+		t.Errorf("expected tag at 5 of size %d, but got %d of size %d", len(tag), ape.Offset, ape.TotalSize())
+	}
+	if item := ape.Item("year"); item == nil || !item.IsReadOnly() || item.Type() != ItemText {
+		t.Errorf("expected read-only text item Year, but got %+v", item)
+	}
+
+	track, err := Decode(f)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Title" || track.Artist != "One; Two" || track.Date != "2001" {
+		t.Errorf("expected title, artist and date, but got %q, %q and %q", track.Title, track.Artist, track.Date)
+	}
+	if track.TrackNumber != "3" || track.Comments["tracktotal"] != "12" {
+		t.Errorf("expected track 3 of 12, but got %q of %q", track.TrackNumber, track.Comments["tracktotal"])
+	}
+	if x := track.Comments["replaygain_track_gain"]; x != "-6.50 dB" {
+		t.Errorf(`expected Comments[replaygain_track_gain] to be "-6.50 dB", but got %q`, x)
+	}
+
+	if len(track.Pictures) != 2 {
+		t.Fatalf("expected 2 pictures, but got %d", len(track.Pictures))
+	}
+	front, back := track.Pictures[0], track.Pictures[1]
+	if front.MIME != "image/jpeg" || front.Description != "cover.jpg" || string(front.Data) != jpeg {
+		t.Errorf("expected front cover jpeg, but got %q %q", front.MIME, front.Description)
+	}
+	if !back.IsPictureLink || back.Description != "Back" || string(back.Data) != "http://example.com/back.png" {
+		t.Errorf("expected back cover link, but got %+v", back)
+	}
+
+	links, _ := track.Extra["links"].([]*Item)
+	if len(links) != 1 || links[0].Key != "Buy URL" || !links[0].IsReadOnly() || string(links[0].Value) != "http://example.com/buy" {
+		t.Errorf("expected read-only Buy URL link, but got %+v", links)
+	}
+}
+
+func TestReadWithEnhancedID3v1(t *testing.T) {
+	tag := mktag(mkitem("Title", 0, "Title"))
+	enhanced := make([]byte, 227)
+	copy(enhanced, "TAG+")
+	id3 := make([]byte, 128)
+	copy(id3, "TAG")
+	file := bytes.Join([][]byte{[]byte("audio"), tag, enhanced, id3}, nil)
+
+	ape, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if ape.Offset != 5 || ape.Item("title").String() != "Title" {
+		t.Errorf(`expected tag at 5 with title "Title", but got %d`, ape.Offset)
+	}
+}
+
+func TestReadV1(t *testing.T) {
+	item := mkitem("Album", 1<<1, "Album")
+	file := bytes.Join([][]byte{
+		[]byte("audio"),
+		item,
+		mkfooter(Version1, uint32(len(item)+FooterSize), 1, FlagHasHeader),
+	}, nil)
+
+	ape, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if ape.Offset != 5 || ape.Flags != 0 {
+		t.Errorf("expected APEv1 tag without header at 5, but got %d with flags %x", ape.Offset, ape.Flags)
+	}
+	if ape.Items[0].Type() != ItemText || ape.Items[0].String() != "Album" {
+		t.Errorf("expected APEv1 item to be text, but got %s", ape.Items[0].Type())
+	}
+}
+
+func TestReadHeaderOnly(t *testing.T) {
+	item := mkitem("Title", 0, "Leading")
+	file := bytes.Join([][]byte{
+		mkfooter(Version2, uint32(len(item)), 1, FlagHasHeader|FlagNoFooter|FlagIsHeader),
+		item,
+		[]byte("audio"),
+	}, nil)
+
+	ape, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if ape.Item("title").String() != "Leading" {
+		t.Errorf(`expected title "Leading", but got %q`, ape.Item("title").String())
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	file := append([]byte("audio"), mkfooter(Version2, FooterSize+4, 1000000, 0)...)
+	if _, err := Read(bytes.NewReader(file)); err != ErrInvalidTag {
+		t.Errorf("expected ErrInvalidTag, but got %v", err)
+	}
+	if _, err := Read(bytes.NewReader([]byte("no tag here at all, just audio data"))); err != ErrNoTag {
+		t.Errorf("expected ErrNoTag, but got %v", err)
+	}
+}
+
+func TestReadTrailing(t *testing.T) {
+	id3 := make([]byte, 128)
+	copy(id3, "TAG")
+	tag := mktag(mkitem("Title", 0, "Title"))
+	// Item count exceeds the tag size
+	broken := append([]byte("audio"), mkfooter(Version2, FooterSize+4, 1000000, 0)...)
+
+	tests := []struct {
+		file  []byte
+		title string
+		end   int64
+	}{
+		{[]byte("audio"), "", 5},
+		{bytes.Join([][]byte{[]byte("audio"), tag, id3}, nil), "Title", 5},
+		{append(broken, id3...), "", int64(len(broken))},
+	}
+
+	for _, test := range tests {
+		ape, end, err := ReadTrailing(bytes.NewReader(test.file))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if end != test.end {
+			t.Errorf("expected audio to end at %d, but got %d", test.end, end)
+		}
+		if test.title == "" {
+			if ape != nil {
+				t.Errorf("expected no tag, but got %+v", ape)
+			}
+		} else if ape == nil || ape.Item("title").String() != test.title {
+			t.Errorf(`expected tag with title "%s", but got %+v`, test.title, ape)
+		}
+	}
+}
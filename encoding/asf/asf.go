@@ -0,0 +1,176 @@
+// Package asf implements Advanced Systems Format (ASF) header objects,
+// used by Windows Media Audio (WMA) files.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package asf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/audioid/audioid/encoding/wav"
+	"github.com/audioid/audioid/errors"
+)
+
+const (
+	// ObjectHeaderSize is the size of object GUID and 64-bit size.
+	ObjectHeaderSize = 24
+	// HeaderObjectSize is the size of Header Object without child objects.
+	HeaderObjectSize = 30
+
+	// maxHeaderSize limits the size of Header Object, which is read into memory.
+	maxHeaderSize = 64 << 20
+)
+
+// GUID identifies ASF objects. It is stored as little-endian
+// 32-bit, 16-bit and 16-bit fields followed by 8 bytes.
+type GUID [16]byte
+
+// GUIDs of known objects.
+//
+// ref: https://docs.microsoft.com/en-us/windows/win32/wmformat/asf-specification
+var (
+	GUIDHeader                     = GUID{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	GUIDFileProperties             = GUID{0xA1, 0xDC, 0xAB, 0x8C, 0x47, 0xA9, 0xCF, 0x11, 0x8E, 0xE4, 0x00, 0xC0, 0x0C, 0x20, 0x53, 0x65}
+	GUIDStreamProperties           = GUID{0x91, 0x07, 0xDC, 0xB7, 0xB7, 0xA9, 0xCF, 0x11, 0x8E, 0xE6, 0x00, 0xC0, 0x0C, 0x20, 0x53, 0x65}
+	GUIDContentDescription         = GUID{0x33, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11, 0xA6, 0xD9, 0x00, 0xAA, 0x00, 0x62, 0xCE, 0x6C}
+	GUIDExtendedContentDescription = GUID{0x40, 0xA4, 0xD0, 0xD2, 0x07, 0xE3, 0xD2, 0x11, 0x97, 0xF0, 0x00, 0xA0, 0xC9, 0x5E, 0xA8, 0x50}
+	GUIDHeaderExtension            = GUID{0xB5, 0x03, 0xBF, 0x5F, 0x2E, 0xA9, 0xCF, 0x11, 0x8E, 0xE3, 0x00, 0xC0, 0x0C, 0x20, 0x53, 0x65}
+	GUIDMetadata                   = GUID{0xEA, 0xCB, 0xF8, 0xC5, 0xAF, 0x5B, 0x77, 0x48, 0x84, 0x67, 0xAA, 0x8C, 0x44, 0xFA, 0x4C, 0xCA}
+	GUIDMetadataLibrary            = GUID{0x94, 0x1C, 0x23, 0x44, 0x98, 0x94, 0xD1, 0x49, 0xA1, 0x41, 0x1D, 0x13, 0x4E, 0x45, 0x70, 0x54}
+	// GUIDAudioMedia is a stream type of audio streams.
+	GUIDAudioMedia = GUID{0x40, 0x9E, 0x69, 0xF8, 0x4D, 0x5B, 0xCF, 0x11, 0xA8, 0xFD, 0x00, 0x80, 0x5F, 0x5C, 0x44, 0x2B}
+)
+
+// String formats GUID as "75B22630-668E-11CF-A6D9-00AA0062CE6C".
+func (id GUID) String() string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(id[0:4]),
+		binary.LittleEndian.Uint16(id[4:6]),
+		binary.LittleEndian.Uint16(id[6:8]),
+		id[8:10], id[10:16])
+}
+
+var (
+	// ErrNotASF is returned when file does not start with ASF Header Object.
+	ErrNotASF = errors.New("not an asf file")
+	// ErrNoAudio is returned when file has no audio stream.
+	ErrNoAudio = errors.New("no asf audio stream")
+)
+
+// Format tags of Windows Media Audio codecs in WAVEFORMATEX.
+const (
+	FormatWMA1        wav.FormatTag = 0x0160
+	FormatWMA2        wav.FormatTag = 0x0161
+	FormatWMAPro      wav.FormatTag = 0x0162
+	FormatWMALossless wav.FormatTag = 0x0163
+	FormatWMAVoice    wav.FormatTag = 0x000A
+)
+
+// DataType of attribute values.
+type DataType uint16
+
+const (
+	DataTypeUnicode   DataType = 0
+	DataTypeByteArray DataType = 1
+	DataTypeBool      DataType = 2
+	DataTypeDWORD     DataType = 3
+	DataTypeQWORD     DataType = 4
+	DataTypeWORD      DataType = 5
+	DataTypeGUID      DataType = 6
+)
+
+// Object is a header of top-level child object of Header Object.
+type Object struct {
+	ID GUID
+	// Offset of the object data from the start of file.
+	Offset int64
+	// Size of the object data without header.
+	Size int64
+}
+
+// FileProperties is a content of File Properties Object.
+type FileProperties struct {
+	FileID   GUID
+	FileSize uint64
+	// CreationDate is in 100-nanosecond units since January 1, 1601.
+	CreationDate      uint64
+	DataPacketsCount  uint64
+	PlayDuration      time.Duration
+	SendDuration      time.Duration
+	Preroll           time.Duration
+	Flags             uint32
+	MinDataPacketSize uint32
+	MaxDataPacketSize uint32
+	MaxBitrate        uint32
+}
+
+// FlagBroadcast is set in FileProperties.Flags, when the file is a live
+// stream and its size, duration and packets count are not valid.
+const FlagBroadcast = 0x1
+
+// Duration is the play duration without preroll.
+func (props *FileProperties) Duration() time.Duration {
+	if props.PlayDuration < props.Preroll {
+		return 0
+	}
+	return props.PlayDuration - props.Preroll
+}
+
+// StreamProperties is a content of Stream Properties Object.
+type StreamProperties struct {
+	StreamType GUID
+	// Number of the stream from 1 to 127.
+	Number uint16
+	// Format is parsed from type-specific data of audio streams.
+	Format *wav.Format
+}
+
+// ContentDescription is a content of Content Description Object.
+type ContentDescription struct {
+	Title       string
+	Author      string
+	Copyright   string
+	Description string
+	Rating      string
+}
+
+// Attribute is a descriptor of Extended Content Description Object,
+// or a record of Metadata or Metadata Library Objects.
+type Attribute struct {
+	Name string
+	Type DataType
+	// Stream is the number of the stream, the attribute applies to,
+	// or 0 for the whole file.
+	Stream uint16
+	// Language is an index in Language List Object.
+	Language uint16
+	Value    []byte
+}
+
+// File describes the parts of ASF file relevant to metadata.
+type File struct {
+	// Objects are all child objects of Header Object in the order of the file.
+	Objects        []*Object
+	FileProperties *FileProperties
+	Streams        []*StreamProperties
+	Content        *ContentDescription
+	// Attributes are collected from Extended Content Description,
+	// Metadata and Metadata Library Objects in this order.
+	Attributes []*Attribute
+}
+
+// AudioStream returns the first audio stream, or nil.
+func (file *File) AudioStream() *StreamProperties {
+	for _, stream := range file.Streams {
+		if stream.StreamType == GUIDAudioMedia && stream.Format != nil {
+			return stream
+		}
+	}
+	return nil
+}
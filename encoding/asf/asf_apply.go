@@ -0,0 +1,210 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package asf
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/wav"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// attributeKeys maps lowercased attribute names to Vorbis comment keys.
+// Other "WM/" attributes are mostly internal identifiers of Windows Media
+// Player and are skipped, and the rest keep their lowercased names.
+//
+// ref: https://docs.microsoft.com/en-us/windows/win32/wmformat/attribute-list
+var attributeKeys = map[string]string{
+	"wm/albumtitle":                "album",
+	"wm/albumartist":               "albumartist",
+	"wm/year":                      "date",
+	"wm/originalreleaseyear":       "originaldate",
+	"wm/originalalbumtitle":        "originalalbum",
+	"wm/originalartist":            "originalartist",
+	"wm/genre":                     "genre",
+	"wm/composer":                  "composer",
+	"wm/conductor":                 "conductor",
+	"wm/writer":                    "lyricist",
+	"wm/modifiedby":                "remixer",
+	"wm/publisher":                 "organization",
+	"wm/lyrics":                    "lyrics",
+	"wm/subtitle":                  "subtitle",
+	"wm/mood":                      "mood",
+	"wm/language":                  "language",
+	"wm/beatsperminute":            "bpm",
+	"wm/isrc":                      "isrc",
+	"wm/barcode":                   "barcode",
+	"wm/catalogno":                 "catalognumber",
+	"wm/encodedby":                 "encodedby",
+	"wm/toolname":                  "encoder",
+	"wm/iscompilation":             "compilation",
+	"musicbrainz/track id":         "musicbrainz_trackid",
+	"musicbrainz/album id":         "musicbrainz_albumid",
+	"musicbrainz/artist id":        "musicbrainz_artistid",
+	"musicbrainz/release group id": "musicbrainz_releasegroupid",
+}
+
+// String converts text and numeric values to string.
+// Booleans are "1" or "0", and binary values are empty.
+func (attr *Attribute) String() string {
+	v := attr.Value
+	switch attr.Type {
+	case DataTypeUnicode:
+		return decodeUTF16(v)
+	case DataTypeBool:
+		for _, c := range v {
+			if c != 0 {
+				return "1"
+			}
+		}
+		return "0"
+	case DataTypeWORD:
+		if len(v) >= 2 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(v)), 10)
+		}
+	case DataTypeDWORD:
+		if len(v) >= 4 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(v)), 10)
+		}
+	case DataTypeQWORD:
+		if len(v) >= 8 {
+			return strconv.FormatUint(binary.LittleEndian.Uint64(v), 10)
+		}
+	}
+	return ""
+}
+
+// Comments converts Content Description and attributes into
+// Vorbis comment keys and values, so they are applied to a track
+// the same way as in other formats. Attributes, which are repeated
+// in several objects, keep the last value.
+func (file *File) Comments() map[string]string {
+	comments := map[string]string{}
+	if c := file.Content; c != nil {
+		for key, value := range map[string]string{
+			"title":       c.Title,
+			"artist":      c.Author,
+			"copyright":   c.Copyright,
+			"description": c.Description,
+		} {
+			if value != "" {
+				comments[key] = value
+			}
+		}
+	}
+
+	// WM/Track is zero-based and is superseded by WM/TrackNumber
+	track := ""
+	for _, attr := range file.Attributes {
+		value := attr.String()
+		if value == "" {
+			continue
+		}
+
+		key := strings.ToLower(attr.Name)
+		switch key {
+		case "wm/tracknumber":
+			utils.SetPair(comments, "tracknumber", "tracktotal", value)
+		case "wm/track":
+			if n, err := strconv.Atoi(value); err == nil {
+				track = strconv.Itoa(n + 1)
+			}
+		case "wm/partofset":
+			utils.SetPair(comments, "discnumber", "disctotal", value)
+		default:
+			if mapped, ok := attributeKeys[key]; ok {
+				comments[mapped] = value
+			} else if !strings.HasPrefix(key, "wm/") {
+				comments[key] = value
+			}
+		}
+	}
+	if _, ok := comments["tracknumber"]; !ok && track != "" {
+		comments["tracknumber"] = track
+	}
+	return comments
+}
+
+// Picture converts WM/Picture attribute into *metadata.Picture.
+// The value is picture type, 32-bit size of data, NUL-terminated
+// UTF-16 MIME type and description, and data. Other attributes return nil.
+func (attr *Attribute) Picture() *metadata.Picture {
+	if !strings.EqualFold(attr.Name, "WM/Picture") || attr.Type != DataTypeByteArray || len(attr.Value) < 5 {
+		return nil
+	}
+	size := binary.LittleEndian.Uint32(attr.Value[1:5])
+	mime, b := cutUTF16(attr.Value[5:])
+	description, b := cutUTF16(b)
+	if uint64(size) < uint64(len(b)) {
+		b = b[:size]
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	return &metadata.Picture{
+		MIME:        mime,
+		Description: description,
+		Data:        b,
+	}
+}
+
+// Apply audio stream properties, content description and attributes
+// to the track. Duration of broadcast files is unknown.
+func (file *File) Apply(t *metadata.Track) {
+	if stream := file.AudioStream(); stream != nil {
+		format := stream.Format
+		t.Properties = metadata.Properties{
+			Codec:         codecName(format.Codec()),
+			SampleRate:    format.SampleRate,
+			Channels:      uint8(format.Channels),
+			BitsPerSample: uint8(format.BitsPerSample),
+			Bitrate:       format.ByteRate * 8,
+		}
+		// Sample size field is meaningless for lossy codecs
+		switch format.Codec() {
+		case FormatWMA1, FormatWMA2, FormatWMAPro, FormatWMAVoice, wav.FormatMPEGLayer3:
+			t.Properties.BitsPerSample = 0
+		}
+	}
+
+	if props := file.FileProperties; props != nil && props.Flags&FlagBroadcast == 0 {
+		t.Duration = props.Duration()
+		rate := uint64(t.Properties.SampleRate)
+		t.Properties.TotalSamples = uint64(t.Duration/time.Second)*rate +
+			uint64(t.Duration%time.Second)*rate/uint64(time.Second)
+	} else {
+		t.Duration = -1
+	}
+
+	comment := &flac.VorbisComment{Comments: file.Comments()}
+	comment.Apply(t)
+
+	for _, attr := range file.Attributes {
+		if pic := attr.Picture(); pic != nil {
+			t.Pictures = append(t.Pictures, *pic)
+		}
+	}
+}
+
+func codecName(tag wav.FormatTag) string {
+	switch tag {
+	case FormatWMA1, FormatWMA2:
+		return "WMA"
+	case FormatWMAPro:
+		return "WMA Pro"
+	case FormatWMALossless:
+		return "WMA Lossless"
+	case FormatWMAVoice:
+		return "WMA Voice"
+	}
+	return tag.String()
+}
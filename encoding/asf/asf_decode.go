@@ -0,0 +1,301 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package asf
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+	"unicode/utf16"
+
+	"github.com/audioid/audioid/encoding/wav"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// Decode reads ASF stream properties and attributes into *metadata.Track.
+// f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode asf", err)
+	}
+	if file.AudioStream() == nil {
+		return nil, errors.Wrap("could not decode asf", ErrNoAudio)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+	return t, nil
+}
+
+// ReadFile reads child objects of ASF Header Object.
+// Data and index objects are not read.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+
+	var header [HeaderObjectSize]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotASF
+		}
+		return nil, errors.Wrap("could not read header object", err)
+	}
+	var id GUID
+	copy(id[:], header[:16])
+	if id != GUIDHeader {
+		return nil, ErrNotASF
+	}
+	size := binary.LittleEndian.Uint64(header[16:24])
+	if size < HeaderObjectSize || size > maxHeaderSize {
+		return nil, errors.New("invalid asf header object size")
+	}
+
+	b := make([]byte, size-HeaderObjectSize)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read header object", err)
+	}
+
+	file := &File{}
+	offset := start + HeaderObjectSize
+	err = walkObjects(b, func(id GUID, data []byte) error {
+		file.Objects = append(file.Objects, &Object{
+			ID:     id,
+			Offset: offset + ObjectHeaderSize,
+			Size:   int64(len(data)),
+		})
+		offset += ObjectHeaderSize + int64(len(data))
+		return file.parseObject(id, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// walkObjects calls fn for every object of b.
+// Size of the last object is truncated to the end of b.
+func walkObjects(b []byte, fn func(id GUID, data []byte) error) error {
+	for len(b) >= ObjectHeaderSize {
+		var id GUID
+		copy(id[:], b[:16])
+		size := binary.LittleEndian.Uint64(b[16:24])
+		if size < ObjectHeaderSize {
+			return errors.New("invalid asf object size")
+		}
+		if size > uint64(len(b)) {
+			size = uint64(len(b))
+		}
+		if err := fn(id, b[ObjectHeaderSize:size]); err != nil {
+			return err
+		}
+		b = b[size:]
+	}
+	return nil
+}
+
+func (file *File) parseObject(id GUID, b []byte) error {
+	switch id {
+	case GUIDFileProperties:
+		props, err := parseFileProperties(b)
+		if err != nil {
+			return err
+		}
+		file.FileProperties = props
+	case GUIDStreamProperties:
+		stream, err := parseStreamProperties(b)
+		if err != nil {
+			return err
+		}
+		file.Streams = append(file.Streams, stream)
+	case GUIDContentDescription:
+		content, err := parseContentDescription(b)
+		if err != nil {
+			return err
+		}
+		file.Content = content
+	case GUIDExtendedContentDescription:
+		attrs, err := parseExtendedContentDescription(b)
+		if err != nil {
+			return err
+		}
+		file.Attributes = append(file.Attributes, attrs...)
+	case GUIDHeaderExtension:
+		// Reserved GUID, reserved 16-bit field and size of extension objects
+		if len(b) < 22 {
+			return errors.New("asf header extension object is too short")
+		}
+		return walkObjects(b[22:], file.parseObject)
+	case GUIDMetadata, GUIDMetadataLibrary:
+		attrs, err := parseMetadata(b)
+		if err != nil {
+			return err
+		}
+		file.Attributes = append(file.Attributes, attrs...)
+	}
+	return nil
+}
+
+func parseFileProperties(b []byte) (*FileProperties, error) {
+	if len(b) < 80 {
+		return nil, errors.New("asf file properties object is too short")
+	}
+	props := &FileProperties{
+		FileSize:          binary.LittleEndian.Uint64(b[16:24]),
+		CreationDate:      binary.LittleEndian.Uint64(b[24:32]),
+		DataPacketsCount:  binary.LittleEndian.Uint64(b[32:40]),
+		PlayDuration:      time.Duration(binary.LittleEndian.Uint64(b[40:48])) * 100,
+		SendDuration:      time.Duration(binary.LittleEndian.Uint64(b[48:56])) * 100,
+		Preroll:           time.Duration(binary.LittleEndian.Uint64(b[56:64])) * time.Millisecond,
+		Flags:             binary.LittleEndian.Uint32(b[64:68]),
+		MinDataPacketSize: binary.LittleEndian.Uint32(b[68:72]),
+		MaxDataPacketSize: binary.LittleEndian.Uint32(b[72:76]),
+		MaxBitrate:        binary.LittleEndian.Uint32(b[76:80]),
+	}
+	copy(props.FileID[:], b[:16])
+	return props, nil
+}
+
+// parseStreamProperties parses stream type, error correction type,
+// time offset, sizes of type-specific and error correction data,
+// flags with stream number, reserved field and type-specific data.
+func parseStreamProperties(b []byte) (*StreamProperties, error) {
+	if len(b) < 54 {
+		return nil, errors.New("asf stream properties object is too short")
+	}
+	stream := &StreamProperties{
+		Number: binary.LittleEndian.Uint16(b[48:50]) & 0x7F,
+	}
+	copy(stream.StreamType[:], b[:16])
+
+	size := binary.LittleEndian.Uint32(b[40:44])
+	data := b[54:]
+	if uint64(size) < uint64(len(data)) {
+		data = data[:size]
+	}
+	if stream.StreamType == GUIDAudioMedia {
+		format, err := wav.ParseFormat(data)
+		if err != nil {
+			return nil, errors.Wrap("could not parse audio stream format", err)
+		}
+		stream.Format = format
+	}
+	return stream, nil
+}
+
+// parseContentDescription parses five 16-bit lengths
+// followed by UTF-16 strings of the same order.
+func parseContentDescription(b []byte) (*ContentDescription, error) {
+	if len(b) < 10 {
+		return nil, errors.New("asf content description object is too short")
+	}
+	content := &ContentDescription{}
+	fields := [...]*string{&content.Title, &content.Author, &content.Copyright, &content.Description, &content.Rating}
+	data := b[10:]
+	for i, field := range fields {
+		n := int(binary.LittleEndian.Uint16(b[i*2:]))
+		if n > len(data) {
+			n = len(data)
+		}
+		*field = decodeUTF16(data[:n])
+		data = data[n:]
+	}
+	return content, nil
+}
+
+// parseExtendedContentDescription parses descriptors: name, type and value.
+func parseExtendedContentDescription(b []byte) ([]*Attribute, error) {
+	errShort := errors.New("asf extended content description object is too short")
+	if len(b) < 2 {
+		return nil, errShort
+	}
+	count := int(binary.LittleEndian.Uint16(b))
+	b = b[2:]
+
+	attrs := make([]*Attribute, 0, count)
+	for i := 0; i < count; i++ {
+		if len(b) < 2 {
+			return nil, errShort
+		}
+		n := int(binary.LittleEndian.Uint16(b))
+		if len(b) < 2+n+4 {
+			return nil, errShort
+		}
+		attr := &Attribute{Name: decodeUTF16(b[2 : 2+n])}
+		b = b[2+n:]
+		attr.Type = DataType(binary.LittleEndian.Uint16(b[0:2]))
+		n = int(binary.LittleEndian.Uint16(b[2:4]))
+		if len(b) < 4+n {
+			return nil, errShort
+		}
+		attr.Value = b[4 : 4+n]
+		b = b[4+n:]
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// parseMetadata parses records of Metadata and Metadata Library Objects:
+// language index, stream number, name length, type, value length,
+// name and value.
+func parseMetadata(b []byte) ([]*Attribute, error) {
+	errShort := errors.New("asf metadata object is too short")
+	if len(b) < 2 {
+		return nil, errShort
+	}
+	count := int(binary.LittleEndian.Uint16(b))
+	b = b[2:]
+
+	attrs := make([]*Attribute, 0, count)
+	for i := 0; i < count; i++ {
+		if len(b) < 12 {
+			return nil, errShort
+		}
+		attr := &Attribute{
+			Language: binary.LittleEndian.Uint16(b[0:2]),
+			Stream:   binary.LittleEndian.Uint16(b[2:4]),
+			Type:     DataType(binary.LittleEndian.Uint16(b[6:8])),
+		}
+		nameSize := uint64(binary.LittleEndian.Uint16(b[4:6]))
+		valueSize := uint64(binary.LittleEndian.Uint32(b[8:12]))
+		b = b[12:]
+		if uint64(len(b)) < nameSize+valueSize {
+			return nil, errShort
+		}
+		attr.Name = decodeUTF16(b[:nameSize])
+		attr.Value = b[nameSize : nameSize+valueSize]
+		b = b[nameSize+valueSize:]
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// decodeUTF16 decodes UTF-16LE string, which is usually NUL-terminated.
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	for len(u) > 0 && u[len(u)-1] == 0 {
+		u = u[:len(u)-1]
+	}
+	return string(utf16.Decode(u))
+}
+
+// cutUTF16 splits b after the first NUL-terminated UTF-16LE string.
+func cutUTF16(b []byte) (string, []byte) {
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			return decodeUTF16(b[:i]), b[i+2:]
+		}
+	}
+	return decodeUTF16(b), nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package asf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func object(id GUID, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, ObjectHeaderSize)
+	copy(b, id[:])
+	binary.LittleEndian.PutUint64(b[16:], uint64(ObjectHeaderSize+len(body)))
+	return append(b, body...)
+}
+
+func header(objects ...[]byte) []byte {
+	body := bytes.Join(objects, nil)
+	b := make([]byte, HeaderObjectSize)
+	copy(b, GUIDHeader[:])
+	binary.LittleEndian.PutUint64(b[16:], uint64(HeaderObjectSize+len(body)))
+	binary.LittleEndian.PutUint32(b[24:], uint32(len(objects)))
+	return append(b, body...)
+}
+
+// wstr encodes NUL-terminated UTF-16LE string.
+func wstr(s string) []byte {
+	u := utf16.Encode([]rune(s + "\x00"))
+	b := make([]byte, len(u)*2)
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[i*2:], c)
+	}
+	return b
+}
+
+func le16(x uint16) []byte {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, x)
+	return b
+}
+
+func le32(x uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, x)
+	return b
+}
+
+func le64(x uint64) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, x)
+	return b
+}
+
+func fileProperties(duration, preroll uint64, flags uint32) []byte {
+	return object(GUIDFileProperties, make([]byte, 40),
+		le64(duration), le64(0), le64(preroll), le32(flags), make([]byte, 12))
+}
+
+func audioStream(tag uint16) []byte {
+	format := bytes.Join([][]byte{le16(tag), le16(2), le32(44100), le32(16000), le16(4096), le16(16), le16(0)}, nil)
+	return object(GUIDStreamProperties, GUIDAudioMedia[:], make([]byte, 16+8),
+		le32(uint32(len(format))), le32(0), le16(1), le32(0), format)
+}
+
+func contentDescription(title, author string) []byte {
+	return object(GUIDContentDescription,
+		le16(uint16(len(wstr(title)))), le16(uint16(len(wstr(author)))), le16(0), le16(0), le16(0),
+		wstr(title), wstr(author))
+}
+
+func descriptor(name string, typ DataType, value []byte) []byte {
+	return bytes.Join([][]byte{le16(uint16(len(wstr(name)))), wstr(name), le16(uint16(typ)), le16(uint16(len(value))), value}, nil)
+}
+
+func record(name string, typ DataType, value []byte) []byte {
+	return bytes.Join([][]byte{le16(0), le16(0), le16(uint16(len(wstr(name)))), le16(uint16(typ)), le32(uint32(len(value))), wstr(name), value}, nil)
+}
+
+func TestDecode(t *testing.T) {
+	picture := bytes.Join([][]byte{{3}, le32(4), wstr("image/png"), wstr("Front"), []byte("\x89PNG")}, nil)
+	library := object(GUIDMetadataLibrary, le16(2),
+		record("WM/Picture", DataTypeByteArray, picture),
+		record("WM/PartOfSet", DataTypeUnicode, wstr("1/2")))
+	file := header(
+		fileProperties(6*10000000, 3000, 0),
+		audioStream(uint16(FormatWMA2)),
+		contentDescription("WMA Title", "WMA Artist"),
+		object(GUIDExtendedContentDescription, le16(5),
+			descriptor("WM/AlbumTitle", DataTypeUnicode, wstr("WMA Album")),
+			descriptor("WM/Track", DataTypeDWORD, le32(4)),
+			descriptor("WM/TrackNumber", DataTypeDWORD, le32(7)),
+			descriptor("WM/WMContentID", DataTypeUnicode, wstr("internal")),
+			descriptor("REPLAYGAIN_TRACK_GAIN", DataTypeUnicode, wstr("-1.00 dB"))),
+		object(GUIDHeaderExtension, make([]byte, 18), le32(uint32(len(library))), library),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "WMA Title" || track.Artist != "WMA Artist" || track.Album != "WMA Album" {
+		t.Errorf("expected title, artist and album, but got %q, %q and %q", track.Title, track.Artist, track.Album)
+	}
+	if track.TrackNumber != "7" || track.Comments["discnumber"] != "1" || track.Comments["disctotal"] != "2" {
+		t.Errorf("expected track 7 on disc 1 of 2, but got %q on %q of %q", track.TrackNumber, track.Comments["discnumber"], track.Comments["disctotal"])
+	}
+	if x := track.Comments["replaygain_track_gain"]; x != "-1.00 dB" {
+		t.Errorf(`expected Comments[replaygain_track_gain] to be "-1.00 dB", but got %q`, x)
+	}
+	if _, ok := track.Comments["wmcontentid"]; ok {
+		t.Error("expected internal WM/ attributes to be skipped")
+	}
+	if len(track.Pictures) != 1 || track.Pictures[0].MIME != "image/png" || track.Pictures[0].Description != "Front" || string(track.Pictures[0].Data) != "\x89PNG" {
+		t.Errorf("expected front cover from WM/Picture, but got %+v", track.Pictures)
+	}
+	if track.Duration != 3*time.Second {
+		t.Errorf("expected duration 3s without preroll, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "WMA" || p.SampleRate != 44100 || p.Channels != 2 || p.BitsPerSample != 0 || p.Bitrate != 128000 || p.TotalSamples != 132300 {
+		t.Errorf("expected 3 seconds of 128 kbps stereo WMA, but got %+v", p)
+	}
+}
+
+func TestDecodeTrackZeroBased(t *testing.T) {
+	file := header(
+		fileProperties(0, 0, FlagBroadcast),
+		audioStream(uint16(FormatWMALossless)),
+		object(GUIDExtendedContentDescription, le16(1),
+			descriptor("WM/Track", DataTypeDWORD, le32(4))),
+	)
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.TrackNumber != "5" {
+		t.Errorf(`expected TrackNumber from zero-based WM/Track to be "5", but got %q`, track.TrackNumber)
+	}
+	if track.Duration != -1 {
+		t.Errorf("expected unknown duration of broadcast, but got %s", track.Duration)
+	}
+	if p := track.Properties; p.Codec != "WMA Lossless" || p.BitsPerSample != 16 {
+		t.Errorf("expected 16-bit WMA Lossless, but got %+v", p)
+	}
+}
+
+func TestReadFileNotASF(t *testing.T) {
+	if _, err := ReadFile(bytes.NewReader([]byte("RIFF"))); err != ErrNotASF {
+		t.Errorf("expected ErrNotASF, but got %v", err)
+	}
+	if _, err := Decode(bytes.NewReader(header())); err == nil {
+		t.Error("expected error for file without audio stream")
+	}
+}
+
+func TestGUIDString(t *testing.T) {
+	if x := GUIDHeader.String(); x != "75B22630-668E-11CF-A6D9-00AA0062CE6C" {
+		t.Errorf(`expected "75B22630-668E-11CF-A6D9-00AA0062CE6C", but got %q`, x)
+	}
+}
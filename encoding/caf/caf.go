@@ -0,0 +1,193 @@
+// Package caf implements Apple Core Audio Format (CAF) chunks.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package caf
+
+import (
+	"github.com/audioid/audioid/errors"
+)
+
+const (
+	// FileHeaderSize is the size of file type, version and flags.
+	FileHeaderSize = 8
+	// ChunkHeaderSize is the size of chunk type and 64-bit size.
+	ChunkHeaderSize = 12
+	// DescriptionSize is the size of desc chunk.
+	DescriptionSize = 32
+)
+
+var (
+	// ErrNotCAF is returned when file is not a CAF file.
+	ErrNotCAF = errors.New("not a caf file")
+	// ErrNoDescription is returned when file has no desc chunk.
+	ErrNoDescription = errors.New("no caf audio description chunk")
+)
+
+// FormatID is a four-character code of audio data format.
+type FormatID string
+
+const (
+	FormatLinearPCM FormatID = "lpcm"
+	FormatAAC       FormatID = "aac "
+	FormatALAC      FormatID = "alac"
+	FormatIMA4      FormatID = "ima4"
+	FormatULaw      FormatID = "ulaw"
+	FormatALaw      FormatID = "alaw"
+	FormatMP3       FormatID = ".mp3"
+	FormatOpus      FormatID = "opus"
+	FormatFLAC      FormatID = "flac"
+	FormatAC3       FormatID = "ac-3"
+	FormatEAC3      FormatID = "ec-3"
+	FormatILBC      FormatID = "ilbc"
+	FormatAMR       FormatID = "samr"
+)
+
+var codecs = map[FormatID]string{
+	FormatAAC:  "AAC",
+	FormatALAC: "ALAC",
+	FormatIMA4: "IMA ADPCM",
+	FormatULaw: "µ-law",
+	FormatALaw: "A-law",
+	FormatMP3:  "MP3",
+	FormatOpus: "Opus",
+	FormatFLAC: "FLAC",
+	FormatAC3:  "AC-3",
+	FormatEAC3: "E-AC-3",
+	FormatILBC: "iLBC",
+	FormatAMR:  "AMR",
+	"aach":     "HE-AAC",
+	"aacp":     "HE-AAC v2",
+	"MAC3":     "MACE 3:1",
+	"MAC6":     "MACE 6:1",
+}
+
+// Format flags of linear PCM.
+const (
+	FlagIsFloat        = 1 << 0
+	FlagIsLittleEndian = 1 << 1
+)
+
+// Description is a content of desc chunk.
+//
+// ref: https://developer.apple.com/library/archive/documentation/MusicAudio/Reference/CAFSpec/CAF_spec/CAF_spec.html
+type Description struct {
+	SampleRate  float64
+	FormatID    FormatID
+	FormatFlags uint32
+	// BytesPerPacket and FramesPerPacket are 0 for variable sizes.
+	BytesPerPacket   uint32
+	FramesPerPacket  uint32
+	ChannelsPerFrame uint32
+	// BitsPerChannel is 0 for compressed formats.
+	BitsPerChannel uint32
+}
+
+// Codec returns a short codec name, e.g. "PCM" or "AAC".
+func (desc *Description) Codec() string {
+	if desc.FormatID == FormatLinearPCM {
+		if desc.FormatFlags&FlagIsFloat != 0 {
+			return "IEEE float"
+		}
+		return "PCM"
+	}
+	if codec, ok := codecs[desc.FormatID]; ok {
+		return codec
+	}
+	return string(desc.FormatID)
+}
+
+// BitsPerSample returns bit depth of linear PCM and ALAC,
+// whose format flags hold the bit depth of the source. It is 0 otherwise.
+func (desc *Description) BitsPerSample() uint32 {
+	switch desc.FormatID {
+	case FormatLinearPCM:
+		return desc.BitsPerChannel
+	case FormatALAC:
+		switch desc.FormatFlags {
+		case 1:
+			return 16
+		case 2:
+			return 20
+		case 3:
+			return 24
+		case 4:
+			return 32
+		}
+	}
+	return 0
+}
+
+// PacketTable is a header of pakt chunk.
+// The table of variable packet sizes is not parsed.
+type PacketTable struct {
+	Packets int64
+	// ValidFrames is the number of frames without priming and remainder.
+	ValidFrames     int64
+	PrimingFrames   int32
+	RemainderFrames int32
+}
+
+// ChannelDescription describes a channel of chan chunk.
+type ChannelDescription struct {
+	Label       uint32
+	Flags       uint32
+	Coordinates [3]float32
+}
+
+// ChannelLayout is a content of chan chunk.
+type ChannelLayout struct {
+	// Tag is kCAFChannelLayoutTag_* value. It is 0, when the layout
+	// is defined by Descriptions, and 0x10000, when it is defined by Bitmap.
+	Tag          uint32
+	Bitmap       uint32
+	Descriptions []ChannelDescription
+}
+
+// Chunk is a chunk header of CAF file.
+type Chunk struct {
+	ID string
+	// Offset of the chunk data from the start of file.
+	Offset int64
+	// Size of the chunk data without header.
+	Size int64
+}
+
+// File describes the parts of CAF file relevant to metadata.
+type File struct {
+	Version     uint16
+	Description *Description
+	PacketTable *PacketTable
+	// ChannelLayout is an optional chan chunk.
+	ChannelLayout *ChannelLayout
+	// Info holds key-value pairs of info chunk, e.g. "artist".
+	Info map[string]string
+	// DataSize is the size of audio data without edit count.
+	DataSize int64
+	// EditCount is incremented every time audio data is modified.
+	EditCount uint32
+	// Chunks are all top-level chunks in the order of the file.
+	Chunks []*Chunk
+}
+
+// TotalFrames is the number of playable frames. Packet table
+// is exact, and constant bitrate formats are derived from data size.
+func (file *File) TotalFrames() uint64 {
+	desc := file.Description
+	if pakt := file.PacketTable; pakt != nil {
+		if pakt.ValidFrames > 0 {
+			return uint64(pakt.ValidFrames)
+		}
+		frames := pakt.Packets*int64(desc.FramesPerPacket) - int64(pakt.PrimingFrames) - int64(pakt.RemainderFrames)
+		if frames > 0 {
+			return uint64(frames)
+		}
+	}
+	if desc.BytesPerPacket != 0 && file.DataSize > 0 {
+		return uint64(file.DataSize) / uint64(desc.BytesPerPacket) * uint64(desc.FramesPerPacket)
+	}
+	return 0
+}
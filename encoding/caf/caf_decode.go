@@ -0,0 +1,318 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package caf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// maxChunkSize rejects desc, chan and info chunks with corrupted sizes
+// before they are allocated.
+const maxChunkSize = 64 << 20
+
+// Decode reads desc, pakt, chan and info chunks
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode caf", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+	return t, nil
+}
+
+// ReadFile walks chunks of CAF file.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+	var header [FileHeaderSize]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotCAF
+		}
+		return nil, errors.Wrap("could not read file header", err)
+	}
+	if string(header[:4]) != "caff" {
+		return nil, ErrNotCAF
+	}
+	file := &File{Version: binary.BigEndian.Uint16(header[4:6])}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+
+	for offset := start + FileHeaderSize; offset+ChunkHeaderSize <= end; {
+		chunk, err := readChunkHeader(f, offset, end)
+		if err != nil {
+			return nil, err
+		}
+
+		file.Chunks = append(file.Chunks, chunk)
+		if err := file.readChunk(f, chunk); err != nil {
+			return nil, err
+		}
+
+		offset = chunk.Offset + chunk.Size
+	}
+
+	if file.Description == nil {
+		return nil, ErrNoDescription
+	}
+	return file, nil
+}
+
+// readChunkHeader reads chunk header at given offset.
+// Size of data chunk may be -1, when it extends to the end of file.
+// Size of the last chunk is truncated to the end of file.
+func readChunkHeader(f io.ReadSeeker, offset, end int64) (*Chunk, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk", err)
+	}
+	var b [ChunkHeaderSize]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return nil, errors.Wrap("could not read chunk header", err)
+	}
+	chunk := &Chunk{
+		ID:     string(b[:4]),
+		Offset: offset + ChunkHeaderSize,
+		Size:   int64(binary.BigEndian.Uint64(b[4:12])),
+	}
+	if chunk.Size < 0 || chunk.Offset+chunk.Size > end {
+		chunk.Size = end - chunk.Offset
+	}
+	return chunk, nil
+}
+
+func (file *File) readChunk(f io.ReadSeeker, chunk *Chunk) error {
+	switch chunk.ID {
+	case "desc", "pakt", "chan", "info":
+		size := chunk.Size
+		// Only the header of packet table is needed
+		if chunk.ID == "pakt" && size > 24 {
+			size = 24
+		}
+		b, err := readChunkData(f, chunk.Offset, size)
+		if err != nil {
+			return err
+		}
+		return file.parseChunk(chunk.ID, b)
+	case "data":
+		var b [4]byte
+		if chunk.Size < 4 {
+			return errors.New("caf data chunk is too short")
+		}
+		if _, err := f.Seek(chunk.Offset, io.SeekStart); err != nil {
+			return errors.Wrap("could not seek to audio data", err)
+		}
+		if _, err := io.ReadFull(f, b[:]); err != nil {
+			return errors.Wrap("could not read edit count", err)
+		}
+		file.EditCount = binary.BigEndian.Uint32(b[:])
+		file.DataSize = chunk.Size - 4
+	}
+	return nil
+}
+
+func readChunkData(f io.ReadSeeker, offset, size int64) ([]byte, error) {
+	if size > maxChunkSize {
+		return nil, errors.New("caf chunk is too large")
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk", err)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read chunk", err)
+	}
+	return b, nil
+}
+
+func (file *File) parseChunk(id string, b []byte) error {
+	switch id {
+	case "desc":
+		if len(b) < DescriptionSize {
+			return errors.New("caf audio description chunk is too short")
+		}
+		file.Description = &Description{
+			SampleRate:       math.Float64frombits(binary.BigEndian.Uint64(b[0:8])),
+			FormatID:         FormatID(b[8:12]),
+			FormatFlags:      binary.BigEndian.Uint32(b[12:16]),
+			BytesPerPacket:   binary.BigEndian.Uint32(b[16:20]),
+			FramesPerPacket:  binary.BigEndian.Uint32(b[20:24]),
+			ChannelsPerFrame: binary.BigEndian.Uint32(b[24:28]),
+			BitsPerChannel:   binary.BigEndian.Uint32(b[28:32]),
+		}
+	case "pakt":
+		if len(b) < 24 {
+			return errors.New("caf packet table chunk is too short")
+		}
+		file.PacketTable = &PacketTable{
+			Packets:         int64(binary.BigEndian.Uint64(b[0:8])),
+			ValidFrames:     int64(binary.BigEndian.Uint64(b[8:16])),
+			PrimingFrames:   int32(binary.BigEndian.Uint32(b[16:20])),
+			RemainderFrames: int32(binary.BigEndian.Uint32(b[20:24])),
+		}
+	case "chan":
+		layout, err := parseChannelLayout(b)
+		if err != nil {
+			return err
+		}
+		file.ChannelLayout = layout
+	case "info":
+		file.Info = parseInfo(b)
+	}
+	return nil
+}
+
+// parseChannelLayout parses layout tag, bitmap, number of descriptions
+// and 20-byte descriptions: label, flags and three coordinates.
+func parseChannelLayout(b []byte) (*ChannelLayout, error) {
+	if len(b) < 12 {
+		return nil, errors.New("caf channel layout chunk is too short")
+	}
+	layout := &ChannelLayout{
+		Tag:    binary.BigEndian.Uint32(b[0:4]),
+		Bitmap: binary.BigEndian.Uint32(b[4:8]),
+	}
+	count := binary.BigEndian.Uint32(b[8:12])
+	b = b[12:]
+	for i := uint32(0); i < count && len(b) >= 20; i++ {
+		desc := ChannelDescription{
+			Label: binary.BigEndian.Uint32(b[0:4]),
+			Flags: binary.BigEndian.Uint32(b[4:8]),
+		}
+		for j := range desc.Coordinates {
+			desc.Coordinates[j] = math.Float32frombits(binary.BigEndian.Uint32(b[8+j*4:]))
+		}
+		layout.Descriptions = append(layout.Descriptions, desc)
+		b = b[20:]
+	}
+	return layout, nil
+}
+
+// parseInfo parses number of entries followed by pairs
+// of NUL-terminated UTF-8 keys and values.
+func parseInfo(b []byte) map[string]string {
+	info := map[string]string{}
+	if len(b) < 4 {
+		return info
+	}
+	count := binary.BigEndian.Uint32(b)
+	b = b[4:]
+
+	next := func() string {
+		n := bytes.IndexByte(b, 0)
+		if n < 0 {
+			n = len(b)
+		}
+		s := string(b[:n])
+		b = b[n:]
+		if len(b) > 0 {
+			b = b[1:]
+		}
+		return s
+	}
+	for i := uint32(0); i < count && len(b) > 0; i++ {
+		key := next()
+		info[key] = next()
+	}
+	return info
+}
+
+// infoKeys maps lowercased info keys to Vorbis comment keys,
+// when they differ. Other keys are used lowercased.
+var infoKeys = map[string]string{
+	"year":                 "date",
+	"comments":             "comment",
+	"tempo":                "bpm",
+	"key signature":        "key",
+	"encoding application": "encoder",
+}
+
+// infoProperties are info keys, which duplicate stream properties.
+var infoProperties = map[string]bool{
+	"channel layout":                  true,
+	"nominal bit rate":                true,
+	"approximate duration in seconds": true,
+	"source bit depth":                true,
+}
+
+// Comments converts info chunk into Vorbis comment keys and values.
+// Entries, which describe audio properties, are skipped.
+func (file *File) Comments() map[string]string {
+	comments := map[string]string{}
+	for key, value := range file.Info {
+		value = strings.TrimSpace(value)
+		key = strings.ToLower(key)
+		if value == "" || infoProperties[key] {
+			continue
+		}
+		switch key {
+		case "track number":
+			utils.SetPair(comments, "tracknumber", "tracktotal", value)
+		case "recorded date":
+			// Year is preferred
+			if _, ok := file.Info["year"]; !ok {
+				comments["date"] = value
+			}
+		default:
+			if mapped, ok := infoKeys[key]; ok {
+				key = mapped
+			}
+			comments[key] = value
+		}
+	}
+	return comments
+}
+
+// Apply audio description, packet table and info chunk to the track.
+// Channel layout is stored in Track.Extra under "chan".
+func (file *File) Apply(t *metadata.Track) {
+	desc := file.Description
+	sampleRate := uint32(math.Round(desc.SampleRate))
+	if desc.SampleRate < 0 || desc.SampleRate > math.MaxUint32 || math.IsNaN(desc.SampleRate) {
+		sampleRate = 0
+	}
+	t.Properties = metadata.Properties{
+		Codec:         desc.Codec(),
+		SampleRate:    sampleRate,
+		Channels:      uint8(desc.ChannelsPerFrame),
+		BitsPerSample: uint8(desc.BitsPerSample()),
+		TotalSamples:  file.TotalFrames(),
+	}
+	t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, sampleRate)
+	if desc.FormatID == FormatLinearPCM {
+		t.Properties.Bitrate = sampleRate * desc.ChannelsPerFrame * desc.BitsPerChannel
+	} else if t.Properties.TotalSamples != 0 {
+		// Compressed formats have no bit depth, so bitrate is averaged over audio data
+		t.Properties.Bitrate = uint32(uint64(file.DataSize) * 8 * uint64(sampleRate) / t.Properties.TotalSamples)
+	}
+
+	if comments := file.Comments(); len(comments) != 0 {
+		comment := &flac.VorbisComment{Comments: comments}
+		comment.Apply(t)
+	}
+
+	if file.ChannelLayout != nil {
+		t.SetExtra("chan", file.ChannelLayout)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package caf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+func chunk(id string, size int64, data ...[]byte) []byte {
+	b := make([]byte, ChunkHeaderSize)
+	copy(b, id)
+	binary.BigEndian.PutUint64(b[4:], uint64(size))
+	return append(b, bytes.Join(data, nil)...)
+}
+
+func be32(x uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, x)
+	return b
+}
+
+func be64(x uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, x)
+	return b
+}
+
+func desc(rate float64, format string, flags, bytesPerPacket, framesPerPacket, channels, bits uint32) []byte {
+	return chunk("desc", DescriptionSize, be64(math.Float64bits(rate)), []byte(format),
+		be32(flags), be32(bytesPerPacket), be32(framesPerPacket), be32(channels), be32(bits))
+}
+
+func file(chunks ...[]byte) []byte {
+	return append([]byte("caff\x00\x01\x00\x00"), bytes.Join(chunks, nil)...)
+}
+
+func TestDecodeAAC(t *testing.T) {
+	info := []byte("\x00\x00\x00\x04artist\x00Voice Memo\x00title\x00Meeting\x00track number\x003/12\x00year\x002019\x00")
+	pakt := bytes.Join([][]byte{be64(89), be64(44100 * 2), be32(2112), be32(1024*89 - 44100*2 - 2112), []byte{1, 2, 3}}, nil)
+	layout := bytes.Join([][]byte{be32(0), be32(0), be32(1), be32(42), be32(0), make([]byte, 12)}, nil)
+	data := make([]byte, 1000)
+	track, err := Decode(bytes.NewReader(file(
+		desc(44100, "aac ", 0, 0, 1024, 1, 0),
+		chunk("chan", int64(len(layout)), layout),
+		chunk("info", int64(len(info)), info),
+		chunk("pakt", int64(len(pakt)), pakt),
+		chunk("data", int64(4+len(data)), be32(1), data),
+	)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Artist != "Voice Memo" || track.Title != "Meeting" || track.Date != "2019" {
+		t.Errorf("expected artist, title and date from info chunk, but got %q, %q and %q", track.Artist, track.Title, track.Date)
+	}
+	if track.TrackNumber != "3" || track.Comments["tracktotal"] != "12" {
+		t.Errorf("expected track 3 of 12, but got %q of %q", track.TrackNumber, track.Comments["tracktotal"])
+	}
+	if track.Duration != 2*time.Second {
+		t.Errorf("expected duration 2s from packet table, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "AAC" || p.SampleRate != 44100 || p.Channels != 1 || p.TotalSamples != 88200 || p.Bitrate != 4000 {
+		t.Errorf("expected 88200 samples of mono AAC at 4 kbps, but got %+v", p)
+	}
+	if layout, ok := track.Extra["chan"].(*ChannelLayout); !ok || len(layout.Descriptions) != 1 || layout.Descriptions[0].Label != 42 {
+		t.Errorf("expected channel layout in Extra, but got %+v", track.Extra["chan"])
+	}
+}
+
+func TestDecodePCM(t *testing.T) {
+	// Data chunk of unknown size extends to the end of file
+	data := make([]byte, 4+48000*2*3)
+	track, err := Decode(bytes.NewReader(file(
+		desc(48000, "lpcm", FlagIsLittleEndian, 6, 1, 2, 24),
+		chunk("data", -1, data),
+	)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Duration != time.Second {
+		t.Errorf("expected duration 1s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "PCM" || p.BitsPerSample != 24 || p.TotalSamples != 48000 || p.Bitrate != 48000*2*24 {
+		t.Errorf("expected 48000 samples of 24-bit stereo PCM, but got %+v", p)
+	}
+}
+
+func TestReadFileInvalid(t *testing.T) {
+	if _, err := ReadFile(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00"))); err != ErrNotCAF {
+		t.Errorf("expected ErrNotCAF, but got %v", err)
+	}
+	if _, err := ReadFile(bytes.NewReader(file(chunk("data", 4, be32(0))))); err != ErrNoDescription {
+		t.Errorf("expected ErrNoDescription, but got %v", err)
+	}
+}
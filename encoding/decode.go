@@ -11,7 +11,27 @@ package encoding
 import (
 	"io"
 
+	"github.com/audioid/audioid/encoding/ac3"
+	"github.com/audioid/audioid/encoding/adts"
+	"github.com/audioid/audioid/encoding/aiff"
+	"github.com/audioid/audioid/encoding/ape"
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/asf"
+	"github.com/audioid/audioid/encoding/caf"
+	"github.com/audioid/audioid/encoding/dsd"
 	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/encoding/matroska"
+	"github.com/audioid/audioid/encoding/midi"
+	"github.com/audioid/audioid/encoding/mp4"
+	"github.com/audioid/audioid/encoding/mpeg"
+	"github.com/audioid/audioid/encoding/musepack"
+	"github.com/audioid/audioid/encoding/ogg"
+	"github.com/audioid/audioid/encoding/tracker"
+	"github.com/audioid/audioid/encoding/tta"
+	"github.com/audioid/audioid/encoding/wav"
+	"github.com/audioid/audioid/encoding/wavpack"
 	"github.com/audioid/audioid/errors"
 	"github.com/audioid/audioid/metadata"
 	"github.com/audioid/audioid/utils"
@@ -20,7 +40,12 @@ import (
 )
 
 // Decode given Reader into a Track.
-// In current opensource release, this package supports only FLAC.
+// In current opensource release, this package supports FLAC,
+// MPEG audio (MP3), Ogg Vorbis, Opus, Ogg FLAC, MP4 (AAC and ALAC), Matroska, WebM,
+// WAV (including RF64, BW64 and Wave64), AIFF, AIFF-C, CAF, WavPack,
+// Monkey's Audio, Musepack, TTA, DSF, DSDIFF, ASF (WMA), raw AAC (ADTS),
+// AC-3, E-AC-3, tracker modules (MOD, S3M, XM and IT), Standard MIDI Files
+// and files carrying only APE or ID3v1 tags.
 func Decode(r io.ReadSeeker) (*metadata.Track, error) {
 	const detectionLength = 8
 	bb := bytebufferpool.Get()
@@ -38,7 +63,177 @@ func Decode(r io.ReadSeeker) (*metadata.Track, error) {
 			return nil, errors.Wrap("could not seek back", err)
 		}
 		return flac.DecodeFlacUsingBuffer(r, bb)
+	case isWave(bb.B):
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return wav.Decode(r)
+	case string(bb.B[:4]) == "FORM":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return aiff.Decode(r)
+	case string(bb.B[:4]) == "caff":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return caf.Decode(r)
+	case string(bb.B[:4]) == "wvpk":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return wavpack.Decode(r)
+	case string(bb.B[:4]) == "MAC ":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return ape.Decode(r)
+	case isMusepack(bb.B):
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return musepack.Decode(r)
+	case string(bb.B[:4]) == "TTA1":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return tta.Decode(r)
+	case string(bb.B) == string(asf.GUIDHeader[:detectionLength]):
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return asf.Decode(r)
+	case string(bb.B[:4]) == "\x1A\x45\xDF\xA3":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return matroska.Decode(r)
+	case string(bb.B[:4]) == "DSD " || string(bb.B[:4]) == "FRM8":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return dsd.Decode(r)
+	case string(bb.B[4:8]) == "ftyp":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return mp4.Decode(r)
+	case string(bb.B[:4]) == "MThd":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return midi.Decode(r)
+	case string(bb.B[:4]) == "OggS":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return ogg.Decode(r)
+	case string(bb.B[:3]) == "ID3":
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return decodeID3Prefixed(r)
+	case isAC3(bb.B):
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return ac3.Decode(r)
+	case isADTS(bb.B):
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return adts.Decode(r)
+	case isMPEGFrame(bb.B):
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		return mpeg.Decode(r)
+	}
+
+	// Signatures of MOD and S3M modules are far from the start of file.
+	format, err := tracker.Detect(r)
+	if err != nil {
+		return nil, err
+	}
+	if format != 0 {
+		return tracker.Decode(r)
+	}
+
+	// Legacy files may have nothing but APE or ID3v1 tags at the end.
+	track, err := apetag.Decode(r)
+	if err == nil {
+		if tag, err := id3v1.Read(r); err == nil {
+			tag.Apply(track)
+		}
+		return track, nil
+	} else if err != apetag.ErrNoTag {
+		return nil, err
+	}
+
+	track, err = id3v1.Decode(r)
+	if err == id3v1.ErrNoTag {
+		return nil, errors.New("unknown file type")
+	}
+	return track, err
+}
+
+// isWave detects RIFF WAVE and its 64-bit variants: RF64, BW64 and Sony Wave64.
+func isWave(b []byte) bool {
+	switch string(b[:4]) {
+	case "RIFF", "RF64", "BW64":
+		return true
+	case "riff":
+		// Wave64 starts with the riff GUID
+		return string(b[4:8]) == "\x2e\x91\xcf\x11"
 	}
+	return false
+}
+
+// isMusepack detects Musepack SV8 and SV7 streams.
+func isMusepack(b []byte) bool {
+	return string(b[:4]) == "MPCK" || string(b[:3]) == "MP+" && b[3]&0x0F == 7
+}
+
+// decodeID3Prefixed detects the format after ID3v2 tag.
+// It is mostly MP3, but Musepack, TTA, ADTS and AC-3 files
+// may start with ID3v2 tag too.
+func decodeID3Prefixed(r io.ReadSeeker) (*metadata.Track, error) {
+	if _, err := id3v2.Skip(r); err != nil {
+		return nil, err
+	}
+	var magic [4]byte
+	_, err := io.ReadFull(r, magic[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, errors.Wrap("could not read magic", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek back", err)
+	}
+
+	switch {
+	case isMusepack(magic[:]):
+		return musepack.Decode(r)
+	case string(magic[:]) == "TTA1":
+		return tta.Decode(r)
+	case isAC3(magic[:]):
+		return ac3.Decode(r)
+	case isADTS(magic[:]):
+		return adts.Decode(r)
+	}
+	return mpeg.Decode(r)
+}
+
+// isAC3 detects AC-3 and E-AC-3 sync word.
+func isAC3(b []byte) bool {
+	return b[0] == 0x0B && b[1] == 0x77
+}
+
+// isADTS detects ADTS sync word with layer bits, which are always 0.
+func isADTS(b []byte) bool {
+	return b[0] == 0xFF && b[1]&0xF6 == 0xF0
+}
 
-	return nil, errors.New("unknown file type")
+func isMPEGFrame(b []byte) bool {
+	_, err := mpeg.ParseFrameHeader(b)
+	return err == nil
 }
@@ -0,0 +1,72 @@
+// Package dsd implements DSD Stream File (DSF)
+// and Direct Stream Digital Interchange File Format (DSDIFF).
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package dsd
+
+import (
+	"io"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// maxChunkSize bounds DSDIFF property, comment and ID3 chunks, which are loaded
+// entirely. Sound data itself is never read.
+const maxChunkSize = 64 << 20
+
+var (
+	// ErrNotDSD is returned when file is neither DSF nor DSDIFF.
+	ErrNotDSD = errors.New("not a dsd file")
+	// ErrNoFormat is returned when file has no format description.
+	ErrNoFormat = errors.New("no dsd format chunk")
+)
+
+// Decode reads DSF or DSDIFF stream properties and tags
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil {
+		return nil, errors.Wrap("could not read magic", err)
+	}
+	if _, err := f.Seek(-4, io.SeekCurrent); err != nil {
+		return nil, errors.Wrap("could not seek back", err)
+	}
+
+	t := &metadata.Track{}
+	switch string(magic[:]) {
+	case "DSD ":
+		file, err := ReadDSF(f)
+		if err != nil {
+			return nil, errors.Wrap("could not decode dsf", err)
+		}
+		file.Apply(t)
+	case "FRM8":
+		file, err := ReadDFF(f)
+		if err != nil {
+			return nil, errors.Wrap("could not decode dsdiff", err)
+		}
+		file.Apply(t)
+	default:
+		return nil, ErrNotDSD
+	}
+	return t, nil
+}
+
+func readData(f io.ReadSeeker, offset, size int64) ([]byte, error) {
+	if size > maxChunkSize {
+		return nil, errors.New("dsd chunk is too large")
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk", err)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read chunk", err)
+	}
+	return b, nil
+}
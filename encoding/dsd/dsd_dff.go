@@ -0,0 +1,325 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package dsd
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// DFFChunkHeaderSize is the size of DSDIFF chunk identifier and 64-bit size.
+const DFFChunkHeaderSize = 12
+
+// Compression types of DSDIFF CMPR chunk.
+const (
+	CompressionDSD = "DSD "
+	CompressionDST = "DST "
+)
+
+// DFFChunk is a chunk header of DSDIFF file.
+type DFFChunk struct {
+	ID string
+	// Offset of the chunk data from the start of file.
+	Offset int64
+	// Size of the chunk data without header and padding byte.
+	Size int64
+}
+
+// DFFFile describes the parts of DSDIFF file relevant to metadata.
+//
+// ref: https://dsd-guide.com/sites/default/files/white-papers/DSDIFF_1.5_Spec.pdf
+type DFFFile struct {
+	// Version of the format from FVER chunk, e.g. 0x01050000 for 1.5.
+	Version    uint32
+	SampleRate uint32
+	// ChannelIDs are identifiers of channels, e.g. "SLFT" and "SRGT".
+	ChannelIDs      []string
+	Compression     string
+	CompressionName string
+	// SampleCount is the number of samples per channel.
+	SampleCount uint64
+	// Chunks are all top-level chunks in the order of the file.
+	Chunks []*DFFChunk
+	// Artist and Title are from DIIN chunk.
+	Artist   string
+	Title    string
+	Comments []string
+	// ID3 is a tag of "ID3 " chunk, which is not in the specification,
+	// but is written by the most of software.
+	ID3 *id3v2.Tag
+}
+
+// ReadDFF walks chunks of DSDIFF file.
+// f must be positioned at the start of the file.
+func ReadDFF(f io.ReadSeeker) (*DFFFile, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+	var header [DFFChunkHeaderSize + 4]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return nil, errors.Wrap("could not read form header", err)
+	}
+	if string(header[:4]) != "FRM8" || string(header[12:16]) != "DSD " {
+		return nil, ErrNotDSD
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+	if formEnd := start + DFFChunkHeaderSize + int64(binary.BigEndian.Uint64(header[4:12])); formEnd < end && formEnd > start {
+		end = formEnd
+	}
+
+	file := &DFFFile{Compression: CompressionDSD}
+	chunks, err := readDFFChunks(f, start+int64(len(header)), end)
+	if err != nil {
+		return nil, err
+	}
+	file.Chunks = chunks
+	hasProperties := false
+	for _, chunk := range chunks {
+		switch chunk.ID {
+		case "FVER", "PROP", "DIIN", "COMT", "ID3 ":
+			b, err := readData(f, chunk.Offset, chunk.Size)
+			if err != nil {
+				return nil, err
+			}
+			if err := file.parseChunk(chunk.ID, b); err != nil {
+				return nil, err
+			}
+			hasProperties = hasProperties || chunk.ID == "PROP"
+		case "DSD ":
+			// Samples of all channels are interleaved bytes of 8 samples
+			if channels := uint64(len(file.ChannelIDs)); channels != 0 {
+				file.SampleCount = uint64(chunk.Size) * 8 / channels
+			}
+		case "DST ":
+			// Frame information is the first local chunk of compressed sound data
+			size := chunk.Size
+			if size > DFFChunkHeaderSize+6 {
+				size = DFFChunkHeaderSize + 6
+			}
+			b, err := readData(f, chunk.Offset, size)
+			if err != nil {
+				return nil, err
+			}
+			file.parseFrameInformation(b)
+		}
+	}
+	if !hasProperties || file.SampleRate == 0 {
+		return nil, ErrNoFormat
+	}
+	return file, nil
+}
+
+// readDFFChunks reads chunk headers from offset to end.
+// Size of the last chunk is truncated to the end of file.
+func readDFFChunks(f io.ReadSeeker, offset, end int64) ([]*DFFChunk, error) {
+	var chunks []*DFFChunk
+	for offset+DFFChunkHeaderSize <= end {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to chunk", err)
+		}
+		var b [DFFChunkHeaderSize]byte
+		if _, err := io.ReadFull(f, b[:]); err != nil {
+			return nil, errors.Wrap("could not read chunk header", err)
+		}
+		chunk := &DFFChunk{
+			ID:     string(b[:4]),
+			Offset: offset + DFFChunkHeaderSize,
+			Size:   int64(binary.BigEndian.Uint64(b[4:12])),
+		}
+		if chunk.Size < 0 || chunk.Offset+chunk.Size > end {
+			chunk.Size = end - chunk.Offset
+		}
+		chunks = append(chunks, chunk)
+		offset = chunk.Offset + chunk.Size + chunk.Size&1
+	}
+	return chunks, nil
+}
+
+// walkDFF calls fn for every local chunk of b.
+func walkDFF(b []byte, fn func(id string, data []byte) error) error {
+	for len(b) >= DFFChunkHeaderSize {
+		id := string(b[:4])
+		size := binary.BigEndian.Uint64(b[4:12])
+		b = b[DFFChunkHeaderSize:]
+		if size > uint64(len(b)) {
+			size = uint64(len(b))
+		}
+		if err := fn(id, b[:size]); err != nil {
+			return err
+		}
+		b = b[size:]
+		if size&1 == 1 && len(b) > 0 {
+			b = b[1:]
+		}
+	}
+	return nil
+}
+
+func (file *DFFFile) parseChunk(id string, b []byte) error {
+	switch id {
+	case "FVER":
+		if len(b) >= 4 {
+			file.Version = binary.BigEndian.Uint32(b)
+		}
+	case "PROP":
+		if len(b) < 4 || string(b[:4]) != "SND " {
+			return errors.New("dsdiff property chunk is not a sound property")
+		}
+		return walkDFF(b[4:], file.parseProperty)
+	case "DIIN":
+		return walkDFF(b, func(id string, data []byte) error {
+			switch id {
+			case "DIAR":
+				file.Artist = parseDFFText(data)
+			case "DITI":
+				file.Title = parseDFFText(data)
+			}
+			return nil
+		})
+	case "COMT":
+		file.Comments = parseDFFComments(b)
+	case "ID3 ":
+		// A broken tag is ignored, format and DIIN are still useful
+		if tag, err := id3v2.Parse(b); err == nil {
+			file.ID3 = tag
+		}
+	}
+	return nil
+}
+
+func (file *DFFFile) parseProperty(id string, b []byte) error {
+	switch id {
+	case "FS  ":
+		if len(b) >= 4 {
+			file.SampleRate = binary.BigEndian.Uint32(b)
+		}
+	case "CHNL":
+		if len(b) < 2 {
+			return errors.New("dsdiff channels chunk is too short")
+		}
+		count := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		file.ChannelIDs = nil
+		for i := 0; i < count && len(b) >= 4; i++ {
+			file.ChannelIDs = append(file.ChannelIDs, string(b[:4]))
+			b = b[4:]
+		}
+	case "CMPR":
+		if len(b) >= 4 {
+			file.Compression = string(b[:4])
+		}
+		if len(b) >= 5 {
+			n := int(b[4])
+			if n > len(b)-5 {
+				n = len(b) - 5
+			}
+			file.CompressionName = utils.TrimFixed(string(b[5 : 5+n]))
+		}
+	}
+	return nil
+}
+
+// parseFrameInformation parses FRTE chunk of DST sound data:
+// number of frames and frames per second.
+func (file *DFFFile) parseFrameInformation(b []byte) {
+	walkDFF(b, func(id string, data []byte) error {
+		if id == "FRTE" && len(data) >= 6 {
+			frames := uint64(binary.BigEndian.Uint32(data[0:4]))
+			if rate := uint64(binary.BigEndian.Uint16(data[4:6])); rate != 0 {
+				file.SampleCount = frames * uint64(file.SampleRate) / rate
+			}
+		}
+		return nil
+	})
+}
+
+// parseDFFText parses text of DIIN sub-chunks: 32-bit count and text.
+func parseDFFText(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	n := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	if uint64(n) < uint64(len(b)) {
+		b = b[:n]
+	}
+	return utils.TrimFixed(string(b))
+}
+
+// parseDFFComments parses COMT chunk: time stamp, type, reference,
+// 32-bit count and text of every comment.
+func parseDFFComments(b []byte) []string {
+	if len(b) < 2 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	var comments []string
+	for i := 0; i < count && len(b) >= 14; i++ {
+		n := int(binary.BigEndian.Uint32(b[10:14]))
+		b = b[14:]
+		if n > len(b) {
+			n = len(b)
+		}
+		comments = append(comments, utils.TrimFixed(string(b[:n])))
+		b = b[n:]
+		if n&1 == 1 && len(b) > 0 {
+			b = b[1:]
+		}
+	}
+	return comments
+}
+
+// Apply properties, DIIN, COMT and ID3 chunks to the track.
+// ID3 is richer than DIIN, so it overrides the same fields.
+func (file *DFFFile) Apply(t *metadata.Track) {
+	channels := uint32(len(file.ChannelIDs))
+	t.Properties = metadata.Properties{
+		Codec:         "DSD",
+		SampleRate:    file.SampleRate,
+		Channels:      uint8(channels),
+		BitsPerSample: 1,
+		TotalSamples:  file.SampleCount,
+	}
+	if file.Compression == CompressionDST {
+		t.Properties.Codec = "DST"
+	} else {
+		t.Properties.Bitrate = file.SampleRate * channels
+	}
+	t.Duration = metadata.SamplesDuration(file.SampleCount, file.SampleRate)
+
+	comments := map[string]string{}
+	if file.Artist != "" {
+		comments["artist"] = file.Artist
+	}
+	if file.Title != "" {
+		comments["title"] = file.Title
+	}
+	if len(file.Comments) != 0 {
+		comments["comment"] = strings.Join(file.Comments, "\n")
+	}
+	if len(comments) != 0 {
+		comment := &flac.VorbisComment{Comments: comments}
+		comment.Apply(t)
+	}
+
+	if file.ID3 != nil {
+		file.ID3.Apply(t)
+	}
+}
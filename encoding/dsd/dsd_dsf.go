@@ -0,0 +1,158 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package dsd
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// Sizes of DSF chunks.
+const (
+	DSFHeaderSize = 28
+	DSFFormatSize = 52
+
+	dsfChunkHeaderSize = 12
+)
+
+// ChannelType of DSF fmt chunk.
+type ChannelType uint32
+
+const (
+	ChannelMono    ChannelType = 1
+	ChannelStereo  ChannelType = 2
+	Channel3       ChannelType = 3
+	ChannelQuad    ChannelType = 4
+	Channel4       ChannelType = 5
+	Channel5       ChannelType = 6
+	Channel5Point1 ChannelType = 7
+)
+
+func (typ ChannelType) String() string {
+	switch typ {
+	case ChannelMono:
+		return "mono"
+	case ChannelStereo:
+		return "stereo"
+	case Channel3:
+		return "3 channels"
+	case ChannelQuad:
+		return "quad"
+	case Channel4:
+		return "4 channels"
+	case Channel5:
+		return "5 channels"
+	case Channel5Point1:
+		return "5.1 channels"
+	}
+	return "unknown"
+}
+
+// DSFFormat is a content of DSF fmt chunk.
+//
+// ref: https://dsd-guide.com/sites/default/files/white-papers/DSFFileFormatSpec_E.pdf
+type DSFFormat struct {
+	Version     uint32
+	FormatID    uint32
+	ChannelType ChannelType
+	Channels    uint32
+	// SampleRate is 2822400 for DSD64, 5644800 for DSD128 and so on.
+	SampleRate    uint32
+	BitsPerSample uint32
+	// SampleCount is the number of samples per channel.
+	SampleCount uint64
+	// BlockSize is the size of interleaved block per channel.
+	BlockSize uint32
+}
+
+// DSFFile describes the parts of DSF file relevant to metadata.
+type DSFFile struct {
+	FileSize uint64
+	// MetadataOffset points to ID3v2 tag at the end of file, or is 0.
+	MetadataOffset uint64
+	Format         *DSFFormat
+	DataSize       uint64
+	ID3            *id3v2.Tag
+}
+
+// ReadDSF reads DSD, fmt and data chunks of DSF file and its ID3v2 tag.
+// f must be positioned at the start of the file.
+func ReadDSF(f io.ReadSeeker) (*DSFFile, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+
+	var b [DSFHeaderSize + DSFFormatSize + dsfChunkHeaderSize]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return nil, errors.Wrap("could not read dsf header", err)
+	}
+	if string(b[:4]) != "DSD " {
+		return nil, ErrNotDSD
+	}
+	file := &DSFFile{
+		FileSize:       binary.LittleEndian.Uint64(b[12:20]),
+		MetadataOffset: binary.LittleEndian.Uint64(b[20:28]),
+	}
+
+	fmtChunk := b[DSFHeaderSize:]
+	if string(fmtChunk[:4]) != "fmt " {
+		return nil, ErrNoFormat
+	}
+	file.Format = &DSFFormat{
+		Version:       binary.LittleEndian.Uint32(fmtChunk[12:16]),
+		FormatID:      binary.LittleEndian.Uint32(fmtChunk[16:20]),
+		ChannelType:   ChannelType(binary.LittleEndian.Uint32(fmtChunk[20:24])),
+		Channels:      binary.LittleEndian.Uint32(fmtChunk[24:28]),
+		SampleRate:    binary.LittleEndian.Uint32(fmtChunk[28:32]),
+		BitsPerSample: binary.LittleEndian.Uint32(fmtChunk[32:36]),
+		SampleCount:   binary.LittleEndian.Uint64(fmtChunk[36:44]),
+		BlockSize:     binary.LittleEndian.Uint32(fmtChunk[44:48]),
+	}
+
+	dataChunk := fmtChunk[DSFFormatSize:]
+	if string(dataChunk[:4]) == "data" {
+		if size := binary.LittleEndian.Uint64(dataChunk[4:12]); size >= dsfChunkHeaderSize {
+			file.DataSize = size - dsfChunkHeaderSize
+		}
+	}
+
+	if file.MetadataOffset != 0 {
+		if _, err := f.Seek(start+int64(file.MetadataOffset), io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to id3v2 tag", err)
+		}
+		tag, err := id3v2.Read(f)
+		if err == nil {
+			file.ID3 = tag
+		} else if err != id3v2.ErrNoTag {
+			return nil, errors.Wrap("could not read id3v2 tag", err)
+		}
+	}
+	return file, nil
+}
+
+// Apply format properties and ID3v2 tag to the track.
+func (file *DSFFile) Apply(t *metadata.Track) {
+	format := file.Format
+	t.Properties = metadata.Properties{
+		Codec:         "DSD",
+		SampleRate:    format.SampleRate,
+		Channels:      uint8(format.Channels),
+		BitsPerSample: 1,
+		Bitrate:       format.SampleRate * format.Channels,
+		TotalSamples:  format.SampleCount,
+	}
+	t.Duration = metadata.SamplesDuration(format.SampleCount, format.SampleRate)
+
+	if file.ID3 != nil {
+		file.ID3.Apply(t)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package dsd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+var id3 = []byte("ID3\x03\x00\x00\x00\x00\x00\x12TIT2\x00\x00\x00\x08\x00\x00\x00ID3 Tit")
+
+func TestDecodeDSF(t *testing.T) {
+	const rate = 2822400
+	data := make([]byte, 4096*2)
+	b := make([]byte, DSFHeaderSize+DSFFormatSize+dsfChunkHeaderSize)
+	copy(b, "DSD ")
+	binary.LittleEndian.PutUint64(b[4:], DSFHeaderSize)
+	binary.LittleEndian.PutUint64(b[12:], uint64(len(b)+len(data)+len(id3)))
+	binary.LittleEndian.PutUint64(b[20:], uint64(len(b)+len(data)))
+
+	f := b[DSFHeaderSize:]
+	copy(f, "fmt ")
+	binary.LittleEndian.PutUint64(f[4:], DSFFormatSize)
+	binary.LittleEndian.PutUint32(f[12:], 1)
+	binary.LittleEndian.PutUint32(f[20:], uint32(ChannelStereo))
+	binary.LittleEndian.PutUint32(f[24:], 2)
+	binary.LittleEndian.PutUint32(f[28:], rate)
+	binary.LittleEndian.PutUint32(f[32:], 1)
+	binary.LittleEndian.PutUint64(f[36:], rate*3)
+	binary.LittleEndian.PutUint32(f[44:], 4096)
+
+	d := f[DSFFormatSize:]
+	copy(d, "data")
+	binary.LittleEndian.PutUint64(d[4:], uint64(dsfChunkHeaderSize+len(data)))
+
+	file := bytes.Join([][]byte{b, data, id3}, nil)
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "ID3 Tit" {
+		t.Errorf(`expected Title from ID3 to be "ID3 Tit", but got %q`, track.Title)
+	}
+	if track.Duration != 3*time.Second {
+		t.Errorf("expected duration 3s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "DSD" || p.SampleRate != rate || p.Channels != 2 || p.BitsPerSample != 1 || p.Bitrate != rate*2 {
+		t.Errorf("expected stereo DSD64, but got %+v", p)
+	}
+}
+
+func dffChunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, DFFChunkHeaderSize, DFFChunkHeaderSize+len(body)+1)
+	copy(b, id)
+	binary.BigEndian.PutUint64(b[4:], uint64(len(body)))
+	b = append(b, body...)
+	if len(body)&1 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func dffText(s string) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func dffProperties(compression string) []byte {
+	fs := make([]byte, 4)
+	binary.BigEndian.PutUint32(fs, 2822400*2)
+	return dffChunk("PROP", []byte("SND "),
+		dffChunk("FS  ", fs),
+		dffChunk("CHNL", []byte("\x00\x02SLFTSRGT")),
+		dffChunk("CMPR", []byte(compression)))
+}
+
+func TestDecodeDFF(t *testing.T) {
+	comment := []byte("\x00\x01\x07\xe3\x01\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x05Hello")
+	file := dffChunk("FRM8", []byte("DSD "),
+		dffChunk("FVER", []byte{1, 5, 0, 0}),
+		dffProperties("DSD \x0enot compressed"),
+		dffChunk("COMT", comment),
+		dffChunk("DSD ", make([]byte, 2822400*2*2/8)),
+		dffChunk("DIIN", dffChunk("DIAR", dffText("Artist")), dffChunk("DITI", dffText("Title"))),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Artist != "Artist" || track.Title != "Title" || track.Comments["comment"] != "Hello" {
+		t.Errorf("expected artist, title and comment from DIIN and COMT, but got %q, %q and %q", track.Artist, track.Title, track.Comments["comment"])
+	}
+	if track.Duration != time.Second {
+		t.Errorf("expected duration 1s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "DSD" || p.SampleRate != 2822400*2 || p.Channels != 2 || p.TotalSamples != 2822400*2 {
+		t.Errorf("expected 1 second of stereo DSD128, but got %+v", p)
+	}
+}
+
+func TestDecodeDFFInvalidID3(t *testing.T) {
+	file := dffChunk("FRM8", []byte("DSD "),
+		dffChunk("FVER", []byte{1, 5, 0, 0}),
+		dffProperties("DSD \x0enot compressed"),
+		dffChunk("DSD ", make([]byte, 2822400*2*2/8)),
+		dffChunk("DIIN", dffChunk("DITI", dffText("Title"))),
+		dffChunk("ID3 ", []byte("ID3\x09broken")),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Title" {
+		t.Errorf(`expected Title to be "Title", but got %q`, track.Title)
+	}
+	if track.Duration != time.Second {
+		t.Errorf("expected duration 1s, but got %s", track.Duration)
+	}
+}
+
+func TestReadDFFCompressed(t *testing.T) {
+	frte := []byte{0, 0, 0, 150, 0, 75}
+	file := dffChunk("FRM8", []byte("DSD "),
+		dffProperties("DST \x0eDST Encoded"),
+		dffChunk("DST ", dffChunk("FRTE", frte), dffChunk("DSTF", make([]byte, 100))),
+		dffChunk("ID3 ", id3),
+	)
+
+	dff, err := ReadDFF(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if dff.Compression != CompressionDST || dff.CompressionName != "DST Encoded" || dff.SampleCount != 2822400*2*2 {
+		t.Errorf("expected 2 seconds of DST, but got %+v", dff)
+	}
+	if dff.ID3 == nil {
+		t.Error("expected ID3 chunk")
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package flac
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/audioid/audioid/metadata"
+	"github.com/valyala/bytebufferpool"
+)
+
+func TestTrackComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		track    *metadata.Track
+		expected map[string]string
+	}{
+		{
+			name:     "empty",
+			track:    &metadata.Track{Comments: map[string]string{"mood": ""}},
+			expected: map[string]string{},
+		},
+		{
+			name:     "fields",
+			track:    &metadata.Track{Title: "Title", Contact: "mail@example.com", TrackNumber: "3"},
+			expected: map[string]string{"title": "Title", "contact": "mail@example.com", "tracknumber": "3"},
+		},
+		{
+			name: "comments",
+			track: &metadata.Track{
+				Artist:   "Field",
+				Comments: map[string]string{"artist": "Comment", "tracktotal": "12"},
+			},
+			expected: map[string]string{"artist": "Field", "tracktotal": "12"},
+		},
+	}
+
+	for _, test := range tests {
+		if comments := TrackComments(test.track); !reflect.DeepEqual(comments, test.expected) {
+			t.Errorf("%s: expected comments to be %q, but got %q", test.name, test.expected, comments)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		key   string
+		field func(t *metadata.Track) string
+	}{
+		{"title", func(t *metadata.Track) string { return t.Title }},
+		{"tracknumber", func(t *metadata.Track) string { return t.TrackNumber }},
+		{"contact", func(t *metadata.Track) string { return t.Contact }},
+		{"isrc", func(t *metadata.Track) string { return t.ISRC }},
+		{"mood", func(t *metadata.Track) string { return t.Comments["mood"] }},
+	}
+
+	for _, test := range tests {
+		track := &metadata.Track{}
+		(&VorbisComment{Comments: map[string]string{test.key: "value"}}).Apply(track)
+		if x := test.field(track); x != "value" {
+			t.Errorf(`expected %s to be "value", but got %q`, test.key, x)
+		}
+		if comments := TrackComments(track); comments[test.key] != "value" {
+			t.Errorf(`expected %s to be converted back, but got %q`, test.key, comments)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		comment  *VorbisComment
+		expected []byte
+	}{
+		{
+			comment:  &VorbisComment{Vendor: "v"},
+			expected: []byte("\x01\x00\x00\x00v\x00\x00\x00\x00"),
+		},
+		{
+			comment:  &VorbisComment{Comments: map[string]string{"title": "T", "artist": "A=B"}},
+			expected: []byte("\x00\x00\x00\x00\x02\x00\x00\x00\x0a\x00\x00\x00ARTIST=A=B\x07\x00\x00\x00TITLE=T"),
+		},
+	}
+
+	for _, test := range tests {
+		b := test.comment.Encode()
+		if !bytes.Equal(b, test.expected) {
+			t.Errorf("expected %q, but got %q", test.expected, b)
+		}
+
+		bb := bytebufferpool.Get()
+		decoded, err := ReadVorbisComment(bytes.NewReader(b), bb)
+		bytebufferpool.Put(bb)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if decoded.Vendor != test.comment.Vendor || len(decoded.Comments) != len(test.comment.Comments) {
+			t.Errorf("expected %+v to be read back, but got %+v", test.comment, decoded)
+		}
+		for key, value := range test.comment.Comments {
+			if decoded.Comments[key] != value {
+				t.Errorf("expected %s to be %q, but got %q", key, value, decoded.Comments[key])
+			}
+		}
+	}
+}
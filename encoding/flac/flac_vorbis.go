@@ -9,6 +9,7 @@ package flac
 import (
 	"encoding/binary"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/audioid/audioid/errors"
@@ -19,6 +20,19 @@ import (
 func (block *MetadataBlock) LoadVorbisComment(f io.ReadSeeker, bb *bytebufferpool.ByteBuffer) error {
 	block.Type = BlockTypeVorbisComment
 
+	comment, err := ReadVorbisComment(f, bb)
+	if err != nil {
+		return err
+	}
+
+	block.Data = comment
+	return nil
+}
+
+// ReadVorbisComment reads Vorbis comment structure from r using given bb.
+// The same structure is used by FLAC, Ogg Vorbis and Opus,
+// so r should be positioned right at vendor length.
+func ReadVorbisComment(r io.Reader, bb *bytebufferpool.ByteBuffer) (*VorbisComment, error) {
 	comment := &VorbisComment{
 		Comments: map[string]string{},
 	}
@@ -28,42 +42,71 @@ func (block *MetadataBlock) LoadVorbisComment(f io.ReadSeeker, bb *bytebufferpoo
 	// length is uint32 according to
 	// https://xiph.org/flac/api/structFLAC____StreamMetadata__VorbisComment__Entry.html
 	vendorLen := uint32(0)
-	err := binary.Read(f, binary.LittleEndian, &vendorLen)
+	err := binary.Read(r, binary.LittleEndian, &vendorLen)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	vendor, err := utils.ReadCString(bb, f, vendorLen)
+	vendor, err := utils.ReadCString(bb, r, vendorLen)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	comment.Vendor = vendor
 
 	commentsLength := uint32(0)
-	err = binary.Read(f, binary.LittleEndian, &commentsLength)
+	err = binary.Read(r, binary.LittleEndian, &commentsLength)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	for i := uint32(0); i < commentsLength; i++ {
 		length := uint32(0)
-		err = binary.Read(f, binary.LittleEndian, &length)
+		err = binary.Read(r, binary.LittleEndian, &length)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		s, err := utils.ReadCString(bb, f, length)
+		s, err := utils.ReadCString(bb, r, length)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		parts := strings.Split(s, "=")
+		// Value may contain "=" as well, only the first one separates the key
+		parts := strings.SplitN(s, "=", 2)
 		if len(parts) != 2 {
-			return errors.New("Invalid vorbis comment: " + s)
+			return nil, errors.New("Invalid vorbis comment: " + s)
 		}
 		// Key is case-insensitive
 		// https://www.xiph.org/vorbis/doc/v-comment.html
 		comment.Comments[strings.ToLower(parts[0])] = parts[1]
 	}
 
-	block.Data = comment
-	return nil
+	return comment, nil
+}
+
+// Encode serializes the comment into the structure read by ReadVorbisComment,
+// without Vorbis framing bit. Keys are written in upper case and sorted,
+// so the result does not depend on map order.
+func (vc *VorbisComment) Encode() []byte {
+	keys := make([]string, 0, len(vc.Comments))
+	for key := range vc.Comments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b := appendVorbisString(nil, vc.Vendor)
+	b = appendUint32LE(b, uint32(len(keys)))
+	for _, key := range keys {
+		b = appendVorbisString(b, strings.ToUpper(key)+"="+vc.Comments[key])
+	}
+	return b
+}
+
+func appendVorbisString(b []byte, s string) []byte {
+	b = appendUint32LE(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func appendUint32LE(b []byte, x uint32) []byte {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], x)
+	return append(b, n[:]...)
 }
@@ -86,6 +86,14 @@ func (stream *StreamInfo) Apply(t *metadata.Track) {
 		t.Duration = -1
 	}
 
+	t.Properties = metadata.Properties{
+		Codec:         "FLAC",
+		SampleRate:    stream.SampleRate,
+		Channels:      stream.Channels,
+		BitsPerSample: stream.BitsPerSample,
+		TotalSamples:  stream.TotalSamples,
+	}
+
 	t.Checksum = metadata.Checksum{
 		Algorithm: metadata.AlgoMD5,
 		Sum:       stream.MD5Sum,
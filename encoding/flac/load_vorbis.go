@@ -33,6 +33,8 @@ func (vc *VorbisComment) Apply(t *metadata.Track) {
 			t.License = value
 		case "organization":
 			t.Organization = value
+		case "contact":
+			t.Contact = value
 		case "description":
 			t.Description = value
 		case "genre":
@@ -51,3 +53,44 @@ func (vc *VorbisComment) Apply(t *metadata.Track) {
 		}
 	}
 }
+
+// TrackComments converts the track into Vorbis comment keys and values,
+// so writers of any format share the mapping of Apply.
+// Empty fields are omitted.
+func TrackComments(t *metadata.Track) map[string]string {
+	comments := map[string]string{}
+	for key, value := range t.Comments {
+		if value != "" {
+			comments[key] = value
+		}
+	}
+	fields := map[string]string{
+		"title":        t.Title,
+		"version":      t.Version,
+		"album":        t.Album,
+		"tracknumber":  t.TrackNumber,
+		"artist":       t.Artist,
+		"performer":    t.Performer,
+		"copyright":    t.Copyright,
+		"license":      t.License,
+		"organization": t.Organization,
+		"contact":      t.Contact,
+		"description":  t.Description,
+		"genre":        t.Genre,
+		"date":         t.Date,
+		"location":     t.Location,
+		"isrc":         t.ISRC,
+	}
+	for key, value := range fields {
+		if value != "" {
+			comments[key] = value
+		}
+	}
+	return comments
+}
+
+// NewVorbisComment converts the track into Vorbis comment
+// without vendor, so writers keep the vendor of the file.
+func NewVorbisComment(t *metadata.Track) *VorbisComment {
+	return &VorbisComment{Comments: TrackComments(t)}
+}
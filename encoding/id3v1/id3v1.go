@@ -0,0 +1,65 @@
+// Package id3v1 implements ID3v1, ID3v1.1 and enhanced "TAG+" tags.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package id3v1
+
+import "time"
+
+const (
+	// TagSize is the size of ID3v1 tag, which is always
+	// the last 128 bytes of the file.
+	TagSize = 128
+	// EnhancedTagSize is the size of "TAG+" tag,
+	// which is placed right before ID3v1 tag.
+	EnhancedTagSize = 227
+)
+
+// GenreNone is used by ID3v1 to mark missing genre.
+const GenreNone = 255
+
+// Speed as defined by enhanced "TAG+" tag.
+type Speed uint8
+
+const (
+	SpeedUnset    Speed = 0
+	SpeedSlow     Speed = 1
+	SpeedMedium   Speed = 2
+	SpeedFast     Speed = 3
+	SpeedHardcore Speed = 4
+)
+
+// Tag is an ID3v1 tag.
+//
+// ref: https://id3.org/ID3v1
+type Tag struct {
+	Title   string
+	Artist  string
+	Album   string
+	Year    string
+	Comment string
+	// Track number as defined by ID3v1.1.
+	// Zero implies ID3v1 tag without track number.
+	Track uint8
+	// Genre index in Genres table.
+	// GenreNone implies unknown genre.
+	Genre uint8
+	// Enhanced is an optional "TAG+" tag.
+	// Title, Artist and Album already include its extended values.
+	Enhanced *EnhancedTag
+}
+
+// EnhancedTag is an extended "TAG+" tag.
+// It is used rarely, but supported by some players.
+//
+// ref: https://en.wikipedia.org/wiki/ID3#ID3v1_and_ID3v1.1
+type EnhancedTag struct {
+	Speed Speed
+	// Genre is a free-text genre.
+	Genre string
+	// Start and End of the music in the file.
+	Start, End time.Duration
+}
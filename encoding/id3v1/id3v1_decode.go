@@ -0,0 +1,189 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v1
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+	"github.com/valyala/bytebufferpool"
+)
+
+var (
+	// ErrNoTag is returned when file has no ID3v1 tag.
+	ErrNoTag = errors.New("no id3v1 tag")
+)
+
+// Decode reads ID3v1 tag from the end of f
+// into *metadata.Track.
+// Duration is unknown, because ID3v1 does not describe audio.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	tag, err := Read(f)
+	if err != nil {
+		return nil, err
+	}
+	t := &metadata.Track{Duration: -1}
+	tag.Apply(t)
+	return t, nil
+}
+
+// Read reads ID3v1 tag and optional "TAG+" tag from the end of f.
+// ErrNoTag is returned if f has no ID3v1 tag.
+// Position of f is restored after reading.
+func Read(f io.ReadSeeker) (*Tag, error) {
+	pos, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+	defer f.Seek(pos, io.SeekStart)
+
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+	if end < TagSize {
+		return nil, ErrNoTag
+	}
+
+	if _, err := f.Seek(-TagSize, io.SeekEnd); err != nil {
+		return nil, errors.Wrap("could not seek to id3v1 tag", err)
+	}
+	utils.Grow(bb, TagSize)
+	if _, err := io.ReadFull(f, bb.B); err != nil {
+		return nil, errors.Wrap("could not read id3v1 tag", err)
+	}
+	if string(bb.B[:3]) != "TAG" {
+		return nil, ErrNoTag
+	}
+	raw := append([]byte(nil), bb.B...)
+
+	var ext []byte
+	if end >= TagSize+EnhancedTagSize {
+		if _, err := f.Seek(-TagSize-EnhancedTagSize, io.SeekEnd); err != nil {
+			return nil, errors.Wrap("could not seek to enhanced tag", err)
+		}
+		utils.Grow(bb, EnhancedTagSize)
+		if _, err := io.ReadFull(f, bb.B); err != nil {
+			return nil, errors.Wrap("could not read enhanced tag", err)
+		}
+		if string(bb.B[:4]) == "TAG+" {
+			ext = bb.B
+		}
+	}
+
+	return parse(raw, ext), nil
+}
+
+// parse builds a Tag from 128 bytes of ID3v1 tag
+// and optional 227 bytes of "TAG+" tag.
+func parse(raw, ext []byte) *Tag {
+	tag := &Tag{
+		Year:  text(raw[93:97]),
+		Genre: raw[127],
+	}
+
+	// ID3v1.1 uses the last two bytes of comment for a zero byte
+	// followed by the track number.
+	comment := raw[97:127]
+	if comment[28] == 0 && comment[29] != 0 {
+		tag.Track = comment[29]
+		comment = comment[:28]
+	}
+	tag.Comment = text(comment)
+
+	if ext == nil {
+		tag.Title = text(raw[3:33])
+		tag.Artist = text(raw[33:63])
+		tag.Album = text(raw[63:93])
+		return tag
+	}
+
+	// "TAG+" fields continue the ones of ID3v1 tag
+	tag.Title = text(concat(raw[3:33], ext[4:64]))
+	tag.Artist = text(concat(raw[33:63], ext[64:124]))
+	tag.Album = text(concat(raw[63:93], ext[124:184]))
+	tag.Enhanced = &EnhancedTag{
+		Speed: Speed(ext[184]),
+		Genre: text(ext[185:215]),
+		Start: parseTime(text(ext[215:221])),
+		End:   parseTime(text(ext[221:227])),
+	}
+	return tag
+}
+
+func concat(a, b []byte) []byte {
+	return append(append(make([]byte, 0, len(a)+len(b)), a...), b...)
+}
+
+func text(b []byte) string {
+	return utils.TrimFixed(utils.DecodeLatin1(b))
+}
+
+// parseTime parses "mmm:ss" time of "TAG+" tag.
+func parseTime(s string) time.Duration {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return 0
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0
+	}
+	sec, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+}
+
+// Size returns the number of bytes the tag occupies at the end of file,
+// including "TAG+" tag, if present.
+func (tag *Tag) Size() int64 {
+	if tag.Enhanced != nil {
+		return TagSize + EnhancedTagSize
+	}
+	return TagSize
+}
+
+// GenreName returns genre name of the tag.
+// Free-text genre of "TAG+" tag is preferred.
+func (tag *Tag) GenreName() string {
+	if tag.Enhanced != nil && tag.Enhanced.Genre != "" {
+		return tag.Enhanced.Genre
+	}
+	return GenreName(tag.Genre)
+}
+
+// Apply current Tag to the track.
+// ID3v1 is the poorest tag format, so only empty fields are filled:
+// values from richer tags, applied before, always take priority.
+func (tag *Tag) Apply(t *metadata.Track) {
+	utils.Fill(&t.Title, tag.Title)
+	utils.Fill(&t.Artist, tag.Artist)
+	utils.Fill(&t.Album, tag.Album)
+	utils.Fill(&t.Date, tag.Year)
+	utils.Fill(&t.Genre, tag.GenreName())
+	if tag.Track != 0 {
+		utils.Fill(&t.TrackNumber, strconv.Itoa(int(tag.Track)))
+	}
+	if tag.Comment != "" {
+		if t.Comments == nil {
+			t.Comments = map[string]string{}
+		}
+		if _, ok := t.Comments["comment"]; !ok {
+			t.Comments["comment"] = tag.Comment
+		}
+	}
+}
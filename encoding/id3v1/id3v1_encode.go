@@ -0,0 +1,70 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v1
+
+import (
+	"strconv"
+
+	"github.com/audioid/audioid/metadata"
+)
+
+// NewTag converts the track into ID3v1.1 tag.
+// Genres, which are not in Genres table, and track numbers
+// beyond 255 are dropped. Long values are truncated by Encode.
+func NewTag(t *metadata.Track) *Tag {
+	tag := &Tag{
+		Title:   t.Title,
+		Artist:  t.Artist,
+		Album:   t.Album,
+		Year:    t.Date,
+		Comment: t.Comments["comment"],
+		Genre:   GenreNone,
+	}
+	if n, err := strconv.ParseUint(t.TrackNumber, 10, 8); err == nil {
+		tag.Track = uint8(n)
+	}
+	if id, ok := GenreID(t.Genre); ok {
+		tag.Genre = id
+	}
+	return tag
+}
+
+// Encode serializes the tag into 128 bytes of ID3v1.1 tag,
+// or ID3v1 tag if it has no track number. Text is written in Latin-1,
+// where other characters are replaced by "?". "TAG+" tag is not written.
+func (tag *Tag) Encode() []byte {
+	b := make([]byte, TagSize)
+	copy(b, "TAG")
+	putText(b[3:33], tag.Title)
+	putText(b[33:63], tag.Artist)
+	putText(b[63:93], tag.Album)
+	putText(b[93:97], tag.Year)
+	if tag.Track != 0 {
+		putText(b[97:125], tag.Comment)
+		b[126] = tag.Track
+	} else {
+		putText(b[97:127], tag.Comment)
+	}
+	b[127] = tag.Genre
+	return b
+}
+
+// putText writes s into fixed-size field b, truncating it.
+// The rest of the field is left zero.
+func putText(b []byte, s string) {
+	i := 0
+	for _, r := range s {
+		if i == len(b) {
+			return
+		}
+		if r > 0xFF {
+			r = '?'
+		}
+		b[i] = byte(r)
+		i++
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v1
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/audioid/audioid/metadata"
+)
+
+func buildTag(title, artist, album, year, comment string, track, genre byte) []byte {
+	b := make([]byte, TagSize)
+	copy(b, "TAG")
+	copy(b[3:33], title)
+	copy(b[33:63], artist)
+	copy(b[63:93], album)
+	copy(b[93:97], year)
+	copy(b[97:127], comment)
+	b[126] = track
+	b[127] = genre
+	return b
+}
+
+func TestDecodeV11(t *testing.T) {
+	file := append([]byte("audio frames"), buildTag("Title", "Artist", "Album", "1999", "Comment", 7, 17)...)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if track.Title != "Title" {
+		t.Errorf(`expected Title to be "Title", but got %q`, track.Title)
+	}
+	if track.TrackNumber != "7" {
+		t.Errorf(`expected TrackNumber to be "7", but got %q`, track.TrackNumber)
+	}
+	if track.Genre != "Rock" {
+		t.Errorf(`expected Genre to be "Rock", but got %q`, track.Genre)
+	}
+	if track.Date != "1999" {
+		t.Errorf(`expected Date to be "1999", but got %q`, track.Date)
+	}
+	if x := track.Comments["comment"]; x != "Comment" {
+		t.Errorf(`expected Comments[comment] to be "Comment", but got %q`, x)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	track := &metadata.Track{
+		Title:       "Tïtle ☺",
+		Artist:      "An artist with a name longer than 30",
+		Date:        "1999-05-01",
+		Genre:       "rock",
+		TrackNumber: "7",
+		Comments:    map[string]string{"comment": "Comment"},
+	}
+	b := NewTag(track).Encode()
+	expected := buildTag("T\xeftle ?", "An artist with a name longer t", "", "1999", "Comment", 7, 17)
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected\n%q, but got\n%q", expected, b)
+	}
+}
+
+func TestDecodeEnhanced(t *testing.T) {
+	ext := make([]byte, EnhancedTagSize)
+	copy(ext, "TAG+")
+	copy(ext[4:], " Extended")
+	copy(ext[185:], "Chiptune")
+	copy(ext[215:], "001:30")
+
+	title := "A title that takes exactly 30."
+	file := append(ext, buildTag(title, "", "", "", "", 0, GenreNone)...)
+
+	tag, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if tag.Title != title+" Extended" {
+		t.Errorf("expected extended title, but got %q", tag.Title)
+	}
+	if tag.GenreName() != "Chiptune" {
+		t.Errorf(`expected genre to be "Chiptune", but got %q`, tag.GenreName())
+	}
+	if tag.Enhanced.Start != 90*time.Second {
+		t.Errorf("expected start to be 1m30s, but got %s", tag.Enhanced.Start)
+	}
+	if tag.Size() != TagSize+EnhancedTagSize {
+		t.Errorf("expected size %d, but got %d", TagSize+EnhancedTagSize, tag.Size())
+	}
+}
+
+func TestNoTag(t *testing.T) {
+	if _, err := Read(bytes.NewReader(make([]byte, 200))); err != ErrNoTag {
+		t.Errorf("expected ErrNoTag, but got %v", err)
+	}
+}
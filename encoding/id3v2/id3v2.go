@@ -0,0 +1,127 @@
+// Package id3v2 implements ID3v2 tags.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package id3v2
+
+import (
+	"io"
+
+	"github.com/audioid/audioid/errors"
+)
+
+const (
+	// HeaderSize is the size of ID3v2 tag header and footer.
+	HeaderSize = 10
+)
+
+// Header flags
+//
+// ref: https://id3.org/id3v2.4.0-structure
+const (
+	FlagUnsynchronisation = 1 << 7
+	FlagExtendedHeader    = 1 << 6
+	FlagExperimental      = 1 << 5
+	FlagFooter            = 1 << 4
+)
+
+var (
+	// ErrNoTag is returned when reader is not positioned at ID3v2 tag.
+	ErrNoTag = errors.New("no id3v2 tag")
+)
+
+// Header is ID3v2 tag header.
+type Header struct {
+	// Version is a major version, e.g. 3 for ID3v2.3.0
+	Version  uint8
+	Revision uint8
+	Flags    uint8
+	// Size of the tag, excluding header and footer.
+	Size uint32
+}
+
+// ReadHeader reads ID3v2 header from r.
+// ErrNoTag is returned if r does not start with ID3v2 header.
+func ReadHeader(r io.Reader) (*Header, error) {
+	var b [HeaderSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNoTag
+		}
+		return nil, errors.Wrap("could not read id3v2 header", err)
+	}
+	return parseHeader(b[:])
+}
+
+func parseHeader(b []byte) (*Header, error) {
+	if string(b[:3]) != "ID3" || b[3] == 0xFF || b[4] == 0xFF {
+		return nil, ErrNoTag
+	}
+	size, ok := synchsafe(b[6:10])
+	if !ok {
+		return nil, ErrNoTag
+	}
+	return &Header{
+		Version:  b[3],
+		Revision: b[4],
+		Flags:    b[5],
+		Size:     size,
+	}, nil
+}
+
+// TotalSize returns the size of the whole tag,
+// including header and footer.
+func (h *Header) TotalSize() int64 {
+	size := int64(HeaderSize) + int64(h.Size)
+	if h.Version >= 4 && h.Flags&FlagFooter != 0 {
+		size += HeaderSize
+	}
+	return size
+}
+
+// Skip seeks f past all ID3v2 tags starting at the current position,
+// and returns the number of skipped bytes.
+// Some taggers prepend a new tag instead of rewriting the old one,
+// so there may be more than one.
+func Skip(f io.ReadSeeker) (int64, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, errors.Wrap("could not get current position", err)
+	}
+
+	skipped := int64(0)
+	for {
+		h, err := ReadHeader(f)
+		if err == ErrNoTag {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		skipped += h.TotalSize()
+		if _, err := f.Seek(start+skipped, io.SeekStart); err != nil {
+			return 0, errors.Wrap("could not skip id3v2 tag", err)
+		}
+	}
+
+	if _, err := f.Seek(start+skipped, io.SeekStart); err != nil {
+		return 0, errors.Wrap("could not seek past id3v2 tags", err)
+	}
+	return skipped, nil
+}
+
+// synchsafe decodes 28-bit synchsafe integer,
+// where the most significant bit of every byte is zero.
+func synchsafe(b []byte) (uint32, bool) {
+	n := uint32(0)
+	for _, x := range b {
+		if x&0x80 != 0 {
+			return 0, false
+		}
+		n = n<<7 | uint32(x)
+	}
+	return n, true
+}
@@ -0,0 +1,179 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v2
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// frameKeys maps text information frames to Vorbis comment keys.
+// Frames, which need special handling, like TRCK, are not listed.
+var frameKeys = map[string]string{
+	"TIT1": "grouping",
+	"TIT2": "title",
+	"TIT3": "subtitle",
+	"TPE1": "artist",
+	"TPE2": "albumartist",
+	"TPE3": "conductor",
+	"TPE4": "remixer",
+	"TALB": "album",
+	"TCOM": "composer",
+	"TEXT": "lyricist",
+	"TCOP": "copyright",
+	"TPUB": "organization",
+	"TSRC": "isrc",
+	"TENC": "encodedby",
+	"TSSE": "encoder",
+	"TBPM": "bpm",
+	"TKEY": "key",
+	"TLAN": "language",
+	"TMED": "media",
+	"TCMP": "compilation",
+	"TSOA": "albumsort",
+	"TSOP": "artistsort",
+	"TSOT": "titlesort",
+	"TSO2": "albumartistsort",
+	"TSOC": "composersort",
+	"TYER": "date",
+	"TDRC": "date",
+	"TORY": "originaldate",
+	"TDOR": "originaldate",
+	"TOPE": "originalartist",
+	"TOAL": "originalalbum",
+}
+
+// musicBrainzOwner is the owner of UFID frame with MusicBrainz recording ID.
+const musicBrainzOwner = "http://musicbrainz.org"
+
+// Comments converts frames into Vorbis comment keys and values.
+// Multiple values are joined with "; ".
+func (tag *Tag) Comments() map[string]string {
+	comments := map[string]string{}
+	for _, frame := range tag.Frames {
+		switch {
+		case frame.ID == "TXXX":
+			if text, err := frame.UserText(); err == nil && text.Description != "" {
+				comments[strings.ToLower(text.Description)] = text.Value
+			}
+		case frame.ID == "COMM":
+			c, err := frame.Comment()
+			if err != nil {
+				continue
+			}
+			if c.Description == "" {
+				comments["comment"] = c.Text
+			} else if _, ok := comments[strings.ToLower(c.Description)]; !ok {
+				comments[strings.ToLower(c.Description)] = c.Text
+			}
+		case frame.ID == "USLT":
+			if c, err := frame.Comment(); err == nil {
+				comments["lyrics"] = c.Text
+			}
+		case frame.ID == "UFID":
+			if id, err := frame.UniqueFileID(); err == nil && id.Owner == musicBrainzOwner {
+				comments["musicbrainz_trackid"] = string(id.Identifier)
+			}
+		case frame.IsText():
+			values, err := frame.Text()
+			if err != nil || len(values) == 0 {
+				continue
+			}
+			tag.applyText(comments, frame.ID, values)
+		}
+	}
+	return comments
+}
+
+func (tag *Tag) applyText(comments map[string]string, id string, values []string) {
+	switch id {
+	case "TRCK":
+		utils.SetPair(comments, "tracknumber", "tracktotal", values[0])
+	case "TPOS":
+		utils.SetPair(comments, "discnumber", "disctotal", values[0])
+	case "TCON":
+		genres := make([]string, 0, len(values))
+		for _, value := range values {
+			genres = append(genres, parseGenre(value)...)
+		}
+		comments["genre"] = strings.Join(genres, "; ")
+	case "TYER":
+		// TDRC of ID3v2.4 is more precise
+		if _, ok := comments["date"]; !ok {
+			comments["date"] = values[0]
+		}
+	default:
+		if key, ok := frameKeys[id]; ok {
+			comments[key] = strings.Join(values, "; ")
+		}
+	}
+}
+
+// parseGenre parses TCON value, which may reference ID3v1 genres
+// as "(17)", "17" or "(17)Rock", and "(RX)" or "(CR)" for remix and cover.
+func parseGenre(value string) []string {
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 && n < len(id3v1.Genres) {
+		return []string{id3v1.Genres[n]}
+	}
+
+	var genres []string
+	for strings.HasPrefix(value, "(") && !strings.HasPrefix(value, "((") {
+		end := strings.IndexByte(value, ')')
+		if end < 0 {
+			break
+		}
+		ref := value[1:end]
+		value = value[end+1:]
+		switch ref {
+		case "RX":
+			genres = append(genres, "Remix")
+		case "CR":
+			genres = append(genres, "Cover")
+		default:
+			if n, err := strconv.Atoi(ref); err == nil && n >= 0 && n < len(id3v1.Genres) {
+				genres = append(genres, id3v1.Genres[n])
+			}
+		}
+	}
+
+	// Refinement text replaces the referenced genre
+	value = strings.TrimPrefix(value, "(")
+	if value != "" {
+		if len(genres) != 0 {
+			genres = genres[:len(genres)-1]
+		}
+		genres = append(genres, value)
+	}
+	return genres
+}
+
+// Apply current Tag to the track.
+func (tag *Tag) Apply(t *metadata.Track) {
+	comment := &flac.VorbisComment{Comments: tag.Comments()}
+	comment.Apply(t)
+
+	for _, frame := range tag.Frames {
+		if frame.ID != "APIC" {
+			continue
+		}
+		pic, err := frame.Picture(tag.Header.Version)
+		if err != nil {
+			continue
+		}
+		t.Pictures = append(t.Pictures, metadata.Picture{
+			MIME:          pic.MIME,
+			Description:   pic.Description,
+			Data:          pic.Data,
+			IsPictureLink: pic.MIME == "-->",
+		})
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v2
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// Frame flags of ID3v2.3
+const (
+	frameFlagCompressionV3 = 0x0080
+	frameFlagEncryptionV3  = 0x0040
+	frameFlagGroupingV3    = 0x0020
+)
+
+// Frame flags of ID3v2.4
+const (
+	frameFlagGroupingV4          = 0x0040
+	frameFlagCompressionV4       = 0x0008
+	frameFlagEncryptionV4        = 0x0004
+	frameFlagUnsynchronisationV4 = 0x0002
+	frameFlagDataLengthV4        = 0x0001
+)
+
+// Tag is an ID3v2 tag.
+//
+// ref: https://id3.org/id3v2.4.0-structure
+type Tag struct {
+	Header *Header
+	Frames []*Frame
+}
+
+// Read reads the whole ID3v2 tag from r.
+// ErrNoTag is returned if r does not start with ID3v2 header.
+func Read(r io.Reader) (*Tag, error) {
+	h, err := ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, h.Size)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, errors.Wrap("could not read id3v2 tag", err)
+	}
+	return parseTag(h, b)
+}
+
+// Parse parses ID3v2 tag from b, e.g. from an "id3 " chunk of WAV file.
+func Parse(b []byte) (*Tag, error) {
+	if len(b) < HeaderSize {
+		return nil, ErrNoTag
+	}
+	h, err := parseHeader(b)
+	if err != nil {
+		return nil, err
+	}
+	b = b[HeaderSize:]
+	if uint32(len(b)) < h.Size {
+		return nil, errors.New("id3v2 tag is truncated")
+	}
+	return parseTag(h, b[:h.Size])
+}
+
+func parseTag(h *Header, b []byte) (*Tag, error) {
+	tag := &Tag{Header: h}
+	switch h.Version {
+	case 2, 3, 4:
+	default:
+		return nil, errors.New("unsupported id3v2 version")
+	}
+
+	if h.Version < 4 && h.Flags&FlagUnsynchronisation != 0 {
+		b = removeUnsynchronisation(b)
+	}
+
+	if h.Flags&FlagExtendedHeader != 0 {
+		switch h.Version {
+		case 2:
+			// ID3v2.2 uses this flag for compression, which was never defined
+			return tag, nil
+		case 3:
+			if len(b) < 4 {
+				return nil, ErrInvalidFrame
+			}
+			b = skipBytes(b, 4+int(binary.BigEndian.Uint32(b)))
+		case 4:
+			if len(b) < 4 {
+				return nil, ErrInvalidFrame
+			}
+			size, ok := synchsafe(b[:4])
+			if !ok {
+				return nil, ErrInvalidFrame
+			}
+			b = skipBytes(b, int(size))
+		}
+	}
+
+	for len(b) != 0 {
+		frame, size, err := parseFrame(h, b)
+		if err != nil {
+			return nil, err
+		}
+		if frame == nil {
+			// padding
+			break
+		}
+		b = b[size:]
+		if frame.Data != nil {
+			tag.Frames = append(tag.Frames, frame)
+		}
+	}
+	return tag, nil
+}
+
+// parseFrame parses a frame at the start of b, and returns its size.
+// Nil frame is returned at padding. Frames, which could not be decoded,
+// e.g. encrypted ones, are returned without data.
+func parseFrame(h *Header, b []byte) (*Frame, int, error) {
+	headerSize := 10
+	if h.Version == 2 {
+		headerSize = 6
+	}
+	if len(b) < headerSize || b[0] == 0 {
+		return nil, 0, nil
+	}
+
+	frame := &Frame{}
+	var size int
+	switch h.Version {
+	case 2:
+		frame.ID = string(b[:3])
+		size = int(b[3])<<16 | int(b[4])<<8 | int(b[5])
+		if id, ok := v22Frames[frame.ID]; ok {
+			frame.ID = id
+		}
+	case 3:
+		frame.ID = string(b[:4])
+		size = int(binary.BigEndian.Uint32(b[4:8]))
+		frame.Flags = binary.BigEndian.Uint16(b[8:10])
+	case 4:
+		frame.ID = string(b[:4])
+		n, ok := synchsafe(b[4:8])
+		if !ok || !fitsFrame(b, headerSize+int(n)) {
+			// Some writers used plain integers for ID3v2.4 frame sizes
+			n = binary.BigEndian.Uint32(b[4:8])
+		}
+		size = int(n)
+		frame.Flags = binary.BigEndian.Uint16(b[8:10])
+	}
+	if headerSize+size > len(b) {
+		return nil, 0, errors.New("id3v2 frame " + strings.TrimSpace(frame.ID) + " is truncated")
+	}
+
+	data, err := decodeFrameData(h, frame.Flags, b[headerSize:headerSize+size])
+	if err != nil {
+		return nil, 0, errors.Wrap("could not decode id3v2 frame "+frame.ID, err)
+	}
+	frame.Data = data
+	return frame, headerSize + size, nil
+}
+
+// fitsFrame reports whether a frame of given size is followed by
+// the end of tag, padding or another frame.
+func fitsFrame(b []byte, size int) bool {
+	if size == len(b) {
+		return true
+	}
+	if size > len(b) {
+		return false
+	}
+	next := b[size:]
+	if next[0] == 0 {
+		return true
+	}
+	return len(next) >= 4 && isFrameID(next[:4])
+}
+
+func isFrameID(b []byte) bool {
+	for _, c := range b {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// maxFrameDataSize limits the size of decompressed frames.
+const maxFrameDataSize = 64 << 20
+
+// decodeFrameData removes frame-level unsynchronisation and compression.
+// Nil is returned for encrypted frames.
+func decodeFrameData(h *Header, flags uint16, b []byte) ([]byte, error) {
+	compressed := false
+	switch h.Version {
+	case 3:
+		if flags&frameFlagCompressionV3 != 0 {
+			// decompressed size
+			b = skipBytes(b, 4)
+			compressed = true
+		}
+		if flags&frameFlagEncryptionV3 != 0 {
+			return nil, nil
+		}
+		if flags&frameFlagGroupingV3 != 0 {
+			b = skipBytes(b, 1)
+		}
+	case 4:
+		if flags&frameFlagGroupingV4 != 0 {
+			b = skipBytes(b, 1)
+		}
+		if flags&frameFlagEncryptionV4 != 0 {
+			return nil, nil
+		}
+		if flags&frameFlagDataLengthV4 != 0 {
+			b = skipBytes(b, 4)
+		}
+		if flags&frameFlagUnsynchronisationV4 != 0 || h.Flags&FlagUnsynchronisation != 0 {
+			b = removeUnsynchronisation(b)
+		}
+		compressed = flags&frameFlagCompressionV4 != 0
+	}
+
+	if compressed {
+		zr, err := zlib.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		// A small frame may expand to gigabytes
+		b, err := ioutil.ReadAll(io.LimitReader(zr, maxFrameDataSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > maxFrameDataSize {
+			return nil, errors.New("decompressed id3v2 frame is too large")
+		}
+		return b, nil
+	}
+	return b, nil
+}
+
+// removeUnsynchronisation removes zero bytes, inserted after 0xFF
+// to prevent false MPEG sync words.
+func removeUnsynchronisation(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xFF && i+1 < len(b) && b[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
+
+func skipBytes(b []byte, n int) []byte {
+	if n > len(b) {
+		return []byte{}
+	}
+	return b[n:]
+}
@@ -0,0 +1,262 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v2
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// DefaultPadding is the padding, which leaves room
+// for small edits of the tag in place.
+const DefaultPadding = 1024
+
+// maxSize is the largest size of synchsafe integer.
+const maxSize = 1<<28 - 1
+
+// unknownLanguage is ISO-639-2 code of COMM and USLT frames,
+// when the language of the text is not known.
+const unknownLanguage = "XXX"
+
+// frameID returns text information frame of given Vorbis comment key,
+// reversing frameKeys. Dates are written by NewTag, as ID3v2.3
+// has other frames for them.
+func frameID(key string) (string, bool) {
+	for id, k := range frameKeys {
+		if k != key || id == "TYER" || id == "TORY" {
+			continue
+		}
+		return id, true
+	}
+	return "", false
+}
+
+// NewTag converts the track into ID3v2.3 or ID3v2.4 tag.
+// Text is written in given encoding, unless the version does not support it
+// or Latin-1 can not represent it, then UTF-16 is used.
+// Fields without dedicated frames are written as TXXX frames.
+func NewTag(t *metadata.Track, version uint8, enc Encoding) (*Tag, error) {
+	if version != 3 && version != 4 {
+		return nil, errors.New("unsupported id3v2 version for writing")
+	}
+	tag := &Tag{Header: &Header{Version: version}}
+	comments := flac.TrackComments(t)
+
+	keys := make([]string, 0, len(comments))
+	for key := range comments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := comments[key]
+		switch key {
+		case "tracknumber", "discnumber":
+			id, totalKey := "TRCK", "tracktotal"
+			if key == "discnumber" {
+				id, totalKey = "TPOS", "disctotal"
+			}
+			if total := comments[totalKey]; total != "" {
+				value += "/" + total
+			}
+			tag.Frames = append(tag.Frames, NewTextFrame(version, enc, id, value))
+		case "tracktotal", "disctotal":
+			// Written with the number
+		case "genre":
+			tag.Frames = append(tag.Frames, NewTextFrame(version, enc, "TCON", value))
+		case "date":
+			tag.Frames = append(tag.Frames, dateFrames(version, enc, value)...)
+		case "originaldate":
+			if version >= 4 {
+				tag.Frames = append(tag.Frames, NewTextFrame(version, enc, "TDOR", value))
+			} else {
+				tag.Frames = append(tag.Frames, yearFrame(version, enc, "TORY", key, value))
+			}
+		case "comment", "lyrics":
+			id := "COMM"
+			if key == "lyrics" {
+				id = "USLT"
+			}
+			tag.Frames = append(tag.Frames, NewCommentFrame(version, id, &Comment{
+				Encoding: enc,
+				Language: unknownLanguage,
+				Text:     value,
+			}))
+		case "musicbrainz_trackid":
+			tag.Frames = append(tag.Frames, NewUniqueFileIDFrame(&UniqueFileID{
+				Owner:      musicBrainzOwner,
+				Identifier: []byte(value),
+			}))
+		default:
+			if id, ok := frameID(key); ok {
+				tag.Frames = append(tag.Frames, NewTextFrame(version, enc, id, value))
+				continue
+			}
+			tag.Frames = append(tag.Frames, NewUserTextFrame(version, &UserText{
+				Encoding:    enc,
+				Description: strings.ToUpper(key),
+				Value:       value,
+			}))
+		}
+	}
+
+	for i, pic := range t.Pictures {
+		// The first picture is assumed to be the front cover
+		picType := byte(0)
+		if i == 0 {
+			picType = 3
+		}
+		tag.Frames = append(tag.Frames, NewPictureFrame(version, &Picture{
+			Encoding:    enc,
+			MIME:        pic.MIME,
+			Type:        picType,
+			Description: pic.Description,
+			Data:        pic.Data,
+		}))
+	}
+	return tag, nil
+}
+
+// dateFrames returns TDRC frame for ID3v2.4. ID3v2.3 has only year
+// in TYER frame, day and month are written in TDAT frame as "DDMM".
+func dateFrames(version uint8, enc Encoding, date string) []*Frame {
+	if version >= 4 {
+		return []*Frame{NewTextFrame(version, enc, "TDRC", date)}
+	}
+	frames := []*Frame{yearFrame(version, enc, "TYER", "date", date)}
+	if frames[0].ID == "TYER" && len(date) >= 10 && date[4] == '-' && date[7] == '-' {
+		frames = append(frames, NewTextFrame(version, enc, "TDAT", date[8:10]+date[5:7]))
+	}
+	return frames
+}
+
+// yearFrame returns ID3v2.3 frame id, which holds only the year of date.
+// Dates, which do not start with a year, are kept as TXXX frame
+// described by the Vorbis comment key.
+func yearFrame(version uint8, enc Encoding, id, key, date string) *Frame {
+	if len(date) < 4 || strings.Trim(date[:4], "0123456789") != "" {
+		return NewUserTextFrame(version, &UserText{
+			Encoding:    enc,
+			Description: strings.ToUpper(key),
+			Value:       date,
+		})
+	}
+	return NewTextFrame(version, enc, id, date[:4])
+}
+
+// NewTextFrame creates text information frame. Multiple values are
+// separated by terminator in ID3v2.4 and by "/" in ID3v2.3.
+func NewTextFrame(version uint8, enc Encoding, id string, values ...string) *Frame {
+	if version < 4 {
+		values = []string{strings.Join(values, "/")}
+	}
+	enc = encodingFor(version, enc, values...)
+	return &Frame{
+		ID:   id,
+		Data: append([]byte{byte(enc)}, encodeStrings(enc, values...)...),
+	}
+}
+
+// NewUserTextFrame creates TXXX frame.
+func NewUserTextFrame(version uint8, text *UserText) *Frame {
+	enc := encodingFor(version, text.Encoding, text.Description, text.Value)
+	return &Frame{
+		ID:   "TXXX",
+		Data: append([]byte{byte(enc)}, encodeStrings(enc, text.Description, text.Value)...),
+	}
+}
+
+// NewCommentFrame creates COMM or USLT frame, given by id.
+func NewCommentFrame(version uint8, id string, c *Comment) *Frame {
+	enc := encodingFor(version, c.Encoding, c.Description, c.Text)
+	language := c.Language
+	if len(language) != 3 {
+		language = unknownLanguage
+	}
+	b := append([]byte{byte(enc)}, language...)
+	return &Frame{
+		ID:   id,
+		Data: append(b, encodeStrings(enc, c.Description, c.Text)...),
+	}
+}
+
+// NewPictureFrame creates APIC frame.
+func NewPictureFrame(version uint8, pic *Picture) *Frame {
+	enc := encodingFor(version, pic.Encoding, pic.Description)
+	b := append([]byte{byte(enc)}, pic.MIME...)
+	b = append(b, 0, pic.Type)
+	b = append(b, encodeStrings(enc, pic.Description, "")...)
+	return &Frame{
+		ID:   "APIC",
+		Data: append(b, pic.Data...),
+	}
+}
+
+// NewUniqueFileIDFrame creates UFID frame.
+func NewUniqueFileIDFrame(id *UniqueFileID) *Frame {
+	b := append([]byte(id.Owner), 0)
+	return &Frame{
+		ID:   "UFID",
+		Data: append(b, id.Identifier...),
+	}
+}
+
+// Encode serializes the tag followed by padding of given size.
+// Frames are written without compression, encryption
+// and unsynchronisation, so all flags are cleared.
+// Frames without data, e.g. encrypted ones, and ID3v2.2 frames
+// without ID3v2.3 equivalent are dropped.
+func (tag *Tag) Encode(padding int) ([]byte, error) {
+	version := tag.Header.Version
+	if version != 3 && version != 4 {
+		return nil, errors.New("unsupported id3v2 version for writing")
+	}
+	if padding < 0 {
+		return nil, errors.New("negative id3v2 padding")
+	}
+
+	b := make([]byte, HeaderSize)
+	for _, frame := range tag.Frames {
+		if frame.Data == nil || len(frame.ID) != 4 {
+			continue
+		}
+		if len(frame.Data) > maxSize {
+			return nil, errors.Wrap("could not encode id3v2 frame "+frame.ID, ErrInvalidFrame)
+		}
+		var h [10]byte
+		copy(h[:], frame.ID)
+		if version == 4 {
+			putSynchsafe(h[4:8], uint32(len(frame.Data)))
+		} else {
+			binary.BigEndian.PutUint32(h[4:8], uint32(len(frame.Data)))
+		}
+		b = append(append(b, h[:]...), frame.Data...)
+	}
+	b = append(b, make([]byte, padding)...)
+
+	size := len(b) - HeaderSize
+	if size > maxSize {
+		return nil, errors.New("id3v2 tag is too large")
+	}
+	copy(b, "ID3")
+	b[3] = version
+	putSynchsafe(b[6:10], uint32(size))
+	return b, nil
+}
+
+// putSynchsafe encodes n as 28-bit synchsafe integer.
+func putSynchsafe(b []byte, n uint32) {
+	b[0] = byte(n >> 21 & 0x7F)
+	b[1] = byte(n >> 14 & 0x7F)
+	b[2] = byte(n >> 7 & 0x7F)
+	b[3] = byte(n & 0x7F)
+}
@@ -0,0 +1,174 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v2
+
+import (
+	"strings"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// Frame is a raw ID3v2 frame.
+// ID3v2.2 frame identifiers are converted to their ID3v2.3 equivalents.
+type Frame struct {
+	ID    string
+	Flags uint16
+	// Data of the frame, which is already decompressed
+	// and has unsynchronisation removed.
+	Data []byte
+}
+
+// Comment is a content of COMM and USLT frames.
+type Comment struct {
+	Encoding Encoding
+	// Language is ISO-639-2 code, e.g. "eng"
+	Language    string
+	Description string
+	Text        string
+}
+
+// UserText is a content of TXXX frame.
+type UserText struct {
+	Encoding    Encoding
+	Description string
+	Value       string
+}
+
+// Picture is a content of APIC frame.
+type Picture struct {
+	Encoding Encoding
+	MIME     string
+	// Type of the picture, same as FLAC picture types,
+	// e.g. 3 for the front cover.
+	Type        byte
+	Description string
+	Data        []byte
+}
+
+// UniqueFileID is a content of UFID frame.
+type UniqueFileID struct {
+	// Owner identifies the database, e.g. "http://musicbrainz.org"
+	Owner      string
+	Identifier []byte
+}
+
+var (
+	// ErrInvalidFrame is returned when frame content does not match its type.
+	ErrInvalidFrame = errors.New("invalid id3v2 frame")
+)
+
+// v22Frames maps ID3v2.2 frame identifiers to ID3v2.3 ones.
+var v22Frames = map[string]string{
+	"BUF": "RBUF", "CNT": "PCNT", "COM": "COMM", "CRA": "AENC", "ETC": "ETCO",
+	"GEO": "GEOB", "IPL": "IPLS", "LNK": "LINK", "MCI": "MCDI", "MLL": "MLLT",
+	"PIC": "APIC", "POP": "POPM", "REV": "RVRB", "RVA": "RVAD", "SLT": "SYLT",
+	"STC": "SYTC", "TAL": "TALB", "TBP": "TBPM", "TCM": "TCOM", "TCO": "TCON",
+	"TCP": "TCMP", "TCR": "TCOP", "TDA": "TDAT", "TDY": "TDLY", "TEN": "TENC",
+	"TFT": "TFLT", "TIM": "TIME", "TKE": "TKEY", "TLA": "TLAN", "TLE": "TLEN",
+	"TMT": "TMED", "TOA": "TOPE", "TOF": "TOFN", "TOL": "TOLY", "TOR": "TORY",
+	"TOT": "TOAL", "TP1": "TPE1", "TP2": "TPE2", "TP3": "TPE3", "TP4": "TPE4",
+	"TPA": "TPOS", "TPB": "TPUB", "TRC": "TSRC", "TRD": "TRDA", "TRK": "TRCK",
+	"TSI": "TSIZ", "TSS": "TSSE", "TT1": "TIT1", "TT2": "TIT2", "TT3": "TIT3",
+	"TXT": "TEXT", "TXX": "TXXX", "TYE": "TYER", "UFI": "UFID", "ULT": "USLT",
+	"WAF": "WOAF", "WAR": "WOAR", "WAS": "WOAS", "WCM": "WCOM", "WCP": "WCOP",
+	"WPB": "WPUB", "WXX": "WXXX",
+}
+
+// IsText reports whether the frame is a text information frame.
+func (f *Frame) IsText() bool {
+	return strings.HasPrefix(f.ID, "T") && f.ID != "TXXX"
+}
+
+// Text returns values of text information frame.
+// ID3v2.4 allows several values, separated by terminator.
+func (f *Frame) Text() ([]string, error) {
+	if !f.IsText() || len(f.Data) < 1 {
+		return nil, ErrInvalidFrame
+	}
+	return decodeStrings(Encoding(f.Data[0]), f.Data[1:]), nil
+}
+
+// UserText returns content of TXXX frame.
+func (f *Frame) UserText() (*UserText, error) {
+	if f.ID != "TXXX" || len(f.Data) < 1 {
+		return nil, ErrInvalidFrame
+	}
+	enc := Encoding(f.Data[0])
+	desc, value := splitString(enc, f.Data[1:])
+	values := decodeStrings(enc, value)
+	return &UserText{
+		Encoding:    enc,
+		Description: decodeString(enc, desc),
+		Value:       strings.Join(values, "; "),
+	}, nil
+}
+
+// Comment returns content of COMM or USLT frame.
+func (f *Frame) Comment() (*Comment, error) {
+	if (f.ID != "COMM" && f.ID != "USLT") || len(f.Data) < 4 {
+		return nil, ErrInvalidFrame
+	}
+	enc := Encoding(f.Data[0])
+	desc, text := splitString(enc, f.Data[4:])
+	text, _ = splitString(enc, text)
+	return &Comment{
+		Encoding:    enc,
+		Language:    string(f.Data[1:4]),
+		Description: decodeString(enc, desc),
+		Text:        decodeString(enc, text),
+	}, nil
+}
+
+// Picture returns content of APIC frame.
+// For ID3v2.2 PIC frame, MIME type is derived from the image format.
+func (f *Frame) Picture(version uint8) (*Picture, error) {
+	if f.ID != "APIC" || len(f.Data) < 2 {
+		return nil, ErrInvalidFrame
+	}
+	pic := &Picture{Encoding: Encoding(f.Data[0])}
+	b := f.Data[1:]
+
+	if version == 2 {
+		if len(b) < 4 {
+			return nil, ErrInvalidFrame
+		}
+		switch strings.ToUpper(string(b[:3])) {
+		case "JPG":
+			pic.MIME = "image/jpeg"
+		case "PNG":
+			pic.MIME = "image/png"
+		default:
+			pic.MIME = "image/" + strings.ToLower(string(b[:3]))
+		}
+		b = b[3:]
+	} else {
+		var mime []byte
+		mime, b = splitString(EncodingLatin1, b)
+		pic.MIME = string(mime)
+		if len(b) < 1 {
+			return nil, ErrInvalidFrame
+		}
+	}
+
+	pic.Type = b[0]
+	desc, data := splitString(pic.Encoding, b[1:])
+	pic.Description = decodeString(pic.Encoding, desc)
+	pic.Data = data
+	return pic, nil
+}
+
+// UniqueFileID returns content of UFID frame.
+func (f *Frame) UniqueFileID() (*UniqueFileID, error) {
+	if f.ID != "UFID" {
+		return nil, ErrInvalidFrame
+	}
+	owner, id := splitString(EncodingLatin1, f.Data)
+	return &UniqueFileID{
+		Owner:      string(owner),
+		Identifier: id,
+	}, nil
+}
@@ -0,0 +1,272 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v2
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/audioid/audioid/metadata"
+)
+
+func buildTag(version uint8, flags uint8, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	size := len(body)
+	return append([]byte{'I', 'D', '3', version, 0, flags,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}, body...)
+}
+
+func buildFrame(id string, data []byte) []byte {
+	b := make([]byte, 10, 10+len(data))
+	copy(b, id)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(data)))
+	return append(b, data...)
+}
+
+func TestReadV23(t *testing.T) {
+	file := buildTag(3, 0,
+		buildFrame("TIT2", []byte("\x01\xff\xfeT\x00i\x00t\x00l\x00e\x00")),
+		buildFrame("TRCK", []byte("\x003/12")),
+		buildFrame("TCON", []byte("\x00(17)")),
+		buildFrame("COMM", []byte("\x00engiTunNORM\x00 0000\x00")),
+		buildFrame("COMM", []byte("\x00eng\x00Comment")),
+		buildFrame("TXXX", []byte("\x03REPLAYGAIN_TRACK_GAIN\x00-7.89 dB")),
+		buildFrame("APIC", []byte("\x00image/png\x00\x03Cover\x00png")),
+	)
+
+	tag, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	track := &metadata.Track{}
+	tag.Apply(track)
+
+	if track.Title != "Title" {
+		t.Errorf(`expected Title to be "Title", but got %q`, track.Title)
+	}
+	if track.TrackNumber != "3" || track.Comments["tracktotal"] != "12" {
+		t.Errorf(`expected track 3 of 12, but got %q of %q`, track.TrackNumber, track.Comments["tracktotal"])
+	}
+	if track.Genre != "Rock" {
+		t.Errorf(`expected Genre to be "Rock", but got %q`, track.Genre)
+	}
+	if x := track.Comments["comment"]; x != "Comment" {
+		t.Errorf(`expected Comments[comment] to be "Comment", but got %q`, x)
+	}
+	if x := track.Comments["replaygain_track_gain"]; x != "-7.89 dB" {
+		t.Errorf(`expected Comments[replaygain_track_gain] to be "-7.89 dB", but got %q`, x)
+	}
+	if len(track.Pictures) != 1 || string(track.Pictures[0].Data) != "png" || track.Pictures[0].Description != "Cover" {
+		t.Errorf("expected a PNG cover, but got %+v", track.Pictures)
+	}
+}
+
+func TestReadV24Unsynchronisation(t *testing.T) {
+	// 0xFF 0x00 is unsynchronised as 0xFF 0x00 0x00
+	frame := buildFrame("TPE1", []byte("\x03A\xff\x00\x00B"))
+	frame[9] = frameFlagUnsynchronisationV4
+
+	tag, err := Read(bytes.NewReader(buildTag(4, 0, frame)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	values, err := tag.Frames[0].Text()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(values) != 2 || values[0] != "A\xff" || values[1] != "B" {
+		t.Errorf(`expected values "A\xff" and "B", but got %q`, values)
+	}
+}
+
+func TestReadV23Compressed(t *testing.T) {
+	compressed := func(data []byte) []byte {
+		var b bytes.Buffer
+		binary.Write(&b, binary.BigEndian, uint32(len(data)))
+		zw := zlib.NewWriter(&b)
+		zw.Write(data)
+		zw.Close()
+		frame := buildFrame("TIT2", b.Bytes())
+		frame[9] = frameFlagCompressionV3
+		return frame
+	}
+
+	tag, err := Read(bytes.NewReader(buildTag(3, 0, compressed([]byte("\x00Title")))))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if string(tag.Frames[0].Data) != "\x00Title" {
+		t.Errorf(`expected frame data "\x00Title", but got %q`, tag.Frames[0].Data)
+	}
+
+	if _, err := Read(bytes.NewReader(buildTag(3, 0, compressed(make([]byte, maxFrameDataSize+1))))); err == nil {
+		t.Error("expected frame, which decompresses beyond the limit, to be rejected")
+	}
+}
+
+func TestReadV22(t *testing.T) {
+	frame := []byte("TT2\x00\x00\x06\x00Title")
+	tag, err := Read(bytes.NewReader(buildTag(2, 0, frame)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if tag.Frames[0].ID != "TIT2" {
+		t.Errorf(`expected frame TT2 to be converted to "TIT2", but got %q`, tag.Frames[0].ID)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	track := &metadata.Track{
+		Title:       "Tïtle",
+		Artist:      "Артист",
+		TrackNumber: "3",
+		Genre:       "Rock",
+		Date:        "2019-05-01",
+		Performer:   "Performer",
+		Comments: map[string]string{
+			"tracktotal":          "12",
+			"comment":             "Comment",
+			"lyrics":              "La la",
+			"musicbrainz_trackid": "f1b1a4c2",
+		},
+		Pictures: []metadata.Picture{{MIME: "image/png", Description: "Cover", Data: []byte("png")}},
+	}
+
+	for _, version := range []uint8{3, 4} {
+		tag, err := NewTag(track, version, EncodingLatin1)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		b, err := tag.Encode(100)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		decoded, err := Read(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if decoded.Header.Version != version || len(decoded.Frames) != len(tag.Frames) {
+			t.Errorf("expected %d frames of ID3v2.%d, but got %d of ID3v2.%d", len(tag.Frames), version, len(decoded.Frames), decoded.Header.Version)
+		}
+
+		got := &metadata.Track{}
+		decoded.Apply(got)
+		if got.Title != track.Title || got.Artist != track.Artist || got.Performer != track.Performer {
+			t.Errorf("expected title, artist and performer of %+v, but got %+v", track, got)
+		}
+		if got.TrackNumber != "3" || got.Comments["tracktotal"] != "12" {
+			t.Errorf(`expected track 3 of 12, but got %q of %q`, got.TrackNumber, got.Comments["tracktotal"])
+		}
+		if x := got.Comments["lyrics"]; x != "La la" {
+			t.Errorf(`expected Comments[lyrics] to be "La la", but got %q`, x)
+		}
+		if x := got.Comments["musicbrainz_trackid"]; x != "f1b1a4c2" {
+			t.Errorf(`expected Comments[musicbrainz_trackid] to be "f1b1a4c2", but got %q`, x)
+		}
+		if len(got.Pictures) != 1 || string(got.Pictures[0].Data) != "png" {
+			t.Errorf("expected a PNG cover, but got %+v", got.Pictures)
+		}
+	}
+
+	tag, _ := NewTag(track, 3, EncodingUTF8)
+	for _, frame := range tag.Frames {
+		values, _ := frame.Text()
+		if frame.ID == "TDRC" || frame.ID == "TDAT" && values[0] != "0105" {
+			t.Errorf("expected ID3v2.3 date in TYER and TDAT, but got %s %q", frame.ID, values)
+		}
+		if frame.IsText() && Encoding(frame.Data[0]) != EncodingUTF16 {
+			t.Errorf("expected UTF-16 instead of UTF-8 in ID3v2.3 frame %s", frame.ID)
+		}
+	}
+
+	if _, err := tag.Encode(-1); err == nil {
+		t.Error("expected error for negative padding")
+	}
+}
+
+func TestEncodeV3Dates(t *testing.T) {
+	track := &metadata.Track{
+		Date:     "99",
+		Comments: map[string]string{"originaldate": "1975-03-01"},
+	}
+	tag, err := NewTag(track, 3, EncodingLatin1)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	frames := map[string]string{}
+	for _, frame := range tag.Frames {
+		if frame.ID == "TXXX" {
+			text, _ := frame.UserText()
+			frames[frame.ID] = text.Description + "=" + text.Value
+		} else if values, err := frame.Text(); err == nil {
+			frames[frame.ID] = values[0]
+		}
+	}
+	if len(frames) != 2 || frames["TORY"] != "1975" || frames["TXXX"] != "DATE=99" {
+		t.Errorf(`expected TORY "1975" and TXXX "DATE=99", but got %q`, frames)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "id3v2")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	audio := bytes.Repeat([]byte{0xFF, 0xFB, 0x90, 0x00}, 100)
+	old := buildTag(3, 0, buildFrame("TIT2", []byte("\x00Old title")), make([]byte, 200))
+	path := filepath.Join(dir, "file.mp3")
+	if err := ioutil.WriteFile(path, append(old, audio...), 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	check := func(title string) []byte {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		tag, err := Read(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		track := &metadata.Track{}
+		tag.Apply(track)
+		if track.Title != title {
+			t.Errorf("expected Title to be %q, but got %q", title, track.Title)
+		}
+		if !bytes.Equal(b[tag.Header.TotalSize():], audio) {
+			t.Errorf("expected audio to be kept")
+		}
+		return b
+	}
+
+	// Fits into the old tag with its padding
+	tag, _ := NewTag(&metadata.Track{Title: "New title"}, 4, EncodingUTF8)
+	if err := WriteFile(path, tag, DefaultPadding, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if b := check("New title"); len(b) != len(old)+len(audio) {
+		t.Errorf("expected tag to be written in place, but file size changed to %d", len(b))
+	}
+
+	tag, _ = NewTag(&metadata.Track{Title: string(bytes.Repeat([]byte("Long "), 100))}, 4, EncodingUTF8)
+	if err := WriteFile(path, tag, DefaultPadding, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if b := check(string(bytes.Repeat([]byte("Long "), 100))); len(b) < len(audio)+DefaultPadding+500 {
+		t.Errorf("expected file to be rewritten with padding, but got %d bytes", len(b))
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("expected temporary file to be renamed, but got %d files", len(files))
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+
+	"github.com/audioid/audioid/utils"
+)
+
+// Encoding of text in ID3v2 frames.
+type Encoding uint8
+
+const (
+	EncodingLatin1  Encoding = 0
+	EncodingUTF16   Encoding = 1
+	EncodingUTF16BE Encoding = 2
+	EncodingUTF8    Encoding = 3
+)
+
+// terminatorSize returns the size of string terminator in given encoding.
+func (enc Encoding) terminatorSize() int {
+	if enc == EncodingUTF16 || enc == EncodingUTF16BE {
+		return 2
+	}
+	return 1
+}
+
+// splitString returns the first terminated string of b
+// and the rest of b after the terminator.
+// If there is no terminator, whole b is returned as a string.
+func splitString(enc Encoding, b []byte) ([]byte, []byte) {
+	if enc.terminatorSize() == 1 {
+		if i := bytes.IndexByte(b, 0); i >= 0 {
+			return b[:i], b[i+1:]
+		}
+		return b, nil
+	}
+	// UTF-16 terminator is aligned to code units
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			return b[:i], b[i+2:]
+		}
+	}
+	return b, nil
+}
+
+// decodeString decodes b, which has no terminator.
+func decodeString(enc Encoding, b []byte) string {
+	switch enc {
+	case EncodingLatin1:
+		return utils.DecodeLatin1(b)
+	case EncodingUTF16:
+		var order binary.ByteOrder = binary.LittleEndian
+		if len(b) >= 2 {
+			switch {
+			case b[0] == 0xFE && b[1] == 0xFF:
+				order = binary.BigEndian
+				b = b[2:]
+			case b[0] == 0xFF && b[1] == 0xFE:
+				b = b[2:]
+			}
+		}
+		return decodeUTF16(order, b)
+	case EncodingUTF16BE:
+		return decodeUTF16(binary.BigEndian, b)
+	}
+	return string(b)
+}
+
+func decodeUTF16(order binary.ByteOrder, b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = order.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// decodeStrings decodes all terminated strings of b.
+// ID3v2.4 uses terminator to separate multiple values of text frames.
+func decodeStrings(enc Encoding, b []byte) []string {
+	var values []string
+	for len(b) != 0 {
+		var s []byte
+		s, b = splitString(enc, b)
+		values = append(values, decodeString(enc, s))
+	}
+	return values
+}
+
+// encodeString encodes s without terminator.
+// UTF-16 is written in little-endian byte order with BOM.
+// Characters, which Latin-1 lacks, are replaced with "?".
+func encodeString(enc Encoding, s string) []byte {
+	switch enc {
+	case EncodingLatin1:
+		b := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xFF {
+				r = '?'
+			}
+			b = append(b, byte(r))
+		}
+		return b
+	case EncodingUTF16:
+		return append([]byte{0xFF, 0xFE}, encodeUTF16(binary.LittleEndian, s)...)
+	case EncodingUTF16BE:
+		return encodeUTF16(binary.BigEndian, s)
+	}
+	return []byte(s)
+}
+
+func encodeUTF16(order binary.ByteOrder, s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, len(u)*2)
+	for i, x := range u {
+		order.PutUint16(b[i*2:], x)
+	}
+	return b
+}
+
+// encodeStrings encodes values separated by terminator.
+func encodeStrings(enc Encoding, values ...string) []byte {
+	var b []byte
+	for i, value := range values {
+		if i != 0 {
+			b = append(b, make([]byte, enc.terminatorSize())...)
+		}
+		b = append(b, encodeString(enc, value)...)
+	}
+	return b
+}
+
+// encodingFor returns enc, if it is supported by given version of ID3v2
+// and can represent all values. Otherwise UTF-16 is returned,
+// which both ID3v2.3 and ID3v2.4 support.
+func encodingFor(version uint8, enc Encoding, values ...string) Encoding {
+	if version < 4 && (enc == EncodingUTF8 || enc == EncodingUTF16BE) {
+		return EncodingUTF16
+	}
+	if enc == EncodingLatin1 {
+		for _, value := range values {
+			if _, ok := utils.EncodeLatin1(value); !ok {
+				return EncodingUTF16
+			}
+		}
+	}
+	return enc
+}
@@ -0,0 +1,109 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package id3v2
+
+import (
+	"io"
+	"os"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/utils"
+)
+
+// WriteFile replaces ID3v2 tags at the start of the file with tag.
+// If the tag fits into the space of existing tags, it is written in place,
+// and padding fills the rest of that space. Otherwise the tag followed
+// by given padding and the rest of the file are written to a new file,
+// which replaces the original, so audio data is kept byte by byte
+// and the original stays intact on failure. In-place writes are journaled,
+// see utils.PatchFile. opts may be nil.
+func WriteFile(path string, tag *Tag, padding int, opts *utils.WriteOptions) error {
+	return WriteFileWithTrailer(path, tag, padding, nil, opts)
+}
+
+// Trailer returns the offset, where audio data of f ends, and tags,
+// which replace the rest of the file after it. size is the size of f.
+type Trailer func(f *os.File, size int64) (end int64, tags []byte, err error)
+
+// WriteFileWithTrailer is WriteFile, which also replaces tags at the end
+// of the file, e.g. APE and ID3v1 tags, as returned by trailer.
+// Both ends of the file are written by one journaled patch or replacement,
+// so they are never left out of sync. trailer may be nil.
+func WriteFileWithTrailer(path string, tag *Tag, padding int, trailer Trailer, opts *utils.WriteOptions) error {
+	// Padding is unused by in-place writes, but must be valid either way
+	if padding < 0 {
+		return errors.New("negative id3v2 padding")
+	}
+	b, err := tag.Encode(0)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.RecoverFile(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	defer f.Close()
+	size, err := Skip(f)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrap("could not stat file", err)
+	}
+	end := info.Size()
+	var trailing []byte
+	if trailer != nil {
+		e, t, err := trailer(f, end)
+		if err != nil {
+			return err
+		}
+		// Trailing tags inside the ID3v2 tags are not real
+		if e >= size {
+			end, trailing = e, t
+		}
+	}
+	f.Close()
+
+	if int64(len(b)) <= size && size-HeaderSize <= maxSize {
+		b, err := tag.Encode(int(size) - len(b))
+		if err != nil {
+			return err
+		}
+		patches := []utils.Patch{{Offset: 0, Data: b}}
+		newSize := int64(-1)
+		if end != info.Size() || trailing != nil {
+			patches = append(patches, utils.Patch{Offset: end, Data: trailing})
+			newSize = end + int64(len(trailing))
+		}
+		return utils.PatchFile(path, newSize, patches, opts)
+	}
+
+	b, err = tag.Encode(padding)
+	if err != nil {
+		return err
+	}
+	return utils.ReplaceFile(path, opts, func(w io.Writer, original *os.File) error {
+		if _, err := w.Write(b); err != nil {
+			return errors.Wrap("could not write id3v2 tag", err)
+		}
+		if _, err := original.Seek(size, io.SeekStart); err != nil {
+			return errors.Wrap("could not seek to audio", err)
+		}
+		if _, err := io.CopyN(w, original, end-size); err != nil {
+			return errors.Wrap("could not copy audio", err)
+		}
+		if _, err := w.Write(trailing); err != nil {
+			return errors.Wrap("could not write trailing tags", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,241 @@
+// Package iff implements writing of Interchange File Format chunks,
+// shared by big-endian IFF, e.g. AIFF, and little-endian RIFF, e.g. WAVE.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package iff
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/utils"
+)
+
+// ChunkHeaderSize is the size of chunk identifier and size.
+const ChunkHeaderSize = 8
+
+// formHeaderSize is the size of form chunk header and form type.
+const formHeaderSize = 12
+
+// maxMovedSize limits the size of chunks, which are read into memory
+// to be moved within the file, when it is written in place.
+const maxMovedSize = 64 << 20
+
+// Form describes the container of the file.
+type Form struct {
+	// ID is the identifier of the form chunk, e.g. "FORM" or "RIFF".
+	ID string
+	// Type is the form type, e.g. "AIFF" or "WAVE".
+	Type  string
+	Order binary.ByteOrder
+	// Filler is the identifier of chunks, which fill the space
+	// left by smaller chunks written in place, e.g. "JUNK".
+	Filler string
+}
+
+// Chunk is a top-level chunk of the written file.
+// Chunks with nil Data are copied from the original file.
+type Chunk struct {
+	ID string
+	// Offset of the chunk data in the original file.
+	Offset int64
+	// Size of the chunk data without header and padding byte.
+	Size int64
+	// Data of a new chunk.
+	Data []byte
+}
+
+// New returns a new chunk with given data.
+func New(id string, data []byte) *Chunk {
+	if data == nil {
+		data = []byte{}
+	}
+	return &Chunk{ID: id, Size: int64(len(data)), Data: data}
+}
+
+// size returns the size of the chunk including header and padding byte.
+func (c *Chunk) size() int64 {
+	return ChunkHeaderSize + c.Size + c.Size&1
+}
+
+// Replace returns chunks, where the first chunk of each kind of updates
+// is replaced by the update and other chunks of that kind are dropped.
+// kinds are kinds of chunks, empty for chunks, which are always kept.
+// Updates of kinds, which the file does not have, are appended in given order.
+// A nil update drops all chunks of its kind.
+func Replace(chunks []*Chunk, kinds []string, order []string, updates map[string]*Chunk) []*Chunk {
+	out := make([]*Chunk, 0, len(chunks)+len(order))
+	done := map[string]bool{}
+	for i, c := range chunks {
+		k := kinds[i]
+		update, ok := updates[k]
+		if k == "" || !ok {
+			out = append(out, c)
+			continue
+		}
+		if !done[k] && update != nil {
+			out = append(out, update)
+		}
+		done[k] = true
+	}
+	for _, k := range order {
+		if update := updates[k]; update != nil && !done[k] {
+			out = append(out, update)
+		}
+	}
+	return out
+}
+
+// WriteFile writes chunks into the file at path in given order.
+// If the chunk identified by fixed, e.g. sound data, can stay at its offset,
+// the file is written in place: preceding chunks, which became smaller,
+// are followed by a filler chunk, and only chunks, which changed or moved,
+// are written with utils.PatchFile. Otherwise the file is rewritten
+// with utils.ReplaceFile. Form size and padding bytes of odd-sized chunks
+// are always fixed. opts may be nil.
+func WriteFile(path string, form *Form, chunks []*Chunk, fixed string, opts *utils.WriteOptions) error {
+	size := int64(formHeaderSize - ChunkHeaderSize)
+	for _, c := range chunks {
+		if len(c.ID) != 4 {
+			return errors.New("invalid chunk identifier " + c.ID)
+		}
+		size += c.size()
+	}
+	if size > math.MaxUint32 {
+		return errors.New("form " + form.ID + " is too large")
+	}
+
+	if err := utils.RecoverFile(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	defer f.Close()
+	patches, end, err := patchInPlace(f, form, chunks, fixed)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	if patches != nil {
+		return utils.PatchFile(path, end, patches, opts)
+	}
+
+	return utils.ReplaceFile(path, opts, func(w io.Writer, original *os.File) error {
+		bw := bufio.NewWriter(w)
+		if _, err := bw.Write(form.header(size)); err != nil {
+			return errors.Wrap("could not write form header", err)
+		}
+		for _, c := range chunks {
+			if _, err := bw.Write(form.chunkHeader(c)); err != nil {
+				return errors.Wrap("could not write chunk header", err)
+			}
+			if c.Data != nil {
+				if _, err := bw.Write(c.Data); err != nil {
+					return errors.Wrap("could not write chunk "+c.ID, err)
+				}
+			} else {
+				if _, err := original.Seek(c.Offset, io.SeekStart); err != nil {
+					return errors.Wrap("could not seek to chunk "+c.ID, err)
+				}
+				if _, err := io.CopyN(bw, original, c.Size); err != nil {
+					return errors.Wrap("could not copy chunk "+c.ID, err)
+				}
+			}
+			if c.Size&1 == 1 {
+				if err := bw.WriteByte(0); err != nil {
+					return errors.Wrap("could not write padding byte", err)
+				}
+			}
+		}
+		return bw.Flush()
+	})
+}
+
+// patchInPlace returns patches, which write chunks into f in place,
+// and the new size of the file, if the fixed chunk keeps its offset.
+// Filler chunks before the fixed chunk, e.g. left by previous writes,
+// are dropped, so their space is reused.
+func patchInPlace(f *os.File, form *Form, chunks []*Chunk, fixed string) ([]utils.Patch, int64, error) {
+	k := 0
+	for k < len(chunks) && (chunks[k].ID != fixed || chunks[k].Data != nil) {
+		k++
+	}
+	if k == len(chunks) {
+		return nil, 0, nil
+	}
+	kept := make([]*Chunk, 0, len(chunks))
+	for i, c := range chunks {
+		if i < k && c.ID == form.Filler && c.Data == nil {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	k -= len(chunks) - len(kept)
+	chunks = kept
+
+	offset := int64(formHeaderSize)
+	for _, c := range chunks[:k] {
+		offset += c.size()
+	}
+	free := chunks[k].Offset - ChunkHeaderSize - offset
+	switch {
+	case free == 0:
+	case free >= ChunkHeaderSize && free&1 == 0:
+		filler := New(form.Filler, make([]byte, free-ChunkHeaderSize))
+		chunks = append(append(append([]*Chunk(nil), chunks[:k]...), filler), chunks[k:]...)
+	default:
+		return nil, 0, nil
+	}
+
+	// Chunks, which move, are read, because patches overwrite them
+	var patches []utils.Patch
+	moved := int64(0)
+	offset = formHeaderSize
+	for _, c := range chunks {
+		data := c.Data
+		if data == nil && c.Offset != offset+ChunkHeaderSize {
+			if moved += c.Size; moved > maxMovedSize {
+				return nil, 0, nil
+			}
+			data = make([]byte, c.Size)
+			if _, err := f.ReadAt(data, c.Offset); err != nil {
+				return nil, 0, errors.Wrap("could not read chunk "+c.ID, err)
+			}
+		}
+		if data != nil {
+			b := append(form.chunkHeader(c), data...)
+			if len(data)&1 == 1 {
+				b = append(b, 0)
+			}
+			patches = append(patches, utils.Patch{Offset: offset, Data: b})
+		}
+		offset += c.size()
+	}
+	patches = append(patches, utils.Patch{Offset: 0, Data: form.header(offset - ChunkHeaderSize)})
+	return patches, offset, nil
+}
+
+func (form *Form) header(size int64) []byte {
+	b := make([]byte, formHeaderSize)
+	copy(b, form.ID)
+	form.Order.PutUint32(b[4:8], uint32(size))
+	copy(b[8:], form.Type)
+	return b
+}
+
+func (form *Form) chunkHeader(c *Chunk) []byte {
+	b := make([]byte, ChunkHeaderSize)
+	copy(b, c.ID)
+	form.Order.PutUint32(b[4:8], uint32(c.Size))
+	return b
+}
@@ -0,0 +1,126 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package iff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testForm = &Form{ID: "FORM", Type: "TEST", Order: binary.BigEndian, Filler: "FLLR"}
+
+func mkchunk(id string, data string) []byte {
+	b := make([]byte, ChunkHeaderSize, ChunkHeaderSize+len(data)+1)
+	copy(b, id)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(data)))
+	b = append(b, data...)
+	if len(data)&1 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func mkform(chunks ...[]byte) []byte {
+	b := append([]byte("FORM\x00\x00\x00\x00TEST"), bytes.Join(chunks, nil)...)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(b)-8))
+	return b
+}
+
+// writeFile writes chunks over the file and returns the result.
+// Chunks of the file are listed in the order of data.
+func writeFile(t *testing.T, file []byte, update func(chunks []*Chunk) []*Chunk) []byte {
+	dir, err := ioutil.TempDir("", "iff")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.iff")
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	var chunks []*Chunk
+	for offset := int64(12); offset < int64(len(file)); {
+		size := int64(binary.BigEndian.Uint32(file[offset+4:]))
+		chunks = append(chunks, &Chunk{ID: string(file[offset : offset+4]), Offset: offset + ChunkHeaderSize, Size: size})
+		offset += ChunkHeaderSize + size + size&1
+	}
+	if err := WriteFile(path, testForm, update(chunks), "SSND", nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return b
+}
+
+func TestReplace(t *testing.T) {
+	a, b, c := &Chunk{ID: "AAAA"}, &Chunk{ID: "NAME"}, &Chunk{ID: "NAME"}
+	name, id3 := New("NAME", []byte("x")), New("ID3 ", nil)
+	chunks := Replace([]*Chunk{b, a, c}, []string{"NAME", "", "NAME"}, []string{"NAME", "ID3 ", "ANNO"},
+		map[string]*Chunk{"NAME": name, "ID3 ": id3, "ANNO": nil})
+	if !reflect.DeepEqual(chunks, []*Chunk{name, a, id3}) {
+		t.Errorf("expected replaced NAME, kept AAAA and appended ID3, but got %v", chunks)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	sound := mkchunk("SSND", "sound data")
+	file := mkform(mkchunk("COMM", "common"), mkchunk("NAME", "Long name"), sound, mkchunk("AUTH", "Author"))
+
+	// Smaller name is followed by filler, so sound data stays in place
+	b := writeFile(t, file, func(chunks []*Chunk) []*Chunk {
+		chunks[1] = New("NAME", []byte("N"))
+		return append(chunks, New("ANNO", []byte("Comment")))
+	})
+	expected := mkform(mkchunk("COMM", "common"), mkchunk("NAME", "N"), mkchunk("FLLR", ""), sound,
+		mkchunk("AUTH", "Author"), mkchunk("ANNO", "Comment"))
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected file to be written in place\n%q, but got\n%q", expected, b)
+	}
+
+	// Larger name moves sound data
+	b = writeFile(t, file, func(chunks []*Chunk) []*Chunk {
+		chunks[1] = New("NAME", []byte("Longer name"))
+		return chunks
+	})
+	expected = mkform(mkchunk("COMM", "common"), mkchunk("NAME", "Longer name"), sound, mkchunk("AUTH", "Author"))
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected file to be rewritten\n%q, but got\n%q", expected, b)
+	}
+}
+
+func TestWriteFileTwice(t *testing.T) {
+	sound := mkchunk("SSND", "sound data")
+	file := mkform(mkchunk("NAME", "A much longer name"), sound)
+	rename := func(name string) func(chunks []*Chunk) []*Chunk {
+		return func(chunks []*Chunk) []*Chunk {
+			chunks[0] = New("NAME", []byte(name))
+			return chunks
+		}
+	}
+
+	// The filler of the first write is reused by the second one
+	b := writeFile(t, writeFile(t, file, rename("N")), rename("Longer nam"))
+	expected := mkform(mkchunk("NAME", "Longer nam"), mkchunk("FLLR", ""), sound)
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected file to be written in place\n%q, but got\n%q", expected, b)
+	}
+
+	// Shrinking again leaves a single filler
+	b = writeFile(t, b, rename("N"))
+	expected = mkform(mkchunk("NAME", "N"), mkchunk("FLLR", "\x00\x00\x00\x00\x00\x00\x00\x00"), sound)
+	if !bytes.Equal(b, expected) {
+		t.Errorf("expected a single filler\n%q, but got\n%q", expected, b)
+	}
+}
@@ -0,0 +1,163 @@
+// Package matroska implements metadata of Matroska and WebM files.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package matroska
+
+import (
+	"github.com/audioid/audioid/errors"
+)
+
+// Element identifiers, with their length markers.
+//
+// ref: https://www.matroska.org/technical/elements.html
+const (
+	IDEBML    = 0x1A45DFA3
+	IDDocType = 0x4282
+
+	IDSegment      = 0x18538067
+	IDSeekHead     = 0x114D9B74
+	IDSeek         = 0x4DBB
+	IDSeekID       = 0x53AB
+	IDSeekPosition = 0x53AC
+	IDCluster      = 0x1F43B675
+
+	IDInfo           = 0x1549A966
+	IDTimestampScale = 0x2AD7B1
+	IDDuration       = 0x4489
+	IDTitle          = 0x7BA9
+	IDMuxingApp      = 0x4D80
+	IDWritingApp     = 0x5741
+
+	IDTracks            = 0x1654AE6B
+	IDTrackEntry        = 0xAE
+	IDTrackNumber       = 0xD7
+	IDTrackUID          = 0x73C5
+	IDTrackType         = 0x83
+	IDCodecID           = 0x86
+	IDName              = 0x536E
+	IDLanguage          = 0x22B59C
+	IDAudio             = 0xE1
+	IDSamplingFrequency = 0xB5
+	IDOutputSampling    = 0x78B5
+	IDChannels          = 0x9F
+	IDBitDepth          = 0x6264
+
+	IDTags            = 0x1254C367
+	IDTag             = 0x7373
+	IDTargets         = 0x63C0
+	IDTargetTypeValue = 0x68CA
+	IDTargetType      = 0x63CA
+	IDTagTrackUID     = 0x63C5
+	IDSimpleTag       = 0x67C8
+	IDTagName         = 0x45A3
+	IDTagLanguage     = 0x447A
+	IDTagDefault      = 0x4484
+	IDTagString       = 0x4487
+	IDTagBinary       = 0x4485
+)
+
+// TrackTypeAudio is a TrackType of audio tracks.
+const TrackTypeAudio = 2
+
+// Target type values of tags.
+const (
+	TargetCollection = 70
+	TargetEdition    = 60
+	TargetAlbum      = 50
+	TargetPart       = 40
+	TargetTrack      = 30
+	TargetSubtrack   = 20
+	TargetShot       = 10
+)
+
+// UnknownSize marks elements, which size is not known,
+// e.g. Segment and Clusters of live streams.
+const UnknownSize = -1
+
+var (
+	// ErrNotMatroska is returned when file is neither Matroska nor WebM.
+	ErrNotMatroska = errors.New("not a matroska file")
+	// ErrNoAudio is returned when file has no audio track.
+	ErrNoAudio = errors.New("no matroska audio track")
+	// ErrInvalidElement is returned when element header is malformed.
+	ErrInvalidElement = errors.New("invalid ebml element")
+)
+
+// Info is a content of Segment Info.
+type Info struct {
+	// TimestampScale is a duration of timestamp tick in nanoseconds.
+	TimestampScale uint64
+	// Duration in timestamp ticks.
+	Duration   float64
+	Title      string
+	MuxingApp  string
+	WritingApp string
+}
+
+// Audio is a content of TrackEntry Audio.
+type Audio struct {
+	SamplingFrequency float64
+	// OutputSamplingFrequency differs from SamplingFrequency for
+	// SBR of HE-AAC. It is zero, if it is not set.
+	OutputSamplingFrequency float64
+	Channels                uint64
+	BitDepth                uint64
+}
+
+// TrackEntry describes a track of the segment.
+type TrackEntry struct {
+	Number   uint64
+	UID      uint64
+	Type     uint64
+	CodecID  string
+	Name     string
+	Language string
+	Audio    *Audio
+}
+
+// Targets specify what a tag applies to.
+type Targets struct {
+	TypeValue uint64
+	Type      string
+	// TrackUIDs limit the tag to given tracks. Empty means all tracks.
+	TrackUIDs []uint64
+}
+
+// SimpleTag is a name and value of a tag, which may have nested tags.
+type SimpleTag struct {
+	Name     string
+	Language string
+	Default  bool
+	String   string
+	Binary   []byte
+	Tags     []*SimpleTag
+}
+
+// Tag is a set of SimpleTags for given Targets.
+type Tag struct {
+	Targets    Targets
+	SimpleTags []*SimpleTag
+}
+
+// File describes the parts of Matroska file relevant to metadata.
+type File struct {
+	// DocType is either "matroska" or "webm".
+	DocType string
+	Info    Info
+	Tracks  []*TrackEntry
+	Tags    []*Tag
+}
+
+// AudioTrack returns the first audio track or nil.
+func (file *File) AudioTrack() *TrackEntry {
+	for _, track := range file.Tracks {
+		if track.Type == TrackTypeAudio && track.Audio != nil {
+			return track
+		}
+	}
+	return nil
+}
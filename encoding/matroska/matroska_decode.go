@@ -0,0 +1,507 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package matroska
+
+import (
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// maxElementSize limits the size of metadata elements, which are read into memory.
+const maxElementSize = 64 << 20
+
+// defaultTimestampScale is 1 ms per tick.
+const defaultTimestampScale = 1000000
+
+// codecs maps codec identifiers to short codec names.
+// Identifiers, which are not listed, are matched by their prefixes.
+var codecs = map[string]string{
+	"A_AAC":            "AAC",
+	"A_VORBIS":         "Vorbis",
+	"A_OPUS":           "Opus",
+	"A_FLAC":           "FLAC",
+	"A_ALAC":           "ALAC",
+	"A_MPEG/L1":        "MP1",
+	"A_MPEG/L2":        "MP2",
+	"A_MPEG/L3":        "MP3",
+	"A_AC3":            "AC-3",
+	"A_EAC3":           "E-AC-3",
+	"A_DTS":            "DTS",
+	"A_TRUEHD":         "TrueHD",
+	"A_WAVPACK4":       "WavPack",
+	"A_TTA1":           "TTA",
+	"A_PCM/INT/LIT":    "PCM",
+	"A_PCM/INT/BIG":    "PCM",
+	"A_PCM/FLOAT/IEEE": "IEEE float",
+}
+
+// Decode reads the first audio track and its tags
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode matroska", err)
+	}
+	if file.AudioTrack() == nil {
+		return nil, ErrNoAudio
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+	return t, nil
+}
+
+// ReadFile reads EBML header, Info, Tracks and Tags of the first segment.
+// Clusters are skipped by their size. If a Cluster has unknown size,
+// the remaining elements are found by SeekHead.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	h, err := ReadElementHeader(f)
+	if err != nil || h.ID != IDEBML || h.Size == UnknownSize || h.Size > maxElementSize {
+		return nil, ErrNotMatroska
+	}
+	b, err := readData(f, h)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{}
+	err = walk(b, func(id uint32, data []byte) error {
+		if id == IDDocType {
+			file.DocType = readString(data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap("could not parse ebml header", err)
+	}
+	if file.DocType != "matroska" && file.DocType != "webm" {
+		return nil, ErrNotMatroska
+	}
+
+	h, err = ReadElementHeader(f)
+	if err != nil {
+		return nil, errors.Wrap("could not read segment", err)
+	}
+	if h.ID != IDSegment {
+		return nil, errors.New("matroska segment not found")
+	}
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get segment position", err)
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+	if h.Size != UnknownSize && start+h.Size < end {
+		end = start + h.Size
+	}
+
+	if err := file.readSegment(f, start, end); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// readSegment reads top-level elements of the segment.
+func (file *File) readSegment(f io.ReadSeeker, start, end int64) error {
+	seen := map[uint32]bool{}
+	var seeks map[uint32]int64
+
+	offset := start
+	for offset < end {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return errors.Wrap("could not seek to element", err)
+		}
+		// Truncated files, trailing garbage and live streams
+		// with Clusters of unknown size leave the rest to SeekHead
+		h, err := ReadElementHeader(f)
+		if err != nil || h.Size == UnknownSize {
+			break
+		}
+
+		switch h.ID {
+		case IDSeekHead:
+			if seeks == nil {
+				b, err := readData(f, h)
+				if err != nil {
+					return err
+				}
+				seeks = parseSeekHead(b)
+			}
+		case IDInfo, IDTracks, IDTags:
+			if err := file.readElement(f, h); err != nil {
+				return err
+			}
+			seen[h.ID] = true
+		}
+		offset += int64(h.HeaderSize) + h.Size
+	}
+
+	for _, id := range []uint32{IDInfo, IDTracks, IDTags} {
+		position, ok := seeks[id]
+		if !ok || seen[id] || start+position >= end {
+			continue
+		}
+		if _, err := f.Seek(start+position, io.SeekStart); err != nil {
+			return errors.Wrap("could not seek to element", err)
+		}
+		h, err := ReadElementHeader(f)
+		if err != nil || h.ID != id || h.Size == UnknownSize {
+			continue
+		}
+		if err := file.readElement(f, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readElement reads and parses Info, Tracks or Tags element.
+func (file *File) readElement(f io.ReadSeeker, h *ElementHeader) error {
+	b, err := readData(f, h)
+	if err != nil {
+		return err
+	}
+	switch h.ID {
+	case IDInfo:
+		err = file.parseInfo(b)
+	case IDTracks:
+		err = file.parseTracks(b)
+	case IDTags:
+		err = file.parseTags(b)
+	}
+	if err != nil {
+		return errors.Wrap("could not parse matroska element", err)
+	}
+	return nil
+}
+
+func readData(f io.Reader, h *ElementHeader) ([]byte, error) {
+	if h.Size > maxElementSize {
+		return nil, errors.New("matroska element is too large")
+	}
+	b := make([]byte, h.Size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read matroska element", err)
+	}
+	return b, nil
+}
+
+// parseSeekHead returns positions of elements relative to segment data.
+func parseSeekHead(b []byte) map[uint32]int64 {
+	seeks := map[uint32]int64{}
+	walk(b, func(id uint32, data []byte) error {
+		if id != IDSeek {
+			return nil
+		}
+		var seekID uint32
+		var position int64 = -1
+		walk(data, func(id uint32, data []byte) error {
+			switch id {
+			case IDSeekID:
+				seekID = uint32(readUint(data))
+			case IDSeekPosition:
+				position = int64(readUint(data))
+			}
+			return nil
+		})
+		if _, ok := seeks[seekID]; !ok && position >= 0 {
+			seeks[seekID] = position
+		}
+		return nil
+	})
+	return seeks
+}
+
+func (file *File) parseInfo(b []byte) error {
+	info := &file.Info
+	info.TimestampScale = defaultTimestampScale
+	return walk(b, func(id uint32, data []byte) error {
+		switch id {
+		case IDTimestampScale:
+			if scale := readUint(data); scale != 0 {
+				info.TimestampScale = scale
+			}
+		case IDDuration:
+			info.Duration = readFloat(data)
+		case IDTitle:
+			info.Title = readString(data)
+		case IDMuxingApp:
+			info.MuxingApp = readString(data)
+		case IDWritingApp:
+			info.WritingApp = readString(data)
+		}
+		return nil
+	})
+}
+
+func (file *File) parseTracks(b []byte) error {
+	return walk(b, func(id uint32, data []byte) error {
+		if id != IDTrackEntry {
+			return nil
+		}
+		track := &TrackEntry{Language: "eng"}
+		err := walk(data, func(id uint32, data []byte) error {
+			switch id {
+			case IDTrackNumber:
+				track.Number = readUint(data)
+			case IDTrackUID:
+				track.UID = readUint(data)
+			case IDTrackType:
+				track.Type = readUint(data)
+			case IDCodecID:
+				track.CodecID = readString(data)
+			case IDName:
+				track.Name = readString(data)
+			case IDLanguage:
+				track.Language = readString(data)
+			case IDAudio:
+				audio, err := parseAudio(data)
+				if err != nil {
+					return err
+				}
+				track.Audio = audio
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		file.Tracks = append(file.Tracks, track)
+		return nil
+	})
+}
+
+func parseAudio(b []byte) (*Audio, error) {
+	audio := &Audio{SamplingFrequency: 8000, Channels: 1}
+	err := walk(b, func(id uint32, data []byte) error {
+		switch id {
+		case IDSamplingFrequency:
+			audio.SamplingFrequency = readFloat(data)
+		case IDOutputSampling:
+			audio.OutputSamplingFrequency = readFloat(data)
+		case IDChannels:
+			audio.Channels = readUint(data)
+		case IDBitDepth:
+			audio.BitDepth = readUint(data)
+		}
+		return nil
+	})
+	return audio, err
+}
+
+func (file *File) parseTags(b []byte) error {
+	return walk(b, func(id uint32, data []byte) error {
+		if id != IDTag {
+			return nil
+		}
+		tag := &Tag{Targets: Targets{TypeValue: TargetAlbum}}
+		err := walk(data, func(id uint32, data []byte) error {
+			switch id {
+			case IDTargets:
+				return walk(data, func(id uint32, data []byte) error {
+					switch id {
+					case IDTargetTypeValue:
+						tag.Targets.TypeValue = readUint(data)
+					case IDTargetType:
+						tag.Targets.Type = readString(data)
+					case IDTagTrackUID:
+						if uid := readUint(data); uid != 0 {
+							tag.Targets.TrackUIDs = append(tag.Targets.TrackUIDs, uid)
+						}
+					}
+					return nil
+				})
+			case IDSimpleTag:
+				simple, err := parseSimpleTag(data)
+				if err != nil {
+					return err
+				}
+				tag.SimpleTags = append(tag.SimpleTags, simple)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		file.Tags = append(file.Tags, tag)
+		return nil
+	})
+}
+
+func parseSimpleTag(b []byte) (*SimpleTag, error) {
+	tag := &SimpleTag{Language: "und", Default: true}
+	err := walk(b, func(id uint32, data []byte) error {
+		switch id {
+		case IDTagName:
+			tag.Name = readString(data)
+		case IDTagLanguage:
+			tag.Language = readString(data)
+		case IDTagDefault:
+			tag.Default = readUint(data) != 0
+		case IDTagString:
+			tag.String = readString(data)
+		case IDTagBinary:
+			tag.Binary = data
+		case IDSimpleTag:
+			nested, err := parseSimpleTag(data)
+			if err != nil {
+				return err
+			}
+			tag.Tags = append(tag.Tags, nested)
+		}
+		return nil
+	})
+	return tag, err
+}
+
+// trackKeys maps tag names at track level to Vorbis comment keys,
+// when they differ. Other names keep their lowercased names.
+//
+// ref: https://www.matroska.org/technical/tagging.html
+var trackKeys = map[string]string{
+	"part_number":        "tracknumber",
+	"date_released":      "date",
+	"date_recorded":      "date",
+	"publisher":          "organization",
+	"label":              "organization",
+	"recording_location": "location",
+}
+
+// albumKeys maps tag names at album level to Vorbis comment keys.
+// Other album tags are not applied, because they describe the album.
+var albumKeys = map[string]string{
+	"title":         "album",
+	"artist":        "albumartist",
+	"total_parts":   "tracktotal",
+	"date_released": "date",
+	"genre":         "genre",
+	"publisher":     "organization",
+	"label":         "organization",
+	"copyright":     "copyright",
+}
+
+// level groups target type values into album, part (disc) and track levels.
+func level(typeValue uint64) uint64 {
+	switch {
+	case typeValue >= TargetAlbum:
+		return TargetAlbum
+	case typeValue == TargetPart:
+		return TargetPart
+	}
+	return TargetTrack
+}
+
+// Comments converts tags of given track into Vorbis comment keys and values.
+// Track level tags override album level ones.
+func (file *File) Comments(track *TrackEntry) map[string]string {
+	comments := map[string]string{}
+	for _, target := range []uint64{TargetAlbum, TargetPart, TargetTrack} {
+		for _, tag := range file.Tags {
+			if level(tag.Targets.TypeValue) != target || !tag.appliesTo(track) {
+				continue
+			}
+			for _, simple := range tag.SimpleTags {
+				if simple.String == "" {
+					continue
+				}
+				name := strings.ToLower(simple.Name)
+				switch target {
+				case TargetAlbum:
+					if key, ok := albumKeys[name]; ok {
+						comments[key] = simple.String
+					}
+				case TargetPart:
+					// Parts are discs of multi-disc albums
+					if name == "part_number" {
+						comments["discnumber"] = simple.String
+					}
+				default:
+					if key, ok := trackKeys[name]; ok {
+						name = key
+					}
+					comments[name] = simple.String
+				}
+			}
+		}
+	}
+	return comments
+}
+
+func (tag *Tag) appliesTo(track *TrackEntry) bool {
+	if len(tag.Targets.TrackUIDs) == 0 || track == nil {
+		return true
+	}
+	for _, uid := range tag.Targets.TrackUIDs {
+		if uid == track.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// Codec returns a short codec name, e.g. "Opus".
+func (track *TrackEntry) Codec() string {
+	if codec, ok := codecs[track.CodecID]; ok {
+		return codec
+	}
+	for id, codec := range codecs {
+		if strings.HasPrefix(track.CodecID, id+"/") {
+			return codec
+		}
+	}
+	return strings.TrimPrefix(track.CodecID, "A_")
+}
+
+// Duration of the segment or -1 if unknown.
+func (info *Info) duration() time.Duration {
+	if info.Duration <= 0 || math.IsInf(info.Duration, 0) || math.IsNaN(info.Duration) {
+		return -1
+	}
+	return time.Duration(info.Duration * float64(info.TimestampScale))
+}
+
+// Apply properties of the first audio track and tags to the track.
+func (file *File) Apply(t *metadata.Track) {
+	t.Duration = file.Info.duration()
+
+	track := file.AudioTrack()
+	if track != nil {
+		audio := track.Audio
+		rate := audio.SamplingFrequency
+		if audio.OutputSamplingFrequency != 0 {
+			rate = audio.OutputSamplingFrequency
+		}
+		t.Properties = metadata.Properties{
+			Codec:         track.Codec(),
+			SampleRate:    uint32(math.Round(rate)),
+			Channels:      uint8(audio.Channels),
+			BitsPerSample: uint8(audio.BitDepth),
+		}
+		if t.Duration > 0 {
+			t.Properties.TotalSamples = uint64(math.Round(t.Duration.Seconds() * rate))
+		}
+	}
+
+	comments := file.Comments(track)
+	if _, ok := comments["title"]; !ok && file.Info.Title != "" {
+		comments["title"] = file.Info.Title
+	}
+	if _, ok := comments["encoder"]; !ok && file.Info.WritingApp != "" {
+		comments["encoder"] = file.Info.WritingApp
+	}
+	comment := &flac.VorbisComment{Comments: comments}
+	comment.Apply(t)
+}
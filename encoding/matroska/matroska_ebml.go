@@ -0,0 +1,145 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package matroska
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// ElementHeader is an identifier and size of EBML element.
+type ElementHeader struct {
+	// ID keeps its length marker, as in the specification.
+	ID uint32
+	// Size of the element data, or UnknownSize.
+	Size int64
+	// HeaderSize is the size of encoded identifier and size.
+	HeaderSize int
+}
+
+// vintLength returns the length of variable size integer
+// by the number of leading zero bits of its first byte.
+func vintLength(first byte) int {
+	for n := 1; n <= 8; n++ {
+		if first&(0x80>>uint(n-1)) != 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// ReadElementHeader reads EBML element header at the current position.
+func ReadElementHeader(r io.Reader) (*ElementHeader, error) {
+	var b [12]byte
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return nil, err
+	}
+	idLen := vintLength(b[0])
+	if idLen == 0 || idLen > 4 {
+		return nil, ErrInvalidElement
+	}
+	if _, err := io.ReadFull(r, b[1:idLen+1]); err != nil {
+		return nil, errors.Wrap("could not read element header", err)
+	}
+	sizeLen := vintLength(b[idLen])
+	if sizeLen == 0 {
+		return nil, ErrInvalidElement
+	}
+	if _, err := io.ReadFull(r, b[idLen+1:idLen+sizeLen]); err != nil {
+		return nil, errors.Wrap("could not read element size", err)
+	}
+	h, _, err := parseElementHeader(b[:idLen+sizeLen])
+	return h, err
+}
+
+// parseElementHeader parses EBML element header from the start of b
+// and returns it with the remaining bytes.
+func parseElementHeader(b []byte) (*ElementHeader, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, ErrInvalidElement
+	}
+	idLen := vintLength(b[0])
+	if idLen == 0 || idLen > 4 || idLen >= len(b) {
+		return nil, nil, ErrInvalidElement
+	}
+	h := &ElementHeader{}
+	for _, c := range b[:idLen] {
+		h.ID = h.ID<<8 | uint32(c)
+	}
+	b = b[idLen:]
+
+	sizeLen := vintLength(b[0])
+	if sizeLen == 0 || sizeLen > len(b) {
+		return nil, nil, ErrInvalidElement
+	}
+	// Length marker is not a part of the size
+	size := uint64(b[0]) & (0xFF >> uint(sizeLen))
+	unknown := size == 0xFF>>uint(sizeLen)
+	for _, c := range b[1:sizeLen] {
+		size = size<<8 | uint64(c)
+		unknown = unknown && c == 0xFF
+	}
+	switch {
+	case unknown:
+		h.Size = UnknownSize
+	case size > math.MaxInt64:
+		return nil, nil, ErrInvalidElement
+	default:
+		h.Size = int64(size)
+	}
+	h.HeaderSize = idLen + sizeLen
+	return h, b[sizeLen:], nil
+}
+
+// walk calls fn for every child element of master element data.
+// Children of unknown size take the rest of data.
+func walk(b []byte, fn func(id uint32, data []byte) error) error {
+	for len(b) > 0 {
+		h, rest, err := parseElementHeader(b)
+		if err != nil {
+			return err
+		}
+		size := h.Size
+		if size == UnknownSize || size > int64(len(rest)) {
+			size = int64(len(rest))
+		}
+		if err := fn(h.ID, rest[:size]); err != nil {
+			return err
+		}
+		b = rest[size:]
+	}
+	return nil
+}
+
+// readUint decodes unsigned integer element of up to 8 bytes.
+func readUint(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
+
+// readFloat decodes float element of 4 or 8 bytes. Empty element is zero.
+func readFloat(b []byte) float64 {
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case 8:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// readString decodes string element, which may be padded with NUL bytes.
+func readString(b []byte) string {
+	return strings.TrimRight(string(b), "\x00")
+}
@@ -0,0 +1,141 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package matroska
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+// el builds EBML element with 8-byte size.
+func el(id uint32, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	var b []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if c := byte(id >> uint(shift)); c != 0 || len(b) > 0 {
+			b = append(b, c)
+		}
+	}
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(body)))
+	size[0] = 0x01
+	b = append(b, size...)
+	return append(b, body...)
+}
+
+func str(s string) []byte {
+	return []byte(s)
+}
+
+func num(n uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, n)
+	return b
+}
+
+func flt(f float64) []byte {
+	return num(math.Float64bits(f))
+}
+
+func simpleTag(name, value string) []byte {
+	return el(IDSimpleTag, el(IDTagName, str(name)), el(IDTagString, str(value)))
+}
+
+func header(docType string) []byte {
+	return el(IDEBML, el(IDDocType, str(docType)))
+}
+
+var (
+	info = el(IDInfo,
+		el(IDTimestampScale, num(1000000)),
+		el(IDDuration, flt(2500)),
+		el(IDWritingApp, str("test writer")))
+	tracks = el(IDTracks,
+		el(IDTrackEntry,
+			el(IDTrackNumber, num(1)),
+			el(IDTrackUID, num(0xCAFE)),
+			el(IDTrackType, num(TrackTypeAudio)),
+			el(IDCodecID, str("A_OPUS")),
+			el(IDAudio,
+				el(IDSamplingFrequency, flt(48000)),
+				el(IDChannels, num(2)))))
+	tags = el(IDTags,
+		el(IDTag,
+			el(IDTargets, el(IDTargetTypeValue, num(TargetAlbum))),
+			simpleTag("TITLE", "Album"),
+			simpleTag("ARTIST", "Album Artist"),
+			simpleTag("TOTAL_PARTS", "12"),
+			simpleTag("DATE_RELEASED", "2019")),
+		el(IDTag,
+			el(IDTargets, el(IDTargetTypeValue, num(TargetTrack)), el(IDTagTrackUID, num(0xCAFE))),
+			simpleTag("TITLE", "Track"),
+			simpleTag("ARTIST", "Track Artist"),
+			simpleTag("PART_NUMBER", "3"),
+			simpleTag("REPLAYGAIN_TRACK_GAIN", "-3.00 dB")),
+		el(IDTag,
+			el(IDTargets, el(IDTargetTypeValue, num(TargetTrack)), el(IDTagTrackUID, num(0xBEEF))),
+			simpleTag("TITLE", "Other track")))
+)
+
+func checkTrack(t *testing.T, file []byte) {
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Track" || track.Artist != "Track Artist" || track.Album != "Album" {
+		t.Errorf("expected track title and artist with album, but got %q, %q and %q", track.Title, track.Artist, track.Album)
+	}
+	if track.TrackNumber != "3" || track.Comments["tracktotal"] != "12" || track.Date != "2019" {
+		t.Errorf("expected track 3 of 12 in 2019, but got %q of %q in %q", track.TrackNumber, track.Comments["tracktotal"], track.Date)
+	}
+	if x := track.Comments["albumartist"]; x != "Album Artist" {
+		t.Errorf(`expected Comments[albumartist] to be "Album Artist", but got %q`, x)
+	}
+	if x := track.Comments["replaygain_track_gain"]; x != "-3.00 dB" {
+		t.Errorf(`expected Comments[replaygain_track_gain] to be "-3.00 dB", but got %q`, x)
+	}
+	if track.Duration != 2500*time.Millisecond {
+		t.Errorf("expected duration 2.5s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "Opus" || p.SampleRate != 48000 || p.Channels != 2 || p.TotalSamples != 120000 {
+		t.Errorf("expected 120000 samples of stereo Opus, but got %+v", p)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cluster := el(IDCluster, make([]byte, 100000))
+	file := append(header("webm"), el(IDSegment, info, tracks, cluster, cluster, tags)...)
+	checkTrack(t, file)
+}
+
+func TestDecodeSeekHead(t *testing.T) {
+	// Live streams have Segment and Cluster of unknown size,
+	// so Tags after the Cluster are found by SeekHead
+	seekHead := func(tagsPosition uint64) []byte {
+		return el(IDSeekHead,
+			el(IDSeek, el(IDSeekID, num(IDTags)), el(IDSeekPosition, num(tagsPosition))))
+	}
+	cluster := append([]byte{0x1F, 0x43, 0xB6, 0x75, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, make([]byte, 1000)...)
+	position := uint64(len(seekHead(0)) + len(info) + len(tracks) + len(cluster))
+	segment := bytes.Join([][]byte{seekHead(position), info, tracks, cluster, tags}, nil)
+
+	file := append(header("matroska"), 0x18, 0x53, 0x80, 0x67, 0xFF)
+	checkTrack(t, append(file, segment...))
+}
+
+func TestReadFileNotMatroska(t *testing.T) {
+	if _, err := ReadFile(bytes.NewReader(header("mp4"))); err != ErrNotMatroska {
+		t.Errorf("expected ErrNotMatroska, but got %v", err)
+	}
+	if _, err := ReadFile(bytes.NewReader([]byte("OggS"))); err != ErrNotMatroska {
+		t.Errorf("expected ErrNotMatroska, but got %v", err)
+	}
+}
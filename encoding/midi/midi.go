@@ -0,0 +1,115 @@
+// Package midi implements Standard MIDI Files (SMF).
+// Only meta events describing the song are read, duration is computed
+// from the tempo map, as MIDI files hold no audio.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package midi
+
+import (
+	"time"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// HeaderSize is the size of "MThd" chunk including its header.
+const HeaderSize = 14
+
+// DefaultTempo is the tempo in microseconds per quarter note
+// until the first tempo event, which is 120 BPM.
+const DefaultTempo = 500000
+
+// Format of SMF.
+type Format uint16
+
+const (
+	// FormatSingleTrack has a single track with all channels.
+	FormatSingleTrack Format = 0
+	// FormatMultiTrack has simultaneous tracks of a single song.
+	FormatMultiTrack Format = 1
+	// FormatMultiSong has independent single-track patterns.
+	FormatMultiSong Format = 2
+)
+
+func (format Format) String() string {
+	switch format {
+	case FormatSingleTrack:
+		return "single track"
+	case FormatMultiTrack:
+		return "multiple tracks"
+	case FormatMultiSong:
+		return "multiple songs"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrNotMIDI is returned when file does not start with "MThd" chunk.
+	ErrNotMIDI = errors.New("not a standard midi file")
+	// ErrInvalidTrack is returned when track events are truncated.
+	ErrInvalidTrack = errors.New("invalid midi track")
+)
+
+// Tempo is a set tempo meta event.
+type Tempo struct {
+	// Tick is the time of event from the start of track.
+	Tick uint64
+	// MicrosecondsPerQuarter is the duration of a quarter note.
+	MicrosecondsPerQuarter uint32
+}
+
+// BPM returns the tempo in quarter notes per minute.
+func (tempo Tempo) BPM() float64 {
+	if tempo.MicrosecondsPerQuarter == 0 {
+		return 0
+	}
+	return 60e6 / float64(tempo.MicrosecondsPerQuarter)
+}
+
+// TimeSignature is a time signature meta event.
+type TimeSignature struct {
+	// Tick is the time of event from the start of track.
+	Tick      uint64
+	Numerator uint8
+	// Denominator is a note value, e.g. 8 for 6/8.
+	Denominator uint8
+	// ClocksPerClick is the number of MIDI clocks in a metronome click.
+	ClocksPerClick uint8
+	// ThirtySecondsPerQuarter is the number of notated 32nd notes
+	// in a MIDI quarter note, which is normally 8.
+	ThirtySecondsPerQuarter uint8
+}
+
+// Track is a "MTrk" chunk.
+type Track struct {
+	// Name is the sequence or track name. The name of the first track
+	// of multitrack file is the name of the song.
+	Name       string
+	Instrument string
+	// Ticks is the length of the track up to its end event.
+	Ticks          uint64
+	Tempos         []Tempo
+	TimeSignatures []TimeSignature
+}
+
+// File describes the header and the tracks of SMF.
+//
+// ref: https://www.midi.org/specifications/file-format-specifications/standard-midi-files
+type File struct {
+	Format Format
+	// TicksPerQuarter is the metrical time division.
+	// It is zero for SMPTE time division.
+	TicksPerQuarter uint16
+	// FramesPerSecond and TicksPerFrame are SMPTE time division,
+	// 29 frames per second stand for 29.97 drop frame.
+	FramesPerSecond uint8
+	TicksPerFrame   uint8
+	Tracks          []Track
+	Copyright       string
+	// Duration of the longest track, or of all tracks in sequence
+	// for FormatMultiSong.
+	Duration time.Duration
+}
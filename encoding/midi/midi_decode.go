@@ -0,0 +1,296 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package midi
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// maxTrackSize limits the size of track chunks, which are read into memory.
+const maxTrackSize = 64 << 20
+
+// Meta event types.
+const (
+	metaCopyright     = 0x02
+	metaTrackName     = 0x03
+	metaInstrument    = 0x04
+	metaEndOfTrack    = 0x2F
+	metaTempo         = 0x51
+	metaTimeSignature = 0x58
+)
+
+// Decode reads SMF meta events into *metadata.Track.
+// f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode midi", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+	return t, nil
+}
+
+// ReadFile reads the header and all tracks of SMF.
+// Chunks other than "MTrk" are skipped, as the specification requires.
+// Tracks, which the header declares past the end of file, are missing.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	var h [HeaderSize]byte
+	if _, err := io.ReadFull(f, h[:]); err != nil {
+		return nil, errors.Wrap("could not read header", err)
+	}
+	size := binary.BigEndian.Uint32(h[4:])
+	if string(h[:4]) != "MThd" || size < HeaderSize-8 {
+		return nil, ErrNotMIDI
+	}
+
+	file := &File{Format: Format(binary.BigEndian.Uint16(h[8:]))}
+	tracks := int(binary.BigEndian.Uint16(h[10:]))
+	division := binary.BigEndian.Uint16(h[12:])
+	if division&0x8000 != 0 {
+		file.FramesPerSecond = uint8(-int8(division >> 8))
+		file.TicksPerFrame = uint8(division)
+	} else {
+		file.TicksPerQuarter = division
+	}
+	// Ticks have no duration without division
+	if division == 0 || (division&0x8000 != 0 && (file.FramesPerSecond == 0 || file.TicksPerFrame == 0)) {
+		return nil, ErrNotMIDI
+	}
+
+	offset := 8 + int64(size)
+	for len(file.Tracks) < tracks {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to chunk", err)
+		}
+		var chunk [8]byte
+		if _, err := io.ReadFull(f, chunk[:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap("could not read chunk header", err)
+		}
+		size := binary.BigEndian.Uint32(chunk[4:])
+		offset += 8 + int64(size)
+		if string(chunk[:4]) != "MTrk" {
+			continue
+		}
+
+		if size > maxTrackSize {
+			return nil, errors.New("midi track is too large")
+		}
+		b := make([]byte, size)
+		if _, err := io.ReadFull(f, b); err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap("could not read track", err)
+		}
+		track, copyright, err := parseTrack(b)
+		if err != nil {
+			return nil, err
+		}
+		if file.Copyright == "" {
+			file.Copyright = copyright
+		}
+		file.Tracks = append(file.Tracks, *track)
+	}
+
+	file.Duration = file.duration()
+	return file, nil
+}
+
+// parseTrack reads meta events of the track and skips other events.
+// Copyright notice is returned separately, as it belongs to the file.
+func parseTrack(b []byte) (*Track, string, error) {
+	track := &Track{}
+	var copyright string
+	var status byte
+	for pos := 0; pos < len(b); {
+		delta, n := readVarint(b[pos:])
+		pos += n
+		if n == 0 || pos >= len(b) {
+			return nil, "", ErrInvalidTrack
+		}
+		track.Ticks += uint64(delta)
+
+		// Channel events may omit the status byte of the preceding event
+		if b[pos]&0x80 != 0 {
+			status = b[pos]
+			pos++
+		} else if status == 0 {
+			return nil, "", ErrInvalidTrack
+		}
+
+		switch {
+		case status == 0xFF:
+			if pos >= len(b) {
+				return nil, "", ErrInvalidTrack
+			}
+			kind := b[pos]
+			size, n := readVarint(b[pos+1:])
+			pos += 1 + n
+			if n == 0 || uint64(pos)+uint64(size) > uint64(len(b)) {
+				return nil, "", ErrInvalidTrack
+			}
+			data := b[pos : pos+int(size)]
+			pos += int(size)
+			// Meta and system exclusive events cancel running status
+			status = 0
+
+			switch kind {
+			case metaEndOfTrack:
+				return track, copyright, nil
+			case metaCopyright:
+				copyright = text(data)
+			case metaTrackName:
+				track.Name = text(data)
+			case metaInstrument:
+				track.Instrument = text(data)
+			case metaTempo:
+				if len(data) >= 3 {
+					track.Tempos = append(track.Tempos, Tempo{
+						Tick:                   track.Ticks,
+						MicrosecondsPerQuarter: uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2]),
+					})
+				}
+			case metaTimeSignature:
+				if len(data) >= 4 {
+					track.TimeSignatures = append(track.TimeSignatures, TimeSignature{
+						Tick:                    track.Ticks,
+						Numerator:               data[0],
+						Denominator:             1 << (data[1] & 0x7),
+						ClocksPerClick:          data[2],
+						ThirtySecondsPerQuarter: data[3],
+					})
+				}
+			}
+		case status == 0xF0 || status == 0xF7:
+			size, n := readVarint(b[pos:])
+			pos += n + int(size)
+			if n == 0 || pos > len(b) {
+				return nil, "", ErrInvalidTrack
+			}
+			status = 0
+		case status > 0xF0:
+			// System common and real-time messages are not allowed in SMF
+			return nil, "", ErrInvalidTrack
+		case status&0xF0 == 0xC0 || status&0xF0 == 0xD0:
+			pos++
+		default:
+			pos += 2
+		}
+	}
+	// Track without end event is truncated, but its events are valid
+	return track, copyright, nil
+}
+
+// readVarint reads variable-length quantity of up to 4 bytes.
+// It returns zero length if b ends before the last byte.
+func readVarint(b []byte) (uint32, int) {
+	var x uint32
+	for i := 0; i < len(b) && i < 4; i++ {
+		x = x<<7 | uint32(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			return x, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// text decodes meta event text. Its encoding is not specified,
+// so text, which is not valid UTF-8, is treated as Latin-1.
+func text(b []byte) string {
+	if utf8.Valid(b) {
+		return utils.TrimFixed(string(b))
+	}
+	return utils.TrimFixed(utils.DecodeLatin1(b))
+}
+
+// duration computes the length of the file. Tempo events of format 0 and 1
+// apply to all tracks, while format 2 patterns have tempo maps of their own.
+func (file *File) duration() time.Duration {
+	if file.Format == FormatMultiSong {
+		var d time.Duration
+		for _, track := range file.Tracks {
+			d += file.ticksDuration(track.Ticks, track.Tempos)
+		}
+		return d
+	}
+
+	var ticks uint64
+	var tempos []Tempo
+	for _, track := range file.Tracks {
+		if track.Ticks > ticks {
+			ticks = track.Ticks
+		}
+		tempos = append(tempos, track.Tempos...)
+	}
+	sort.SliceStable(tempos, func(i, j int) bool {
+		return tempos[i].Tick < tempos[j].Tick
+	})
+	return file.ticksDuration(ticks, tempos)
+}
+
+// ticksDuration converts ticks to time using tempos sorted by time.
+func (file *File) ticksDuration(ticks uint64, tempos []Tempo) time.Duration {
+	if file.TicksPerFrame != 0 {
+		fps := float64(file.FramesPerSecond)
+		if file.FramesPerSecond == 29 {
+			fps = 29.97
+		}
+		return time.Duration(float64(ticks) / (fps * float64(file.TicksPerFrame)) * float64(time.Second))
+	}
+
+	var d time.Duration
+	tick, tempo := uint64(0), uint32(DefaultTempo)
+	for _, change := range tempos {
+		if change.Tick >= ticks {
+			break
+		}
+		d += file.quartersDuration(change.Tick-tick, tempo)
+		tick, tempo = change.Tick, change.MicrosecondsPerQuarter
+	}
+	return d + file.quartersDuration(ticks-tick, tempo)
+}
+
+func (file *File) quartersDuration(ticks uint64, tempo uint32) time.Duration {
+	return time.Duration(ticks*uint64(tempo)) * time.Microsecond / time.Duration(file.TicksPerQuarter)
+}
+
+// Apply the name of the first track as the title, copyright and initial
+// tempo to the track. File is stored in Track.Extra under "smf".
+func (file *File) Apply(t *metadata.Track) {
+	if len(file.Tracks) != 0 {
+		first := file.Tracks[0]
+		if first.Name != "" {
+			t.Title = first.Name
+		}
+		if len(first.Tempos) != 0 && first.Tempos[0].Tick == 0 {
+			if t.Comments == nil {
+				t.Comments = map[string]string{}
+			}
+			t.Comments["bpm"] = strconv.Itoa(int(math.Round(first.Tempos[0].BPM())))
+		}
+	}
+	if file.Copyright != "" {
+		t.Copyright = file.Copyright
+	}
+	t.Properties = metadata.Properties{Codec: "MIDI"}
+	t.Duration = file.Duration
+	t.SetExtra("smf", file)
+}
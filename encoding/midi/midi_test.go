@@ -0,0 +1,145 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package midi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func chunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, 8, 8+len(body))
+	copy(b, id)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(body)))
+	return append(b, body...)
+}
+
+func header(format Format, tracks, division uint16) []byte {
+	b := make([]byte, 6)
+	binary.BigEndian.PutUint16(b, uint16(format))
+	binary.BigEndian.PutUint16(b[2:], tracks)
+	binary.BigEndian.PutUint16(b[4:], division)
+	return chunk("MThd", b)
+}
+
+func meta(kind byte, data string) []byte {
+	return append([]byte{0xFF, kind, byte(len(data))}, data...)
+}
+
+var endOfTrack = []byte{0x00, 0xFF, metaEndOfTrack, 0x00}
+
+func TestDecode(t *testing.T) {
+	conductor := chunk("MTrk",
+		[]byte{0x00}, meta(metaTrackName, "Song"),
+		[]byte{0x00}, meta(metaCopyright, "(c) 1999 Nobody"),
+		[]byte{0x00}, meta(metaTempo, "\x07\xA1\x20"),
+		[]byte{0x00}, meta(metaTimeSignature, "\x03\x02\x18\x08"),
+		// Quarter notes at 480 ticks: 1 s at 120 BPM, then 60 BPM
+		[]byte{0x87, 0x40}, meta(metaTempo, "\x0F\x42\x40"),
+		endOfTrack)
+	piano := chunk("MTrk",
+		[]byte{0x00}, meta(metaTrackName, "Piano"),
+		[]byte{0x00}, meta(metaInstrument, "Grand Piano"),
+		[]byte{0x00, 0xC0, 0x00},
+		[]byte{0x00, 0xF0, 0x03, 0x7E, 0x7F, 0xF7},
+		// Note on and off with running status, 1920 ticks in total
+		[]byte{0x00, 0x90, 0x3C, 0x40},
+		[]byte{0x8F, 0x00, 0x3C, 0x00},
+		endOfTrack)
+	file := bytes.Join([][]byte{
+		header(FormatMultiTrack, 2, 480),
+		conductor,
+		chunk("XFIH", []byte("unknown chunk")),
+		piano,
+	}, nil)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Song" {
+		t.Errorf(`expected Title to be "Song", but got %q`, track.Title)
+	}
+	if track.Copyright != "(c) 1999 Nobody" {
+		t.Errorf(`expected Copyright to be "(c) 1999 Nobody", but got %q`, track.Copyright)
+	}
+	if x := track.Comments["bpm"]; x != "120" {
+		t.Errorf(`expected Comments[bpm] to be "120", but got %q`, x)
+	}
+	if track.Duration != 3*time.Second {
+		t.Errorf("expected duration 3s, but got %s", track.Duration)
+	}
+	if track.Properties.Codec != "MIDI" {
+		t.Errorf(`expected Codec to be "MIDI", but got %q`, track.Properties.Codec)
+	}
+
+	smf, ok := track.Extra["smf"].(*File)
+	if !ok || len(smf.Tracks) != 2 {
+		t.Fatalf("expected 2 tracks in Extra, but got %+v", track.Extra["smf"])
+	}
+	if x := smf.Tracks[1]; x.Name != "Piano" || x.Instrument != "Grand Piano" || x.Ticks != 1920 {
+		t.Errorf("expected piano track of 1920 ticks, but got %+v", x)
+	}
+	expected := []TimeSignature{{Numerator: 3, Denominator: 4, ClocksPerClick: 24, ThirtySecondsPerQuarter: 8}}
+	if x := smf.Tracks[0].TimeSignatures; !reflect.DeepEqual(x, expected) {
+		t.Errorf("expected time signature 3/4, but got %+v", x)
+	}
+}
+
+func TestDecodeSMPTE(t *testing.T) {
+	// 25 frames per second, 40 ticks per frame
+	track := chunk("MTrk", []byte{0x93, 0x44, 0x90, 0x3C, 0x40}, endOfTrack)
+	file, err := ReadFile(bytes.NewReader(append(header(FormatSingleTrack, 1, 0xE728), track...)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if file.FramesPerSecond != 25 || file.TicksPerFrame != 40 {
+		t.Errorf("expected 25 fps and 40 ticks per frame, but got %+v", file)
+	}
+	if file.Duration != 2500*time.Millisecond {
+		t.Errorf("expected duration 2.5s, but got %s", file.Duration)
+	}
+}
+
+func TestDecodeMissingTracks(t *testing.T) {
+	track := chunk("MTrk", []byte{0x00}, meta(metaTrackName, "Song"), endOfTrack)
+	truncated := chunk("MTrk", make([]byte, 100))[:20]
+	file := bytes.Join([][]byte{header(FormatMultiTrack, 3, 96), track, truncated}, nil)
+
+	f, err := ReadFile(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(f.Tracks) != 1 || f.Tracks[0].Name != "Song" {
+		t.Errorf(`expected one track "Song", but got %+v`, f.Tracks)
+	}
+}
+
+func TestReadFileInvalidDivision(t *testing.T) {
+	for _, division := range []uint16{0, 0xE700} {
+		track := chunk("MTrk", endOfTrack)
+		_, err := ReadFile(bytes.NewReader(append(header(FormatSingleTrack, 1, division), track...)))
+		if err != ErrNotMIDI {
+			t.Errorf("expected ErrNotMIDI for division %#x, but got %v", division, err)
+		}
+	}
+}
+
+func TestReadFileInvalidTrack(t *testing.T) {
+	track := chunk("MTrk", []byte{0x00, 0xFF, metaTrackName, 0x10, 'N'})
+	_, err := ReadFile(bytes.NewReader(append(header(FormatSingleTrack, 1, 96), track...)))
+	if err != ErrInvalidTrack {
+		t.Errorf("expected ErrInvalidTrack, but got %v", err)
+	}
+	if _, err := ReadFile(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00RMIDdata"))); err != ErrNotMIDI {
+		t.Errorf("expected ErrNotMIDI, but got %v", err)
+	}
+}
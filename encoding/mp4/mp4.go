@@ -0,0 +1,91 @@
+// Package mp4 implements MP4 container (ISO/IEC 14496-12)
+// with iTunes-style metadata, as used by M4A and M4B files.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package mp4
+
+import "github.com/audioid/audioid/errors"
+
+var (
+	// ErrNoMovie is returned when file has no moov box.
+	ErrNoMovie = errors.New("no mp4 movie box")
+	// ErrNoAudio is returned when file has no sound track.
+	ErrNoAudio = errors.New("no mp4 sound track")
+	// ErrInvalidBox is returned when box size does not fit its parent.
+	ErrInvalidBox = errors.New("invalid mp4 box")
+)
+
+// DataType is a well-known type of iTunes metadata item value.
+//
+// ref: https://developer.apple.com/library/archive/documentation/QuickTime/QTFF/Metadata/Metadata.html#//apple_ref/doc/uid/TP40000939-CH1-SW35
+type DataType uint32
+
+const (
+	DataTypeImplicit DataType = 0
+	DataTypeUTF8     DataType = 1
+	DataTypeUTF16    DataType = 2
+	DataTypeJPEG     DataType = 13
+	DataTypePNG      DataType = 14
+	DataTypeSigned   DataType = 21
+	DataTypeUnsigned DataType = 22
+	DataTypeBMP      DataType = 27
+)
+
+// File describes the parts of MP4 file relevant to metadata.
+type File struct {
+	// Brand is the major brand of ftyp box, e.g. "M4A "
+	Brand            string
+	MinorVersion     uint32
+	CompatibleBrands []string
+	// Audio is the first sound track.
+	Audio *AudioTrack
+	// Items are iTunes metadata items of moov/udta/meta/ilst.
+	Items []*Item
+	// MediaSize is the total size of mdat boxes.
+	MediaSize int64
+}
+
+// AudioTrack holds properties of a sound track.
+type AudioTrack struct {
+	// SampleEntry is the type of sample description, e.g. "mp4a" or "alac".
+	SampleEntry string
+	// Codec is a short codec name, e.g. "AAC" or "ALAC".
+	Codec string
+	// ObjectType is the MPEG-4 object type indication of "mp4a" sample entry.
+	ObjectType    uint8
+	SampleRate    uint32
+	Channels      uint16
+	BitsPerSample uint16
+	// Bitrate in bits per second, zero implies unknown.
+	Bitrate uint32
+	// Timescale is the number of media time units per second.
+	Timescale uint32
+	// Duration in media time units.
+	Duration uint64
+	// Language is ISO 639-2/T language code.
+	Language string
+}
+
+// Item is an iTunes metadata item.
+type Item struct {
+	// Type is the item box type, e.g. "©nam" or "----" for freeform items.
+	Type string
+	// Mean and Name are only set for freeform items,
+	// e.g. "com.apple.iTunes" and "ISRC".
+	Mean string
+	Name string
+	// Data is a list of values. Most of items have a single value,
+	// but "covr" may hold several pictures.
+	Data []*Data
+}
+
+// Data is a value of iTunes metadata item.
+type Data struct {
+	Type   DataType
+	Locale uint32
+	Value  []byte
+}
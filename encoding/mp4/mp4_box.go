@@ -0,0 +1,164 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mp4
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// BoxHeader is a header of a box in a file.
+type BoxHeader struct {
+	Type string
+	// Offset of the box from the start of file.
+	Offset int64
+	// Size of the whole box, including header.
+	Size int64
+	// HeaderSize is 8 bytes, or 16 bytes for 64-bit box size.
+	HeaderSize int64
+}
+
+// ReadBoxHeader reads box header at given offset of f.
+// end is the offset, where the parent box ends.
+func ReadBoxHeader(f io.ReadSeeker, offset, end int64) (*BoxHeader, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to box", err)
+	}
+	var b [16]byte
+	if _, err := io.ReadFull(f, b[:8]); err != nil {
+		return nil, errors.Wrap("could not read box header", err)
+	}
+
+	h := &BoxHeader{
+		Type:       string(b[4:8]),
+		Offset:     offset,
+		Size:       int64(binary.BigEndian.Uint32(b[:4])),
+		HeaderSize: 8,
+	}
+	switch h.Size {
+	case 0:
+		// box extends to the end of file
+		h.Size = end - offset
+	case 1:
+		if _, err := io.ReadFull(f, b[8:16]); err != nil {
+			return nil, errors.Wrap("could not read box size", err)
+		}
+		h.Size = int64(binary.BigEndian.Uint64(b[8:16]))
+		h.HeaderSize = 16
+	}
+	if h.Size < h.HeaderSize || offset+h.Size > end {
+		return nil, ErrInvalidBox
+	}
+	return h, nil
+}
+
+// box is a box, which was read into memory.
+type box struct {
+	typ string
+	// offset of the box from the start of its parent payload
+	offset     int
+	headerSize int
+	// payload without header
+	payload []byte
+}
+
+func (b *box) size() int {
+	return b.headerSize + len(b.payload)
+}
+
+// parseBoxes splits payload of a container box into child boxes.
+func parseBoxes(b []byte) ([]*box, error) {
+	var boxes []*box
+	for offset := 0; offset < len(b); {
+		if len(b)-offset < 8 {
+			// Some writers pad containers with zeros
+			break
+		}
+		child := &box{
+			typ:        string(b[offset+4 : offset+8]),
+			offset:     offset,
+			headerSize: 8,
+		}
+		size := int(binary.BigEndian.Uint32(b[offset:]))
+		switch size {
+		case 0:
+			size = len(b) - offset
+		case 1:
+			if len(b)-offset < 16 {
+				return nil, ErrInvalidBox
+			}
+			size64 := binary.BigEndian.Uint64(b[offset+8:])
+			if size64 > uint64(len(b)-offset) {
+				return nil, ErrInvalidBox
+			}
+			size = int(size64)
+			child.headerSize = 16
+		}
+		if size < child.headerSize || size > len(b)-offset {
+			return nil, ErrInvalidBox
+		}
+		child.payload = b[offset+child.headerSize : offset+size]
+		boxes = append(boxes, child)
+		offset += size
+	}
+	return boxes, nil
+}
+
+// findBox returns the first child of given type.
+func findBox(boxes []*box, typ string) *box {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b
+		}
+	}
+	return nil
+}
+
+// findPath descends into containers by box types.
+func findPath(b []byte, path ...string) (*box, error) {
+	var found *box
+	for _, typ := range path {
+		boxes, err := parseBoxes(b)
+		if err != nil {
+			return nil, err
+		}
+		found = findBox(boxes, typ)
+		if found == nil {
+			return nil, nil
+		}
+		b = found.payload
+		if typ == "meta" {
+			b = metaPayload(b)
+		}
+	}
+	return found, nil
+}
+
+// metaPayload returns children of meta box.
+// ISO meta is a full box with 4 bytes of version and flags,
+// while QuickTime meta has no such header.
+func metaPayload(b []byte) []byte {
+	if len(b) >= 8 && isBoxType(b[4:8]) {
+		return b
+	}
+	if len(b) < 4 {
+		return nil
+	}
+	return b[4:]
+}
+
+// isBoxType reports whether b looks like a box type
+// of a meta box child.
+func isBoxType(b []byte) bool {
+	switch string(b) {
+	case "hdlr", "ilst", "keys", "free", "mdta":
+		return true
+	}
+	return false
+}
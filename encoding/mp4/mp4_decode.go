@@ -0,0 +1,174 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mp4
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// maxMovieSize limits the size of moov box, which is read into memory.
+const maxMovieSize = 256 << 20
+
+// Decode reads sound track properties and iTunes metadata
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode mp4", err)
+	}
+	if file.Audio == nil {
+		return nil, ErrNoAudio
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+	return t, nil
+}
+
+// ReadFile walks top-level boxes of f and parses ftyp and moov boxes.
+// Media data is skipped.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+
+	file := &File{}
+	var moov []byte
+	for offset := int64(0); offset+8 <= end; {
+		h, err := ReadBoxHeader(f, offset, end)
+		if err != nil {
+			return nil, err
+		}
+
+		switch h.Type {
+		case "ftyp":
+			b, err := readPayload(f, h)
+			if err != nil {
+				return nil, err
+			}
+			file.parseFileType(b)
+		case "moov":
+			if h.Size > maxMovieSize {
+				return nil, errors.New("mp4 movie box is too large")
+			}
+			moov, err = readPayload(f, h)
+			if err != nil {
+				return nil, err
+			}
+		case "mdat":
+			file.MediaSize += h.Size - h.HeaderSize
+		}
+		offset += h.Size
+	}
+
+	if moov == nil {
+		return nil, ErrNoMovie
+	}
+	if err := file.parseMovie(moov); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func readPayload(f io.ReadSeeker, h *BoxHeader) ([]byte, error) {
+	if _, err := f.Seek(h.Offset+h.HeaderSize, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to "+h.Type, err)
+	}
+	b := make([]byte, h.Size-h.HeaderSize)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read "+h.Type, err)
+	}
+	return b, nil
+}
+
+func (file *File) parseFileType(b []byte) {
+	if len(b) < 8 {
+		return
+	}
+	file.Brand = string(b[:4])
+	file.MinorVersion = binary.BigEndian.Uint32(b[4:8])
+	for i := 8; i+4 <= len(b); i += 4 {
+		file.CompatibleBrands = append(file.CompatibleBrands, string(b[i:i+4]))
+	}
+}
+
+func (file *File) parseMovie(moov []byte) error {
+	boxes, err := parseBoxes(moov)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range boxes {
+		if b.typ != "trak" {
+			continue
+		}
+		audio, err := parseTrack(b.payload)
+		if err != nil {
+			return err
+		}
+		if audio != nil {
+			file.Audio = audio
+			break
+		}
+	}
+
+	ilst, err := findPath(moov, "udta", "meta", "ilst")
+	if err != nil {
+		return err
+	}
+	if ilst != nil {
+		file.Items, err = parseItems(ilst.payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply sound track properties and metadata items to the track.
+func (file *File) Apply(t *metadata.Track) {
+	t.Duration = -1
+	if audio := file.Audio; audio != nil {
+		t.Properties = metadata.Properties{
+			Codec:         audio.Codec,
+			SampleRate:    audio.SampleRate,
+			Channels:      uint8(audio.Channels),
+			BitsPerSample: uint8(audio.BitsPerSample),
+			Bitrate:       audio.Bitrate,
+		}
+		if audio.Timescale != 0 {
+			t.Duration = metadata.SamplesDuration(audio.Duration, audio.Timescale)
+			if audio.Timescale == audio.SampleRate {
+				t.Properties.TotalSamples = audio.Duration
+			}
+		}
+		if t.Properties.Bitrate == 0 && t.Duration > 0 {
+			t.Properties.Bitrate = uint32(float64(file.MediaSize) * 8 / t.Duration.Seconds())
+		}
+	}
+
+	comment := &flac.VorbisComment{Comments: file.Comments()}
+	comment.Apply(t)
+
+	for _, item := range file.Items {
+		if item.Type != "covr" {
+			continue
+		}
+		for _, data := range item.Data {
+			t.Pictures = append(t.Pictures, metadata.Picture{
+				MIME: data.MIME(),
+				Data: data.Value,
+			})
+		}
+	}
+}
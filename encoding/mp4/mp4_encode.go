@@ -0,0 +1,197 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mp4
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/metadata"
+)
+
+// ITunesMean is the mean of freeform items written by iTunes.
+const ITunesMean = "com.apple.iTunes"
+
+// itemType returns iTunes item type of given Vorbis comment key,
+// reversing itemKeys.
+func itemType(key string) (string, bool) {
+	for typ, k := range itemKeys {
+		if k == key {
+			return typ, true
+		}
+	}
+	return "", false
+}
+
+// NewItems converts the track into iTunes metadata items.
+// Fields without dedicated items are written as freeform items
+// of iTunes with upper case names, and pictures as a single "covr" item.
+func NewItems(t *metadata.Track) []*Item {
+	comments := flac.TrackComments(t)
+	keys := make([]string, 0, len(comments))
+	for key := range comments {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var items []*Item
+	for _, key := range keys {
+		value := comments[key]
+		switch key {
+		case "tracknumber", "tracktotal":
+			if key == "tracktotal" && comments["tracknumber"] != "" {
+				continue
+			}
+			items = append(items, pairItem("trkn", comments["tracknumber"], comments["tracktotal"], 8))
+			continue
+		case "discnumber", "disctotal":
+			if key == "disctotal" && comments["discnumber"] != "" {
+				continue
+			}
+			items = append(items, pairItem("disk", comments["discnumber"], comments["disctotal"], 6))
+			continue
+		case "bpm", "compilation":
+			if n, err := strconv.Atoi(value); err == nil {
+				typ, _ := itemType(key)
+				items = append(items, intItem(typ, n, key == "bpm"))
+			} else {
+				items = append(items, freeformItem(key, value))
+			}
+			continue
+		}
+
+		if typ, ok := itemType(key); ok {
+			items = append(items, &Item{
+				Type: typ,
+				Data: []*Data{{Type: DataTypeUTF8, Value: []byte(value)}},
+			})
+			continue
+		}
+		items = append(items, freeformItem(key, value))
+	}
+
+	if len(t.Pictures) != 0 {
+		covr := &Item{Type: "covr"}
+		for _, pic := range t.Pictures {
+			covr.Data = append(covr.Data, &Data{Type: pictureType(pic.MIME), Value: pic.Data})
+		}
+		items = append(items, covr)
+	}
+	return items
+}
+
+func freeformItem(key, value string) *Item {
+	return &Item{
+		Type: FreeformType,
+		Mean: ITunesMean,
+		Name: strings.ToUpper(key),
+		Data: []*Data{{Type: DataTypeUTF8, Value: []byte(value)}},
+	}
+}
+
+// pairItem creates "trkn" or "disk" item, which values are
+// 2 bytes of padding, 2 bytes of number and 2 bytes of total,
+// followed by 2 more bytes of padding for "trkn".
+func pairItem(typ, number, total string, size int) *Item {
+	b := make([]byte, size)
+	n, _ := strconv.Atoi(number)
+	binary.BigEndian.PutUint16(b[2:], clampUint16(n))
+	n, _ = strconv.Atoi(total)
+	binary.BigEndian.PutUint16(b[4:], clampUint16(n))
+	return &Item{
+		Type: typ,
+		Data: []*Data{{Type: DataTypeImplicit, Value: b}},
+	}
+}
+
+// intItem creates an item with signed integer value,
+// which is 2 bytes for "tmpo" and 1 byte for flags like "cpil".
+func intItem(typ string, n int, wide bool) *Item {
+	if !wide {
+		return &Item{
+			Type: typ,
+			Data: []*Data{{Type: DataTypeSigned, Value: []byte{byte(n)}}},
+		}
+	}
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, clampUint16(n))
+	return &Item{
+		Type: typ,
+		Data: []*Data{{Type: DataTypeSigned, Value: b}},
+	}
+}
+
+func clampUint16(n int) uint16 {
+	if n < 0 {
+		return 0
+	}
+	if n > math.MaxUint16 {
+		return math.MaxUint16
+	}
+	return uint16(n)
+}
+
+// pictureType returns data type of "covr" value by MIME type of the picture.
+func pictureType(mime string) DataType {
+	switch mime {
+	case "image/jpeg", "image/jpg":
+		return DataTypeJPEG
+	case "image/png":
+		return DataTypePNG
+	case "image/bmp":
+		return DataTypeBMP
+	}
+	return DataTypeImplicit
+}
+
+// encodeItems serializes items into payload of ilst box.
+func encodeItems(items []*Item) []byte {
+	var b []byte
+	for _, item := range items {
+		var children [][]byte
+		if item.Type == FreeformType {
+			children = append(children,
+				encodeBox("mean", make([]byte, 4), []byte(item.Mean)),
+				encodeBox("name", make([]byte, 4), []byte(item.Name)))
+		}
+		for _, data := range item.Data {
+			var h [8]byte
+			binary.BigEndian.PutUint32(h[:4], uint32(data.Type)&0xFFFFFF)
+			binary.BigEndian.PutUint32(h[4:], data.Locale)
+			children = append(children, encodeBox("data", h[:], data.Value))
+		}
+		b = append(b, encodeBox(item.Type, children...)...)
+	}
+	return b
+}
+
+// encodeBox serializes a box of given type and payload parts.
+// 64-bit size is only used, when the box does not fit 32 bits.
+func encodeBox(typ string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	var b []byte
+	if int64(size) > math.MaxUint32 {
+		b = make([]byte, 16, size+8)
+		binary.BigEndian.PutUint32(b, 1)
+		binary.BigEndian.PutUint64(b[8:], uint64(size+8))
+	} else {
+		b = make([]byte, 8, size)
+		binary.BigEndian.PutUint32(b, uint32(size))
+	}
+	copy(b[4:8], typ)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	return b
+}
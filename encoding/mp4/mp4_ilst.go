@@ -0,0 +1,219 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mp4
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/utils"
+)
+
+// FreeformType is the type of freeform items,
+// which are identified by their mean and name.
+const FreeformType = "----"
+
+// itemKeys maps iTunes item types to Vorbis comment keys.
+// Items, which need special handling, like "trkn", are not listed.
+var itemKeys = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"aART":    "albumartist",
+	"\xa9alb": "album",
+	"\xa9gen": "genre",
+	"\xa9day": "date",
+	"\xa9cmt": "comment",
+	"\xa9wrt": "composer",
+	"\xa9too": "encoder",
+	"\xa9enc": "encodedby",
+	"\xa9lyr": "lyrics",
+	"\xa9grp": "grouping",
+	"\xa9pub": "organization",
+	"cprt":    "copyright",
+	"desc":    "description",
+	"ldes":    "longdescription",
+	"tmpo":    "bpm",
+	"cpil":    "compilation",
+	"soal":    "albumsort",
+	"soar":    "artistsort",
+	"soaa":    "albumartistsort",
+	"sonm":    "titlesort",
+	"soco":    "composersort",
+}
+
+// parseItems parses children of ilst box.
+func parseItems(b []byte) ([]*Item, error) {
+	boxes, err := parseBoxes(b)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*Item, 0, len(boxes))
+	for _, itemBox := range boxes {
+		children, err := parseBoxes(itemBox.payload)
+		if err != nil {
+			return nil, err
+		}
+
+		item := &Item{Type: itemBox.typ}
+		for _, child := range children {
+			if len(child.payload) < 4 {
+				continue
+			}
+			switch child.typ {
+			case "mean":
+				item.Mean = string(child.payload[4:])
+			case "name":
+				item.Name = string(child.payload[4:])
+			case "data":
+				if len(child.payload) < 8 {
+					continue
+				}
+				item.Data = append(item.Data, &Data{
+					Type:   DataType(binary.BigEndian.Uint32(child.payload[:4]) & 0xFFFFFF),
+					Locale: binary.BigEndian.Uint32(child.payload[4:8]),
+					Value:  child.payload[8:],
+				})
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Key returns a key of the item, which is the item type,
+// or "----:mean:name" for freeform items.
+func (item *Item) Key() string {
+	if item.Type == FreeformType {
+		return FreeformType + ":" + item.Mean + ":" + item.Name
+	}
+	return item.Type
+}
+
+// String returns the first value of the item as a string.
+func (item *Item) String() string {
+	if len(item.Data) == 0 {
+		return ""
+	}
+	return item.Data[0].String()
+}
+
+// String returns the value as a string.
+// Integers are formatted in decimal, binary values are returned as is.
+func (data *Data) String() string {
+	switch data.Type {
+	case DataTypeUTF16:
+		u := make([]uint16, len(data.Value)/2)
+		for i := range u {
+			u[i] = binary.BigEndian.Uint16(data.Value[i*2:])
+		}
+		return string(utf16.Decode(u))
+	case DataTypeSigned:
+		return strconv.FormatInt(data.Int(), 10)
+	case DataTypeUnsigned:
+		return strconv.FormatUint(uint64(data.Int()), 10)
+	}
+	return string(data.Value)
+}
+
+// Int returns the value as a big-endian integer of 1, 2, 4 or 8 bytes.
+func (data *Data) Int() int64 {
+	switch len(data.Value) {
+	case 1:
+		return int64(int8(data.Value[0]))
+	case 2:
+		return int64(int16(binary.BigEndian.Uint16(data.Value)))
+	case 4:
+		return int64(int32(binary.BigEndian.Uint32(data.Value)))
+	case 8:
+		return int64(binary.BigEndian.Uint64(data.Value))
+	}
+	return 0
+}
+
+// MIME returns MIME type of the picture value.
+func (data *Data) MIME() string {
+	switch data.Type {
+	case DataTypeJPEG:
+		return "image/jpeg"
+	case DataTypePNG:
+		return "image/png"
+	case DataTypeBMP:
+		return "image/bmp"
+	}
+	return ""
+}
+
+// Comments converts items into Vorbis comment keys and values.
+// Cover art is skipped, see Pictures.
+func (f *File) Comments() map[string]string {
+	comments := map[string]string{}
+	for _, item := range f.Items {
+		if len(item.Data) == 0 {
+			continue
+		}
+
+		switch item.Type {
+		case "covr":
+			continue
+		case "trkn":
+			utils.SetPair(comments, "tracknumber", "tracktotal", item.pair())
+			continue
+		case "disk":
+			utils.SetPair(comments, "discnumber", "disctotal", item.pair())
+			continue
+		case "gnre":
+			// ID3v1 genre index, starting from 1
+			if id := item.Data[0].Int(); id > 0 {
+				if _, ok := comments["genre"]; !ok {
+					comments["genre"] = id3v1.GenreName(uint8(id - 1))
+				}
+			}
+			continue
+		case FreeformType:
+			if item.Name != "" {
+				comments[strings.ToLower(item.Name)] = item.String()
+			}
+			continue
+		}
+
+		if key, ok := itemKeys[item.Type]; ok {
+			if item.Type == "cpil" || item.Type == "tmpo" {
+				comments[key] = strconv.FormatInt(item.Data[0].Int(), 10)
+			} else {
+				comments[key] = item.String()
+			}
+			continue
+		}
+		// Box types are Latin-1, e.g. "©" is 0xA9
+		if item.Data[0].Type == DataTypeUTF8 {
+			comments[strings.ToLower(utils.DecodeLatin1([]byte(item.Type)))] = item.String()
+		}
+	}
+	return comments
+}
+
+// pair parses "trkn" and "disk" items: 2 bytes of padding, 2 bytes
+// of number and 2 bytes of total, where zero is unset.
+// It returns them as "number/total" with unset parts empty.
+func (item *Item) pair() string {
+	b := item.Data[0].Value
+	if len(b) < 6 {
+		return ""
+	}
+	var number, total string
+	if n := binary.BigEndian.Uint16(b[2:4]); n != 0 {
+		number = strconv.Itoa(int(n))
+	}
+	if n := binary.BigEndian.Uint16(b[4:6]); n != 0 {
+		total = strconv.Itoa(int(n))
+	}
+	return number + "/" + total
+}
@@ -0,0 +1,265 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mp4
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// MPEG-4 object type indications of "mp4a" sample entries
+//
+// ref: http://mp4ra.org/#/object_types
+const (
+	ObjectTypeAAC      = 0x40
+	ObjectTypeAACMain  = 0x66
+	ObjectTypeAACLC    = 0x67
+	ObjectTypeAACSSR   = 0x68
+	ObjectTypeMP3      = 0x69
+	ObjectTypeMP3MPEG1 = 0x6B
+)
+
+// audioSampleEntrySize is the size of sample entry fields
+// and audio sample entry fields, which precede child boxes.
+const audioSampleEntrySize = 28
+
+// parseTrack reads audio properties from trak box.
+// Nil is returned if trak is not a sound track.
+func parseTrack(trak []byte) (*AudioTrack, error) {
+	hdlr, err := findPath(trak, "mdia", "hdlr")
+	if err != nil || hdlr == nil || len(hdlr.payload) < 12 || string(hdlr.payload[8:12]) != "soun" {
+		return nil, err
+	}
+
+	audio := &AudioTrack{}
+
+	mdhd, err := findPath(trak, "mdia", "mdhd")
+	if err != nil {
+		return nil, err
+	}
+	if mdhd != nil {
+		parseMediaHeader(audio, mdhd.payload)
+	}
+
+	stsd, err := findPath(trak, "mdia", "minf", "stbl", "stsd")
+	if err != nil {
+		return nil, err
+	}
+	// full box header and entry count precede sample entries
+	if stsd != nil && len(stsd.payload) > 8 {
+		entries, err := parseBoxes(stsd.payload[8:])
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) != 0 {
+			parseSampleEntry(audio, entries[0])
+		}
+	}
+
+	return audio, nil
+}
+
+// parseMediaHeader parses mdhd box.
+func parseMediaHeader(audio *AudioTrack, b []byte) {
+	if len(b) < 24 {
+		return
+	}
+	if b[0] == 1 {
+		if len(b) < 36 {
+			return
+		}
+		audio.Timescale = binary.BigEndian.Uint32(b[20:24])
+		audio.Duration = binary.BigEndian.Uint64(b[24:32])
+		b = b[32:]
+	} else {
+		audio.Timescale = binary.BigEndian.Uint32(b[12:16])
+		audio.Duration = uint64(binary.BigEndian.Uint32(b[16:20]))
+		b = b[20:]
+	}
+
+	// ISO-639-2/T code packed as 3 letters by 5 bits
+	lang := binary.BigEndian.Uint16(b)
+	if lang != 0 && lang != 0x7FFF {
+		audio.Language = string([]byte{
+			byte(lang>>10&0x1F) + 0x60,
+			byte(lang>>5&0x1F) + 0x60,
+			byte(lang&0x1F) + 0x60,
+		})
+	}
+}
+
+// parseSampleEntry parses audio sample entry of stsd box.
+func parseSampleEntry(audio *AudioTrack, entry *box) {
+	b := entry.payload
+	audio.SampleEntry = entry.typ
+	audio.Codec = sampleEntryCodec(entry.typ)
+	if len(b) < audioSampleEntrySize {
+		return
+	}
+
+	version := binary.BigEndian.Uint16(b[8:10])
+	audio.Channels = binary.BigEndian.Uint16(b[16:18])
+	audio.BitsPerSample = binary.BigEndian.Uint16(b[18:20])
+	// 16.16 fixed-point number
+	audio.SampleRate = binary.BigEndian.Uint32(b[24:28]) >> 16
+
+	children := b[audioSampleEntrySize:]
+	switch version {
+	case 1:
+		// QuickTime sound description version 1 has 4 more fields
+		if len(children) < 16 {
+			return
+		}
+		children = children[16:]
+	case 2:
+		// QuickTime sound description version 2 replaces
+		// sample rate and channels with wider fields
+		if len(children) < 36 {
+			return
+		}
+		audio.SampleRate = uint32(math.Float64frombits(binary.BigEndian.Uint64(children[4:12])))
+		audio.Channels = uint16(binary.BigEndian.Uint32(children[12:16]))
+		audio.BitsPerSample = uint16(binary.BigEndian.Uint32(children[20:24]))
+		children = children[36:]
+	}
+
+	// Sample rate does not fit 16.16 number above 65535 Hz,
+	// but media timescale usually equals to it.
+	if audio.SampleRate == 0 {
+		audio.SampleRate = audio.Timescale
+	}
+
+	boxes, err := parseBoxes(children)
+	if err != nil {
+		return
+	}
+	if esds := findBox(boxes, "esds"); esds != nil {
+		parseESDS(audio, esds.payload)
+	}
+	if alac := findBox(boxes, "alac"); alac != nil {
+		parseALAC(audio, alac.payload)
+	}
+	// Sample size field is meaningless for lossy codecs
+	switch audio.Codec {
+	case "AAC", "MP3", "AC-3", "E-AC-3", "Opus":
+		audio.BitsPerSample = 0
+	}
+}
+
+func sampleEntryCodec(typ string) string {
+	switch typ {
+	case "mp4a":
+		return "AAC"
+	case "alac":
+		return "ALAC"
+	case "ac-3":
+		return "AC-3"
+	case "ec-3":
+		return "E-AC-3"
+	case "Opus":
+		return "Opus"
+	case "fLaC":
+		return "FLAC"
+	case ".mp3":
+		return "MP3"
+	case "lpcm", "sowt", "twos", "in24", "in32", "fl32", "fl64":
+		return "PCM"
+	}
+	return typ
+}
+
+// Descriptor tags of esds box
+const (
+	descriptorES            = 0x03
+	descriptorDecoderConfig = 0x04
+)
+
+// parseESDS parses elementary stream descriptor of "mp4a" sample entry.
+//
+// ref: ISO/IEC 14496-1, 7.2.6
+func parseESDS(audio *AudioTrack, b []byte) {
+	if len(b) < 4 {
+		return
+	}
+	b = b[4:]
+
+	tag, payload, _ := readDescriptor(b)
+	if tag != descriptorES || len(payload) < 3 {
+		return
+	}
+	flags := payload[2]
+	payload = payload[3:]
+	if flags&0x80 != 0 {
+		// stream dependence
+		payload = skip(payload, 2)
+	}
+	if flags&0x40 != 0 && len(payload) > 0 {
+		// URL
+		payload = skip(payload, 1+int(payload[0]))
+	}
+	if flags&0x20 != 0 {
+		// OCR stream
+		payload = skip(payload, 2)
+	}
+
+	tag, config, _ := readDescriptor(payload)
+	if tag != descriptorDecoderConfig || len(config) < 13 {
+		return
+	}
+	audio.ObjectType = config[0]
+	audio.Bitrate = binary.BigEndian.Uint32(config[9:13])
+
+	switch audio.ObjectType {
+	case ObjectTypeMP3, ObjectTypeMP3MPEG1:
+		audio.Codec = "MP3"
+	}
+}
+
+// readDescriptor reads descriptor tag and its payload.
+// Size is encoded in up to 4 bytes by 7 bits.
+func readDescriptor(b []byte) (byte, []byte, []byte) {
+	if len(b) < 2 {
+		return 0, nil, nil
+	}
+	tag := b[0]
+	size := 0
+	i := 1
+	for ; i < len(b) && i <= 4; i++ {
+		size = size<<7 | int(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			i++
+			break
+		}
+	}
+	if i+size > len(b) {
+		size = len(b) - i
+	}
+	return tag, b[i : i+size], b[i+size:]
+}
+
+func skip(b []byte, n int) []byte {
+	if n > len(b) {
+		return nil
+	}
+	return b[n:]
+}
+
+// parseALAC parses ALACSpecificConfig.
+//
+// ref: https://github.com/macosforge/alac/blob/master/ALACMagicCookieDescription.txt
+func parseALAC(audio *AudioTrack, b []byte) {
+	// full box header precedes the config
+	if len(b) < 4+24 {
+		return
+	}
+	b = b[4:]
+	audio.Codec = "ALAC"
+	audio.BitsPerSample = uint16(b[5])
+	audio.Channels = uint16(b[9])
+	audio.Bitrate = binary.BigEndian.Uint32(b[16:20])
+	audio.SampleRate = binary.BigEndian.Uint32(b[20:24])
+}
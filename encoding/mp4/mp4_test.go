@@ -0,0 +1,244 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/audioid/audioid/metadata"
+)
+
+func mkbox(typ string, children ...[]byte) []byte {
+	size := 8
+	for _, child := range children {
+		size += len(child)
+	}
+	b := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(b, uint32(size))
+	copy(b[4:], typ)
+	for _, child := range children {
+		b = append(b, child...)
+	}
+	return b
+}
+
+func u32(x uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], x)
+	return b[:]
+}
+
+func mkdata(typ DataType, value []byte) []byte {
+	return mkbox("data", u32(uint32(typ)), u32(0), value)
+}
+
+// mediaMarker starts media data, which chunk offset points at.
+var mediaMarker = []byte("first chunk")
+
+func testFile(ilst []byte) []byte {
+	return testFileWithFree(ilst, 0)
+}
+
+// testFileWithFree builds a file with a free box of given size after moov.
+func testFileWithFree(ilst []byte, free int) []byte {
+	hdlr := mkbox("hdlr", make([]byte, 8), []byte("soun"), make([]byte, 13))
+	// version 0, timescale 44100, duration 10 seconds, language "eng"
+	mdhd := mkbox("mdhd", make([]byte, 12), u32(44100), u32(441000), []byte{0x15, 0xC7, 0, 0})
+
+	esds := mkbox("esds", u32(0),
+		[]byte{descriptorES, 18, 0, 1, 0},
+		[]byte{descriptorDecoderConfig, 13, ObjectTypeAAC, 0x15, 0, 0, 0}, u32(128000), u32(128000))
+	entry := make([]byte, audioSampleEntrySize)
+	binary.BigEndian.PutUint16(entry[6:], 1)
+	binary.BigEndian.PutUint16(entry[16:], 2)
+	binary.BigEndian.PutUint16(entry[18:], 16)
+	binary.BigEndian.PutUint32(entry[24:], 44100<<16)
+	stsd := mkbox("stsd", u32(0), u32(1), mkbox("mp4a", entry, esds))
+
+	meta := mkbox("meta", u32(0), mkbox("hdlr", make([]byte, 8), []byte("mdir"), make([]byte, 13)), ilst)
+	ftyp := mkbox("ftyp", []byte("M4A "), u32(0), []byte("M4A mp42isom"))
+	moov := func(chunkOffset uint32) []byte {
+		stco := mkbox("stco", u32(0), u32(1), u32(chunkOffset))
+		trak := mkbox("trak", mkbox("mdia", hdlr, mdhd, mkbox("minf", mkbox("stbl", stsd, stco))))
+		return mkbox("moov", trak, mkbox("udta", meta))
+	}
+
+	var file bytes.Buffer
+	file.Write(ftyp)
+	file.Write(moov(uint32(len(ftyp) + len(moov(0)) + free + 8)))
+	if free != 0 {
+		file.Write(mkbox("free", make([]byte, free-8)))
+	}
+	file.Write(mkbox("mdat", mediaMarker, make([]byte, 1000)))
+	return file.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	ilst := mkbox("ilst",
+		mkbox("\xa9nam", mkdata(DataTypeUTF8, []byte("Title"))),
+		mkbox("aART", mkdata(DataTypeUTF8, []byte("Album Artist"))),
+		mkbox("trkn", mkdata(DataTypeImplicit, []byte{0, 0, 0, 3, 0, 12, 0, 0})),
+		mkbox("covr", mkdata(DataTypePNG, []byte("png"))),
+		mkbox("----",
+			mkbox("mean", u32(0), []byte("com.apple.iTunes")),
+			mkbox("name", u32(0), []byte("ISRC")),
+			mkdata(DataTypeUTF8, []byte("USRC17607839"))),
+	)
+
+	track, err := Decode(bytes.NewReader(testFile(ilst)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if track.Title != "Title" {
+		t.Errorf(`expected Title to be "Title", but got %q`, track.Title)
+	}
+	if track.TrackNumber != "3" {
+		t.Errorf(`expected TrackNumber to be "3", but got %q`, track.TrackNumber)
+	}
+	if x := track.Comments["tracktotal"]; x != "12" {
+		t.Errorf(`expected Comments[tracktotal] to be "12", but got %q`, x)
+	}
+	if x := track.Comments["albumartist"]; x != "Album Artist" {
+		t.Errorf(`expected Comments[albumartist] to be "Album Artist", but got %q`, x)
+	}
+	if track.ISRC != "USRC17607839" {
+		t.Errorf(`expected ISRC to be "USRC17607839", but got %q`, track.ISRC)
+	}
+	if len(track.Pictures) != 1 || track.Pictures[0].MIME != "image/png" {
+		t.Errorf("expected a PNG picture, but got %v", track.Pictures)
+	}
+	if track.Duration != 10*time.Second {
+		t.Errorf("expected duration 10s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "AAC" || p.SampleRate != 44100 || p.Channels != 2 || p.Bitrate != 128000 {
+		t.Errorf("expected AAC 44100 Hz stereo at 128 kbps, but got %+v", p)
+	}
+}
+
+// checkFile decodes the file and checks that the chunk offset
+// still points at the media data.
+func checkFile(t *testing.T, path string) (*metadata.Track, []byte) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	track, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	moov, err := findPath(b, "moov")
+	if err != nil || moov == nil {
+		t.Fatalf("expected moov box, but got %v", err)
+	}
+	stco, err := findPath(moov.payload, "trak", "mdia", "minf", "stbl", "stco")
+	if err != nil || stco == nil {
+		t.Fatalf("expected stco box, but got %v", err)
+	}
+	offset := binary.BigEndian.Uint32(stco.payload[8:])
+	if !bytes.HasPrefix(b[offset:], mediaMarker) {
+		t.Errorf("expected chunk offset %d to point at media data", offset)
+	}
+	return track, b
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mp4")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.m4a")
+
+	ilst := mkbox("ilst", mkbox("\xa9nam", mkdata(DataTypeUTF8, []byte("Title"))))
+	original := testFileWithFree(ilst, 2048)
+	if err := ioutil.WriteFile(path, original, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	items := NewItems(&metadata.Track{
+		Title:       "New title",
+		TrackNumber: "3",
+		ISRC:        "USRC17607839",
+		Comments:    map[string]string{"tracktotal": "12", "bpm": "120"},
+		Pictures:    []metadata.Picture{{MIME: "image/jpeg", Data: []byte("jpeg")}},
+	})
+	if err := WriteFile(path, items, DefaultPadding, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	track, b := checkFile(t, path)
+	if len(b) != len(original) {
+		t.Errorf("expected moov to be written in place of free box, but size changed to %d", len(b))
+	}
+	if track.Title != "New title" || track.TrackNumber != "3" || track.Comments["tracktotal"] != "12" || track.ISRC != "USRC17607839" {
+		t.Errorf("expected written items, but got %+v", track)
+	}
+	if x := track.Comments["bpm"]; x != "120" {
+		t.Errorf(`expected Comments[bpm] to be "120", but got %q`, x)
+	}
+	if len(track.Pictures) != 1 || track.Pictures[0].MIME != "image/jpeg" {
+		t.Errorf("expected a JPEG picture, but got %v", track.Pictures)
+	}
+
+	// Large picture does not fit, so media data moves
+	items = NewItems(&metadata.Track{
+		Title:    "Moved",
+		Pictures: []metadata.Picture{{MIME: "image/png", Data: make([]byte, 4096)}},
+	})
+	if err := WriteFile(path, items, DefaultPadding, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	track, _ = checkFile(t, path)
+	if track.Title != "Moved" || len(track.Pictures) != 1 || len(track.Pictures[0].Data) != 4096 {
+		t.Errorf("expected title and picture to be written, but got %+v", track)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("expected temporary file to be renamed, but got %d files", len(files))
+	}
+}
+
+func TestShiftMovie(t *testing.T) {
+	stco := mkbox("stco", u32(0), u32(2), u32(100), u32(math.MaxUint32-10))
+	moov := mkbox("trak", mkbox("mdia", mkbox("minf", mkbox("stbl", stco))))
+
+	b, err := shiftMovie(moov, 200, 0, 8)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	co64, err := findPath(b[8:], "trak", "mdia", "minf", "stbl", "co64")
+	if err != nil || co64 == nil {
+		t.Fatalf("expected stco to be converted to co64, but got %v", err)
+	}
+	delta := uint64(len(b) - 8)
+	if x := binary.BigEndian.Uint64(co64.payload[8:]); x != 100 {
+		t.Errorf("expected offset before moov to be kept, but got %d", x)
+	}
+	if x := binary.BigEndian.Uint64(co64.payload[16:]); x != math.MaxUint32-10+delta {
+		t.Errorf("expected offset to be shifted by %d, but got %d", delta, x)
+	}
+
+	// Padding without room for free box header is dropped
+	for _, padding := range []int{-4, 5} {
+		b, err := shiftMovie(moov, 200, padding, 8)
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		co64, _ := findPath(b[8:], "trak", "mdia", "minf", "stbl", "co64")
+		delta := uint64(len(b) - 8)
+		if x := binary.BigEndian.Uint64(co64.payload[16:]); x != math.MaxUint32-10+delta {
+			t.Errorf("expected offset to be shifted by %d with padding %d, but got %d", delta, padding, x)
+		}
+	}
+}
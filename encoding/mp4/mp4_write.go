@@ -0,0 +1,321 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mp4
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/utils"
+)
+
+// DefaultPadding is the size of free box written after moov,
+// when the file is rewritten, so next edits fit in place.
+const DefaultPadding = 1024
+
+// chunkOffsetPath is the path from moov to boxes with chunk offsets.
+var chunkOffsetPath = []string{"trak", "mdia", "minf", "stbl"}
+
+// errOffsetOverflow is returned when a shifted chunk offset
+// does not fit stco box, so it has to be converted to co64.
+var errOffsetOverflow = errors.New("mp4 chunk offset overflows 32 bits")
+
+// WriteFile replaces iTunes metadata items of moov/udta/meta/ilst
+// of the file, creating missing boxes, and drops free boxes inside meta.
+//
+// If the new moov box fits into the old one together with free boxes,
+// which follow it, it is written in place and the rest of that space
+// is left as a free box. Otherwise the file is rewritten with given padding
+// after moov, and chunk offsets of stco and co64 boxes are shifted
+// for media data, which moved. stco is converted to co64 if needed.
+// In-place writes are journaled, see utils.PatchFile. opts may be nil.
+func WriteFile(path string, items []*Item, padding int, opts *utils.WriteOptions) error {
+	if err := utils.RecoverFile(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	defer f.Close()
+
+	boxes, err := readBoxHeaders(f)
+	if err != nil {
+		return err
+	}
+	i := 0
+	for i < len(boxes) && boxes[i].Type != "moov" {
+		i++
+	}
+	if i == len(boxes) {
+		return ErrNoMovie
+	}
+	moovHeader := boxes[i]
+	if moovHeader.Size > maxMovieSize {
+		return errors.New("mp4 movie box is too large")
+	}
+	moov, err := readPayload(f, moovHeader)
+	if err != nil {
+		return err
+	}
+	moov, err = setItems(moov, encodeItems(items))
+	if err != nil {
+		return err
+	}
+
+	// Free boxes after moov are reused
+	space := moovHeader.Size
+	next := i + 1
+	for next < len(boxes) && isFree(boxes[next].Type) {
+		space += boxes[next].Size
+		next++
+	}
+	atEnd := next == len(boxes)
+
+	b := encodeBox("moov", moov)
+	size := int64(len(b))
+	if atEnd || size == space || size+8 <= space {
+		f.Close()
+		end := int64(-1)
+		if atEnd {
+			end = moovHeader.Offset + size
+		} else if size < space {
+			b = append(b, encodeBox("free", make([]byte, space-size-8))...)
+		}
+		return utils.PatchFile(path, end, []utils.Patch{{Offset: moovHeader.Offset, Data: b}}, opts)
+	}
+	f.Close()
+
+	b, err = shiftMovie(moov, moovHeader.Offset+space, padding, space)
+	if err != nil {
+		return err
+	}
+	return utils.ReplaceFile(path, opts, func(w io.Writer, original *os.File) error {
+		for j, h := range boxes {
+			if j > i && j < next {
+				continue
+			}
+			if j == i {
+				if _, err := w.Write(b); err != nil {
+					return errors.Wrap("could not write moov", err)
+				}
+				continue
+			}
+			if _, err := original.Seek(h.Offset, io.SeekStart); err != nil {
+				return errors.Wrap("could not seek to "+h.Type, err)
+			}
+			if _, err := io.CopyN(w, original, h.Size); err != nil {
+				return errors.Wrap("could not copy "+h.Type, err)
+			}
+		}
+		return nil
+	})
+}
+
+// shiftMovie encodes moov followed by free box of given padding,
+// which replaces space bytes of the file. Chunk offsets at or after from
+// are shifted by the difference of sizes. Padding, which can not hold
+// a free box header, is not written.
+func shiftMovie(moov []byte, from int64, padding int, space int64) ([]byte, error) {
+	if padding < 8 {
+		padding = 0
+	}
+	wide := false
+	for {
+		// Shifting does not change the size, unless stco is converted
+		sized, err := shiftChunkOffsets(moov, chunkOffsetPath, func(offset uint64) uint64 {
+			return offset
+		}, wide)
+		if err != nil {
+			return nil, err
+		}
+		delta := int64(8+len(sized)+padding) - space
+		shifted, err := shiftChunkOffsets(moov, chunkOffsetPath, func(offset uint64) uint64 {
+			if int64(offset) >= from {
+				return uint64(int64(offset) + delta)
+			}
+			return offset
+		}, wide)
+		if err == errOffsetOverflow && !wide {
+			wide = true
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		b := encodeBox("moov", shifted)
+		if padding != 0 {
+			b = append(b, encodeBox("free", make([]byte, padding-8))...)
+		}
+		return b, nil
+	}
+}
+
+// shiftChunkOffsets rebuilds container b, descending by path,
+// and maps offsets of stco and co64 boxes at the end of path.
+// If wide is set, stco boxes are converted to co64.
+func shiftChunkOffsets(b []byte, path []string, shift func(uint64) uint64, wide bool) ([]byte, error) {
+	boxes, err := parseBoxes(b)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(b))
+	for _, child := range boxes {
+		raw := b[child.offset : child.offset+child.size()]
+		switch {
+		case len(path) != 0 && child.typ == path[0]:
+			payload, err := shiftChunkOffsets(child.payload, path[1:], shift, wide)
+			if err != nil {
+				return nil, err
+			}
+			raw = encodeBox(child.typ, payload)
+		case len(path) == 0 && (child.typ == "stco" || child.typ == "co64"):
+			raw, err = shiftOffsets(child, shift, wide)
+			if err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, raw...)
+	}
+	return out, nil
+}
+
+// shiftOffsets maps offsets of stco or co64 box:
+// 4 bytes of version and flags, 4 bytes of entry count
+// and 32-bit or 64-bit offsets.
+func shiftOffsets(b *box, shift func(uint64) uint64, wide bool) ([]byte, error) {
+	if len(b.payload) < 8 {
+		return nil, ErrInvalidBox
+	}
+	count := uint64(binary.BigEndian.Uint32(b.payload[4:]))
+	entrySize := uint64(4)
+	if b.typ == "co64" {
+		entrySize = 8
+		wide = true
+	}
+	if uint64(len(b.payload)-8) < count*entrySize {
+		return nil, ErrInvalidBox
+	}
+
+	typ := "stco"
+	if wide {
+		typ = "co64"
+	}
+	out := make([]byte, 8, 8+count*8)
+	copy(out, b.payload[:8])
+	for i := uint64(0); i < count; i++ {
+		var offset uint64
+		if entrySize == 8 {
+			offset = binary.BigEndian.Uint64(b.payload[8+i*8:])
+		} else {
+			offset = uint64(binary.BigEndian.Uint32(b.payload[8+i*4:]))
+		}
+		offset = shift(offset)
+
+		if wide {
+			out = append(out, 0, 0, 0, 0, 0, 0, 0, 0)
+			binary.BigEndian.PutUint64(out[len(out)-8:], offset)
+		} else {
+			if offset > math.MaxUint32 {
+				return nil, errOffsetOverflow
+			}
+			out = append(out, 0, 0, 0, 0)
+			binary.BigEndian.PutUint32(out[len(out)-4:], uint32(offset))
+		}
+	}
+	return encodeBox(typ, out), nil
+}
+
+// setItems returns moov payload with the payload of udta/meta/ilst
+// replaced by ilst. Missing udta, meta and ilst boxes are created.
+func setItems(moov []byte, ilst []byte) ([]byte, error) {
+	boxes, err := parseBoxes(moov)
+	if err != nil {
+		return nil, err
+	}
+	var udta []byte
+	if b := findBox(boxes, "udta"); b != nil {
+		udta = b.payload
+	}
+
+	udtaBoxes, err := parseBoxes(udta)
+	if err != nil {
+		return nil, err
+	}
+	// ISO meta is a full box, QuickTime meta has no version and flags
+	var meta []byte
+	if b := findBox(udtaBoxes, "meta"); b != nil {
+		meta = b.payload
+	} else {
+		meta = append([]byte{0, 0, 0, 0}, encodeBox("hdlr", make([]byte, 8), []byte("mdirappl"), make([]byte, 9))...)
+	}
+
+	header := len(meta) - len(metaPayload(meta))
+	children, err := replaceBox(meta[header:], "ilst", ilst, true)
+	if err != nil {
+		return nil, err
+	}
+	meta = append(meta[:header:header], children...)
+
+	udta, err = replaceBox(udta, "meta", meta, false)
+	if err != nil {
+		return nil, err
+	}
+	return replaceBox(moov, "udta", udta, false)
+}
+
+// replaceBox rebuilds container b with payload of the first child
+// of given type replaced, or appends such child if it is missing.
+// Free boxes are dropped if dropFree is set.
+func replaceBox(b []byte, typ string, payload []byte, dropFree bool) ([]byte, error) {
+	boxes, err := parseBoxes(b)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(b)+len(payload))
+	found := false
+	for _, child := range boxes {
+		switch {
+		case child.typ == typ && !found:
+			out = append(out, encodeBox(typ, payload)...)
+			found = true
+		case isFree(child.typ) && dropFree:
+		default:
+			out = append(out, b[child.offset:child.offset+child.size()]...)
+		}
+	}
+	if !found {
+		out = append(out, encodeBox(typ, payload)...)
+	}
+	return out, nil
+}
+
+func isFree(typ string) bool {
+	return typ == "free" || typ == "skip"
+}
+
+// readBoxHeaders reads headers of all top-level boxes.
+func readBoxHeaders(f io.ReadSeeker) ([]*BoxHeader, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+	var boxes []*BoxHeader
+	for offset := int64(0); offset+8 <= end; {
+		h, err := ReadBoxHeader(f, offset, end)
+		if err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, h)
+		offset += h.Size
+	}
+	return boxes, nil
+}
@@ -0,0 +1,250 @@
+// Package mpeg implements MPEG-1, MPEG-2 and MPEG-2.5 audio
+// Layer I, II and III (MP3) streams.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package mpeg
+
+import (
+	"fmt"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// FrameHeaderSize is the size of MPEG audio frame header.
+const FrameHeaderSize = 4
+
+var (
+	// ErrInvalidFrameHeader is returned when 4 bytes are not a valid frame header.
+	ErrInvalidFrameHeader = errors.New("invalid mpeg frame header")
+)
+
+// Version of MPEG audio as encoded in frame header.
+type Version uint8
+
+const (
+	Version25       Version = 0
+	VersionReserved Version = 1
+	Version2        Version = 2
+	Version1        Version = 3
+)
+
+func (v Version) String() string {
+	switch v {
+	case Version1:
+		return "MPEG-1"
+	case Version2:
+		return "MPEG-2"
+	case Version25:
+		return "MPEG-2.5"
+	}
+	return "reserved"
+}
+
+// Layer of MPEG audio as encoded in frame header.
+type Layer uint8
+
+const (
+	LayerReserved Layer = 0
+	Layer3        Layer = 1
+	Layer2        Layer = 2
+	Layer1        Layer = 3
+)
+
+func (l Layer) String() string {
+	switch l {
+	case Layer1:
+		return "Layer I"
+	case Layer2:
+		return "Layer II"
+	case Layer3:
+		return "Layer III"
+	}
+	return "reserved"
+}
+
+// Codec returns a short codec name, e.g. "MP3" for Layer III.
+func (l Layer) Codec() string {
+	switch l {
+	case Layer1:
+		return "MP1"
+	case Layer2:
+		return "MP2"
+	case Layer3:
+		return "MP3"
+	}
+	return ""
+}
+
+// ChannelMode as encoded in frame header.
+type ChannelMode uint8
+
+const (
+	ChannelModeStereo      ChannelMode = 0
+	ChannelModeJointStereo ChannelMode = 1
+	ChannelModeDualChannel ChannelMode = 2
+	ChannelModeMono        ChannelMode = 3
+)
+
+func (m ChannelMode) String() string {
+	switch m {
+	case ChannelModeStereo:
+		return "Stereo"
+	case ChannelModeJointStereo:
+		return "Joint stereo"
+	case ChannelModeDualChannel:
+		return "Dual channel"
+	case ChannelModeMono:
+		return "Mono"
+	}
+	return fmt.Sprintf("unknown<%d>", m)
+}
+
+// FrameHeader is a parsed MPEG audio frame header.
+//
+// ref: http://www.mp3-tech.org/programmer/frame_header.html
+type FrameHeader struct {
+	Version Version
+	Layer   Layer
+	// Protected is true when the frame is followed by 16-bit CRC.
+	Protected bool
+	// Bitrate in bits per second.
+	Bitrate    uint32
+	SampleRate uint32
+	Padding    bool
+	Private    bool
+	Mode       ChannelMode
+	// ModeExtension is only used in joint stereo mode.
+	ModeExtension uint8
+	Copyright     bool
+	Original      bool
+	Emphasis      uint8
+}
+
+// bitrates in kbps, indexed by [version is MPEG-1][layer][bitrate index]
+var bitrates = [2][4][16]uint16{
+	// MPEG-2 and MPEG-2.5
+	{
+		{},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	},
+	// MPEG-1
+	{
+		{},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	},
+}
+
+// sampleRates indexed by [version][sample rate index]
+var sampleRates = [4][3]uint32{
+	Version25: {11025, 12000, 8000},
+	Version2:  {22050, 24000, 16000},
+	Version1:  {44100, 48000, 32000},
+}
+
+// ParseFrameHeader parses 4 bytes of b as a frame header.
+// Free format bitrate is not supported.
+func ParseFrameHeader(b []byte) (*FrameHeader, error) {
+	if len(b) < FrameHeaderSize || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return nil, ErrInvalidFrameHeader
+	}
+
+	h := &FrameHeader{
+		Version:       Version(b[1] >> 3 & 0x3),
+		Layer:         Layer(b[1] >> 1 & 0x3),
+		Protected:     b[1]&0x1 == 0,
+		Padding:       b[2]>>1&0x1 == 1,
+		Private:       b[2]&0x1 == 1,
+		Mode:          ChannelMode(b[3] >> 6),
+		ModeExtension: b[3] >> 4 & 0x3,
+		Copyright:     b[3]>>3&0x1 == 1,
+		Original:      b[3]>>2&0x1 == 1,
+		Emphasis:      b[3] & 0x3,
+	}
+	if h.Version == VersionReserved || h.Layer == LayerReserved || h.Emphasis == 2 {
+		return nil, ErrInvalidFrameHeader
+	}
+
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := b[2] >> 2 & 0x3
+	if bitrateIndex == 0 || bitrateIndex == 0xF || sampleRateIndex == 0x3 {
+		return nil, ErrInvalidFrameHeader
+	}
+
+	isV1 := 0
+	if h.Version == Version1 {
+		isV1 = 1
+	}
+	h.Bitrate = uint32(bitrates[isV1][h.Layer][bitrateIndex]) * 1000
+	h.SampleRate = sampleRates[h.Version][sampleRateIndex]
+
+	return h, nil
+}
+
+// Channels returns the number of channels.
+func (h *FrameHeader) Channels() uint8 {
+	if h.Mode == ChannelModeMono {
+		return 1
+	}
+	return 2
+}
+
+// SamplesPerFrame returns the number of samples per channel in a frame.
+func (h *FrameHeader) SamplesPerFrame() uint32 {
+	switch {
+	case h.Layer == Layer1:
+		return 384
+	case h.Layer == Layer3 && h.Version != Version1:
+		return 576
+	}
+	return 1152
+}
+
+// FrameSize returns the size of the frame in bytes, including header.
+func (h *FrameHeader) FrameSize() uint32 {
+	if h.Layer == Layer1 {
+		size := 12 * h.Bitrate / h.SampleRate
+		if h.Padding {
+			size++
+		}
+		return size * 4
+	}
+
+	size := h.SamplesPerFrame() / 8 * h.Bitrate / h.SampleRate
+	if h.Padding {
+		size++
+	}
+	return size
+}
+
+// SideInfoSize returns the size of Layer III side information,
+// which follows frame header and precedes Xing header.
+func (h *FrameHeader) SideInfoSize() uint32 {
+	if h.Layer != Layer3 {
+		return 0
+	}
+	if h.Version == Version1 {
+		if h.Mode == ChannelModeMono {
+			return 17
+		}
+		return 32
+	}
+	if h.Mode == ChannelModeMono {
+		return 9
+	}
+	return 17
+}
+
+// matches reports whether other frame header belongs to the same stream.
+func (h *FrameHeader) matches(other *FrameHeader) bool {
+	return h.Version == other.Version &&
+		h.Layer == other.Layer &&
+		h.SampleRate == other.SampleRate
+}
@@ -0,0 +1,257 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mpeg
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+var (
+	// ErrNoFrames is returned when no MPEG audio frame was found.
+	ErrNoFrames = errors.New("no mpeg audio frames found")
+)
+
+// Stream describes an MPEG audio stream.
+type Stream struct {
+	// Header of the first frame.
+	Header *FrameHeader
+	// Offset of the first frame from the start of file.
+	Offset int64
+	// Xing is an optional Xing or Info header in the first frame.
+	Xing *XingHeader
+	// VBRI is an optional VBRI header in the first frame.
+	VBRI *VBRIHeader
+	// Frames is the number of audio frames.
+	Frames uint32
+	// Bytes is the size of audio frames.
+	Bytes int64
+	// TotalSamples is the number of samples per channel,
+	// without encoder delay and padding, if those are known.
+	TotalSamples uint64
+	// Bitrate in bits per second. For VBR streams it is the average bitrate.
+	Bitrate uint32
+	// Scanned is true when there was no Xing or VBRI header,
+	// so frames were counted by scanning the whole stream.
+	Scanned bool
+}
+
+// Decode reads MPEG audio stream properties, APE, ID3v2 and ID3v1 tags
+// into *metadata.Track. f must be positioned at the start of the file.
+// ID3v2 is the native tag of MP3, so it overrides APE tag.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	stream, err := ReadStream(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode mpeg", err)
+	}
+
+	t := &metadata.Track{}
+	stream.Apply(t)
+
+	ape, err := apetag.Read(f)
+	if err == nil {
+		ape.Apply(t)
+	} else if err != apetag.ErrNoTag && err != apetag.ErrInvalidTag {
+		return nil, errors.Wrap("could not read ape tag", err)
+	}
+
+	if stream.Offset != 0 {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to id3v2 tag", err)
+		}
+		tag, err := id3v2.Read(f)
+		if err == nil {
+			tag.Apply(t)
+		} else if err != id3v2.ErrNoTag {
+			return nil, errors.Wrap("could not read id3v2 tag", err)
+		}
+	}
+
+	tag, err := id3v1.Read(f)
+	if err == nil {
+		tag.Apply(t)
+	} else if err != id3v1.ErrNoTag {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ReadStream finds the first frame of the stream and calculates
+// stream properties, using Xing or VBRI headers where possible.
+// f must be positioned at the start of the file.
+func ReadStream(f io.ReadSeeker) (*Stream, error) {
+	start, err := id3v2.Skip(f)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := audioEnd(f)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := frames.ReadWindow(f, start, end)
+	if err != nil {
+		return nil, err
+	}
+	i, header := frames.Find(buf, end-start)
+	if header == nil {
+		return nil, ErrNoFrames
+	}
+	h := header.(*FrameHeader)
+
+	stream := &Stream{
+		Header: h,
+		Offset: start + int64(i),
+	}
+
+	frame := buf[i:]
+	if size := int(h.FrameSize()); size < len(frame) {
+		frame = frame[:size]
+	}
+	stream.Xing = parseXing(h, frame)
+	if stream.Xing == nil {
+		stream.VBRI = parseVBRI(frame)
+	}
+
+	switch {
+	case stream.Xing != nil && stream.Xing.Flags&XingFlagFrames != 0:
+		stream.Frames = stream.Xing.Frames
+		stream.Bytes = int64(stream.Xing.Bytes)
+	case stream.VBRI != nil:
+		stream.Frames = stream.VBRI.Frames
+		stream.Bytes = int64(stream.VBRI.Bytes)
+	default:
+		if err := stream.scan(f, end); err != nil {
+			return nil, err
+		}
+	}
+	if stream.Bytes == 0 {
+		stream.Bytes = end - stream.Offset
+	}
+
+	stream.TotalSamples = uint64(stream.Frames) * uint64(h.SamplesPerFrame())
+	if stream.Xing != nil && stream.Xing.LAME != nil {
+		gap := uint64(stream.Xing.LAME.EncoderDelay) + uint64(stream.Xing.LAME.EncoderPadding)
+		if gap < stream.TotalSamples {
+			stream.TotalSamples -= gap
+		}
+	}
+
+	if stream.Bitrate == 0 && stream.Frames != 0 {
+		seconds := float64(stream.Frames) * float64(h.SamplesPerFrame()) / float64(h.SampleRate)
+		stream.Bitrate = uint32(float64(stream.Bytes) * 8 / seconds)
+	}
+
+	return stream, nil
+}
+
+// frames finds and counts MPEG audio frames.
+var frames = &utils.FrameScanner{
+	Sync:       0xFF,
+	HeaderSize: FrameHeaderSize,
+	Parse: func(b []byte) (interface{}, int64, error) {
+		h, err := ParseFrameHeader(b)
+		if err != nil {
+			return nil, 0, err
+		}
+		return h, int64(h.FrameSize()), nil
+	},
+	Matches: func(first, header interface{}) bool {
+		return first.(*FrameHeader).matches(header.(*FrameHeader))
+	},
+}
+
+// scan counts frames from the first one up to end.
+// It is used when stream has no Xing or VBRI header.
+func (stream *Stream) scan(f io.ReadSeeker, end int64) error {
+	stream.Scanned = true
+	var samples uint64
+	pos, err := frames.Scan(f, stream.Offset, end, stream.Header, func(header interface{}) {
+		samples += uint64(header.(*FrameHeader).SamplesPerFrame())
+		stream.Frames++
+	})
+	if err != nil {
+		return err
+	}
+
+	stream.Bytes = pos - stream.Offset
+	if samples != 0 {
+		stream.Bitrate = uint32(stream.Bytes * 8 * int64(stream.Header.SampleRate) / int64(samples))
+	}
+	return nil
+}
+
+// audioEnd returns the offset where audio frames end,
+// excluding trailing ID3v1 and APE tags.
+func audioEnd(f io.ReadSeeker) (int64, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, errors.Wrap("could not seek to the end", err)
+	}
+	tag, err := id3v1.Read(f)
+	if err == nil {
+		end -= tag.Size()
+	} else if err != id3v1.ErrNoTag {
+		return 0, err
+	}
+
+	// Broken APE tag is left to be skipped as garbage after frames
+	ape, err := apetag.ReadAt(f, end)
+	if err == nil {
+		end = ape.Offset
+	} else if err != apetag.ErrNoTag && err != apetag.ErrInvalidTag {
+		return 0, errors.Wrap("could not read ape tag", err)
+	}
+	return end, nil
+}
+
+// Apply stream properties to the track.
+func (stream *Stream) Apply(t *metadata.Track) {
+	h := stream.Header
+	t.Properties = metadata.Properties{
+		Codec:        h.Layer.Codec(),
+		SampleRate:   h.SampleRate,
+		Channels:     h.Channels(),
+		Bitrate:      stream.Bitrate,
+		TotalSamples: stream.TotalSamples,
+	}
+	if stream.TotalSamples != 0 {
+		t.Duration = metadata.SamplesDuration(stream.TotalSamples, h.SampleRate)
+	} else {
+		t.Duration = -1
+	}
+
+	if stream.Xing != nil && stream.Xing.LAME != nil {
+		stream.Xing.LAME.Apply(t)
+	}
+}
+
+// Apply encoder and ReplayGain information to the track.
+// Keys match the ones, used by Vorbis comments.
+func (lame *LAMEHeader) Apply(t *metadata.Track) {
+	if t.Comments == nil {
+		t.Comments = map[string]string{}
+	}
+	t.Comments["encoder"] = lame.Encoder
+
+	if lame.Peak != 0 {
+		t.Comments["replaygain_track_peak"] = fmt.Sprintf("%.8f", lame.Peak)
+	}
+	if lame.TrackGain.Name == ReplayGainRadio {
+		t.Comments["replaygain_track_gain"] = fmt.Sprintf("%.2f dB", lame.TrackGain.Gain)
+	}
+	if lame.AlbumGain.Name == ReplayGainAudiophile {
+		t.Comments["replaygain_album_gain"] = fmt.Sprintf("%.2f dB", lame.AlbumGain.Gain)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mpeg
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// lameHeaderSize is the size of LAME extension of Xing header.
+const lameHeaderSize = 36
+
+// VBRMethod as stored in LAME header.
+type VBRMethod uint8
+
+const (
+	VBRMethodUnknown    VBRMethod = 0
+	VBRMethodCBR        VBRMethod = 1
+	VBRMethodABR        VBRMethod = 2
+	VBRMethodVBROld     VBRMethod = 3
+	VBRMethodVBRMTRH    VBRMethod = 4
+	VBRMethodVBRMT      VBRMethod = 5
+	VBRMethodCBRTwoPass VBRMethod = 8
+	VBRMethodABRTwoPass VBRMethod = 9
+)
+
+// ReplayGainName identifies the kind of ReplayGain value.
+type ReplayGainName uint8
+
+const (
+	ReplayGainNotSet     ReplayGainName = 0
+	ReplayGainRadio      ReplayGainName = 1
+	ReplayGainAudiophile ReplayGainName = 2
+)
+
+// ReplayGain is a gain adjustment stored in LAME header.
+type ReplayGain struct {
+	Name ReplayGainName
+	// Originator tells who set the value, e.g. 1 for artist, 3 for automatic.
+	Originator uint8
+	// Gain adjustment in dB.
+	Gain float64
+}
+
+// LAMEHeader is an extension of Xing header written by LAME.
+// FFmpeg writes a compatible header, starting with "Lavf" or "Lavc".
+//
+// ref: http://gabriel.mp3-tech.org/mp3infotag.html
+type LAMEHeader struct {
+	// Encoder version, e.g. "LAME3.99r"
+	Encoder     string
+	TagRevision uint8
+	VBRMethod   VBRMethod
+	// Lowpass filter frequency in Hz.
+	Lowpass uint32
+	// Peak signal amplitude, where 1.0 is the maximal amplitude.
+	// Zero implies unknown.
+	Peak       float64
+	TrackGain  ReplayGain
+	AlbumGain  ReplayGain
+	EncodeFlag uint8
+	ATHType    uint8
+	// Bitrate is the ABR bitrate or the minimal VBR bitrate in kbps.
+	Bitrate uint8
+	// EncoderDelay is the number of samples added at the start.
+	EncoderDelay uint16
+	// EncoderPadding is the number of samples added at the end.
+	EncoderPadding uint16
+	Misc           uint8
+	// MP3Gain is a global gain applied by MP3Gain, in steps of 1.5 dB.
+	MP3Gain  int8
+	Surround uint8
+	Preset   uint16
+	// MusicLength is the size of the stream from the first frame
+	// to the last one, including the frame of this header.
+	MusicLength uint32
+	MusicCRC    uint16
+	TagCRC      uint16
+}
+
+func parseLAME(b []byte) *LAMEHeader {
+	if len(b) < lameHeaderSize {
+		return nil
+	}
+	encoder := string(b[:4])
+	if encoder != "LAME" && encoder != "Lavf" && encoder != "Lavc" && encoder != "L3.9" {
+		return nil
+	}
+
+	lame := &LAMEHeader{
+		Encoder:        strings.TrimRight(string(b[:9]), "\x00 "),
+		TagRevision:    b[9] >> 4,
+		VBRMethod:      VBRMethod(b[9] & 0xF),
+		Lowpass:        uint32(b[10]) * 100,
+		TrackGain:      parseReplayGain(binary.BigEndian.Uint16(b[15:17])),
+		AlbumGain:      parseReplayGain(binary.BigEndian.Uint16(b[17:19])),
+		EncodeFlag:     b[19] >> 4,
+		ATHType:        b[19] & 0xF,
+		Bitrate:        b[20],
+		EncoderDelay:   uint16(b[21])<<4 | uint16(b[22]>>4),
+		EncoderPadding: uint16(b[22]&0xF)<<8 | uint16(b[23]),
+		Misc:           b[24],
+		MP3Gain:        int8(b[25]),
+		Surround:       b[26] >> 3 & 0x7,
+		Preset:         binary.BigEndian.Uint16(b[26:28]) & 0x7FF,
+		MusicLength:    binary.BigEndian.Uint32(b[28:32]),
+		MusicCRC:       binary.BigEndian.Uint16(b[32:34]),
+		TagCRC:         binary.BigEndian.Uint16(b[34:36]),
+	}
+
+	// Peak is a fixed-point number, where 1.0 is 2^23.
+	if peak := binary.BigEndian.Uint32(b[11:15]); peak != 0 {
+		lame.Peak = float64(peak) / (1 << 23)
+	}
+
+	return lame
+}
+
+// parseReplayGain parses 16 bits of ReplayGain field:
+// 3 bits of name, 3 bits of originator, sign bit and 9 bits of gain*10.
+func parseReplayGain(x uint16) ReplayGain {
+	rg := ReplayGain{
+		Name:       ReplayGainName(x >> 13),
+		Originator: uint8(x >> 10 & 0x7),
+		Gain:       float64(x&0x1FF) / 10,
+	}
+	if x>>9&0x1 == 1 {
+		rg.Gain = -rg.Gain
+	}
+	return rg
+}
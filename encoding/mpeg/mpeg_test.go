@@ -0,0 +1,109 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// MPEG-1 Layer III, 128 kbps, 44100 Hz, stereo, no CRC
+var testHeader = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+const testFrameSize = 417
+
+func testFrame() []byte {
+	frame := make([]byte, testFrameSize)
+	copy(frame, testHeader)
+	return frame
+}
+
+func TestParseFrameHeader(t *testing.T) {
+	h, err := ParseFrameHeader(testHeader)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if h.Version != Version1 || h.Layer != Layer3 {
+		t.Errorf("expected MPEG-1 Layer III, but got %s %s", h.Version, h.Layer)
+	}
+	if h.Bitrate != 128000 || h.SampleRate != 44100 {
+		t.Errorf("expected 128000 bps at 44100 Hz, but got %d bps at %d Hz", h.Bitrate, h.SampleRate)
+	}
+	if h.FrameSize() != testFrameSize {
+		t.Errorf("expected frame size %d, but got %d", testFrameSize, h.FrameSize())
+	}
+
+	if _, err := ParseFrameHeader([]byte{0xFF, 0xF1, 0x50, 0x80}); err != ErrInvalidFrameHeader {
+		t.Errorf("expected ADTS header to be rejected, but got %v", err)
+	}
+}
+
+func TestDecodeScan(t *testing.T) {
+	var file bytes.Buffer
+	// empty ID3v2.3 tag with 20 bytes of padding
+	file.Write([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 20})
+	file.Write(make([]byte, 20))
+	for i := 0; i < 100; i++ {
+		file.Write(testFrame())
+	}
+	tag := make([]byte, 128)
+	copy(tag, "TAGTitle")
+	file.Write(tag)
+
+	track, err := Decode(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Properties.TotalSamples != 100*1152 {
+		t.Errorf("expected %d samples, but got %d", 100*1152, track.Properties.TotalSamples)
+	}
+	if track.Duration != 2612244897*time.Nanosecond {
+		t.Errorf("expected duration 2.612244897s, but got %s", track.Duration)
+	}
+	if track.Title != "Title" {
+		t.Errorf(`expected Title to be "Title", but got %q`, track.Title)
+	}
+}
+
+func TestDecodeXingLAME(t *testing.T) {
+	first := testFrame()
+	xing := first[FrameHeaderSize+32:]
+	copy(xing, "Xing")
+	binary.BigEndian.PutUint32(xing[4:], XingFlagFrames|XingFlagBytes)
+	binary.BigEndian.PutUint32(xing[8:], 1000)
+	binary.BigEndian.PutUint32(xing[12:], 1000*testFrameSize)
+
+	lame := xing[16:]
+	copy(lame, "LAME3.99r")
+	lame[9] = 0x24
+	binary.BigEndian.PutUint32(lame[11:], 1<<23)
+	// radio gain, set automatically, -6.5 dB
+	binary.BigEndian.PutUint16(lame[15:], 1<<13|3<<10|1<<9|65)
+	// encoder delay 576, padding 1000
+	lame[21], lame[22], lame[23] = 0x24, 0x03, 0xE8
+
+	file := append(first, testFrame()...)
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if x := track.Properties.TotalSamples; x != 1000*1152-1576 {
+		t.Errorf("expected %d samples, but got %d", 1000*1152-1576, x)
+	}
+	if x := track.Comments["encoder"]; x != "LAME3.99r" {
+		t.Errorf(`expected Comments[encoder] to be "LAME3.99r", but got %q`, x)
+	}
+	if x := track.Comments["replaygain_track_gain"]; x != "-6.50 dB" {
+		t.Errorf(`expected Comments[replaygain_track_gain] to be "-6.50 dB", but got %q`, x)
+	}
+	if x := track.Comments["replaygain_track_peak"]; x != "1.00000000" {
+		t.Errorf(`expected Comments[replaygain_track_peak] to be "1.00000000", but got %q`, x)
+	}
+}
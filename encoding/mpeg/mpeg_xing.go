@@ -0,0 +1,151 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package mpeg
+
+import (
+	"encoding/binary"
+)
+
+// Xing header flags
+const (
+	XingFlagFrames  = 0x1
+	XingFlagBytes   = 0x2
+	XingFlagTOC     = 0x4
+	XingFlagQuality = 0x8
+)
+
+// XingHeader is a Xing or Info header, which is stored
+// in the first frame of the stream instead of audio data.
+// Encoders write "Xing" for VBR streams and "Info" for CBR streams.
+//
+// ref: http://gabriel.mp3-tech.org/mp3infotag.html
+type XingHeader struct {
+	// IsInfo is true for "Info" header of CBR stream.
+	IsInfo bool
+	Flags  uint32
+	// Frames is the number of frames in the stream.
+	Frames uint32
+	// Bytes is the size of the stream in bytes.
+	Bytes uint32
+	// TOC is a seek table of 100 entries.
+	TOC []byte
+	// Quality indicator, from 0 (best) to 100 (worst).
+	Quality uint32
+	// LAME is an optional extension written by LAME and compatible encoders.
+	LAME *LAMEHeader
+}
+
+// VBRIHeader is written by Fraunhofer encoder
+// 32 bytes after the frame header of the first frame.
+type VBRIHeader struct {
+	Version uint16
+	Delay   uint16
+	Quality uint16
+	// Bytes is the size of the stream in bytes.
+	Bytes uint32
+	// Frames is the number of frames in the stream.
+	Frames uint32
+	// TOC is a seek table, each entry is scaled by TOCScale.
+	TOC            []uint32
+	TOCScale       uint16
+	FramesPerEntry uint16
+}
+
+// vbriOffset is an offset of VBRI header from the start of the frame.
+const vbriOffset = FrameHeaderSize + 32
+
+// parseXing parses Xing header from the first frame.
+// Nil is returned if the frame has no Xing header.
+func parseXing(h *FrameHeader, frame []byte) *XingHeader {
+	offset := FrameHeaderSize + h.SideInfoSize()
+	if h.Protected {
+		offset += 2
+	}
+	if uint32(len(frame)) < offset+8 {
+		return nil
+	}
+
+	b := frame[offset:]
+	id := string(b[:4])
+	if id != "Xing" && id != "Info" {
+		return nil
+	}
+
+	xing := &XingHeader{
+		IsInfo: id == "Info",
+		Flags:  binary.BigEndian.Uint32(b[4:8]),
+	}
+	b = b[8:]
+
+	if xing.Flags&XingFlagFrames != 0 {
+		if len(b) < 4 {
+			return nil
+		}
+		xing.Frames = binary.BigEndian.Uint32(b)
+		b = b[4:]
+	}
+	if xing.Flags&XingFlagBytes != 0 {
+		if len(b) < 4 {
+			return nil
+		}
+		xing.Bytes = binary.BigEndian.Uint32(b)
+		b = b[4:]
+	}
+	if xing.Flags&XingFlagTOC != 0 {
+		if len(b) < 100 {
+			return nil
+		}
+		xing.TOC = append([]byte(nil), b[:100]...)
+		b = b[100:]
+	}
+	if xing.Flags&XingFlagQuality != 0 {
+		if len(b) < 4 {
+			return nil
+		}
+		xing.Quality = binary.BigEndian.Uint32(b)
+		b = b[4:]
+	}
+
+	xing.LAME = parseLAME(b)
+	return xing
+}
+
+// parseVBRI parses VBRI header from the first frame.
+// Nil is returned if the frame has no VBRI header.
+func parseVBRI(frame []byte) *VBRIHeader {
+	if len(frame) < vbriOffset+26 || string(frame[vbriOffset:vbriOffset+4]) != "VBRI" {
+		return nil
+	}
+
+	b := frame[vbriOffset+4:]
+	vbri := &VBRIHeader{
+		Version:        binary.BigEndian.Uint16(b[0:2]),
+		Delay:          binary.BigEndian.Uint16(b[2:4]),
+		Quality:        binary.BigEndian.Uint16(b[4:6]),
+		Bytes:          binary.BigEndian.Uint32(b[6:10]),
+		Frames:         binary.BigEndian.Uint32(b[10:14]),
+		TOCScale:       binary.BigEndian.Uint16(b[16:18]),
+		FramesPerEntry: binary.BigEndian.Uint16(b[20:22]),
+	}
+
+	entries := int(binary.BigEndian.Uint16(b[14:16]))
+	entrySize := int(binary.BigEndian.Uint16(b[18:20]))
+	b = b[22:]
+	if entrySize < 1 || entrySize > 4 || len(b) < entries*entrySize {
+		return vbri
+	}
+
+	vbri.TOC = make([]uint32, entries)
+	for i := range vbri.TOC {
+		n := uint32(0)
+		for _, x := range b[i*entrySize : (i+1)*entrySize] {
+			n = n<<8 | uint32(x)
+		}
+		vbri.TOC[i] = n * uint32(vbri.TOCScale)
+	}
+	return vbri
+}
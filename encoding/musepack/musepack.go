@@ -0,0 +1,100 @@
+// Package musepack implements Musepack SV7 and SV8 stream headers.
+// Tags of Musepack files are read by packages apetag, id3v2 and id3v1.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package musepack
+
+import (
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+)
+
+const (
+	// FrameSamples is the number of samples per channel in a frame.
+	FrameSamples = 1152
+	// SV7HeaderSize is the size of SV7 stream header.
+	SV7HeaderSize = 28
+
+	// synthDelay is the delay of SV7 decoder,
+	// which is subtracted from files without gapless information.
+	synthDelay = 481
+	// maxPacketSize limits the size of SV8 packets, which are read into memory.
+	maxPacketSize = 1 << 20
+)
+
+// Keys of SV8 packets.
+const (
+	KeyStreamHeader    = "SH"
+	KeyReplayGain      = "RG"
+	KeyEncoderInfo     = "EI"
+	KeySeekTableOffset = "SO"
+	KeyAudio           = "AP"
+	KeyStreamEnd       = "SE"
+)
+
+var (
+	// ErrNotMusepack is returned when file is neither SV7 nor SV8 stream.
+	ErrNotMusepack = errors.New("not a musepack file")
+	// ErrNoStreamHeader is returned when SV8 stream has no SH packet
+	// before the audio.
+	ErrNoStreamHeader = errors.New("no musepack stream header")
+)
+
+// sampleRates are indexed by sample frequency field of both versions.
+var sampleRates = [...]uint32{44100, 48000, 37800, 32000}
+
+// ReplayGain of the stream. Gains are in dB and peaks are linear,
+// where 1.0 is the maximal amplitude. Zero gain means it was not set.
+type ReplayGain struct {
+	TrackGain float64
+	TrackPeak float64
+	AlbumGain float64
+	AlbumPeak float64
+}
+
+// StreamHeader is a normalized header of SV7 or SV8 stream.
+//
+// ref: https://trac.musepack.net/musepack/wiki/SV8Specification
+// ref: https://trac.musepack.net/musepack/wiki/SV7Specification
+type StreamHeader struct {
+	// Version is 7 or 8.
+	Version    uint8
+	SampleRate uint32
+	Channels   uint8
+	// SampleCount is the number of samples per channel
+	// including beginning silence.
+	SampleCount uint64
+	// BeginningSilence is the number of samples to skip at the start.
+	BeginningSilence uint64
+	MidSideStereo    bool
+	// Profile is the quality of encoder, e.g. 5.0 for --standard.
+	Profile float64
+}
+
+// File describes the parts of Musepack file relevant to metadata.
+type File struct {
+	Header     StreamHeader
+	ReplayGain *ReplayGain
+	// Encoder is the name and version of encoder, e.g. "1.15r".
+	Encoder string
+	// Offset of the stream from the start of file,
+	// which is not zero if the file has ID3v2 tag.
+	Offset int64
+	// AudioSize is the size of the stream without tags.
+	AudioSize int64
+	ID3       *id3v2.Tag
+	APE       *apetag.Tag
+}
+
+// TotalSamples is the number of playable samples per channel.
+func (h *StreamHeader) TotalSamples() uint64 {
+	if h.BeginningSilence > h.SampleCount {
+		return 0
+	}
+	return h.SampleCount - h.BeginningSilence
+}
@@ -0,0 +1,188 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package musepack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// Decode reads Musepack stream properties, ID3v2, APE and ID3v1 tags
+// into *metadata.Track. f must be positioned at the start of the file.
+// APE is the native tag of Musepack, so it overrides ID3v2 tag.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode musepack", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+
+	tag, err := id3v1.Read(f)
+	if err == nil {
+		tag.Apply(t)
+	} else if err != id3v1.ErrNoTag {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ReadFile reads stream header of SV7 or SV8 Musepack file and its tags.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	start, err := id3v2.Skip(f)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{Offset: start}
+	if start != 0 {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to id3v2 tag", err)
+		}
+		tag, err := id3v2.Read(f)
+		if err != nil {
+			return nil, errors.Wrap("could not read id3v2 tag", err)
+		}
+		file.ID3 = tag
+		if _, err := f.Seek(start, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to stream", err)
+		}
+	}
+
+	var b [SV7HeaderSize]byte
+	if _, err := io.ReadFull(f, b[:4]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotMusepack
+		}
+		return nil, errors.Wrap("could not read magic", err)
+	}
+	switch {
+	case string(b[:4]) == "MPCK":
+		if err := file.readSV8(f, start+4); err != nil {
+			return nil, err
+		}
+	case string(b[:3]) == "MP+" && b[3]&0x0F == 7:
+		if _, err := io.ReadFull(f, b[4:]); err != nil {
+			return nil, errors.Wrap("could not read sv7 header", err)
+		}
+		file.parseSV7(b[:])
+	default:
+		return nil, ErrNotMusepack
+	}
+
+	ape, end, err := apetag.ReadTrailing(f)
+	if err != nil {
+		return nil, err
+	}
+	file.APE = ape
+	file.AudioSize = end - start
+	return file, nil
+}
+
+// parseSV7 parses SV7 header, which is a sequence of little-endian
+// 32-bit words, whose fields are stored from the most significant bit.
+func (file *File) parseSV7(b []byte) {
+	frames := uint64(binary.LittleEndian.Uint32(b[4:8]))
+	flags := binary.LittleEndian.Uint32(b[8:12])
+	gapless := binary.LittleEndian.Uint32(b[20:24])
+
+	h := StreamHeader{
+		Version:       7,
+		SampleRate:    sampleRates[flags>>16&0x3],
+		Channels:      2,
+		SampleCount:   frames * FrameSamples,
+		MidSideStereo: flags>>30&0x1 == 1,
+	}
+	// Profiles 5 to 15 are qualities 0 to 10
+	if profile := flags >> 20 & 0xF; profile >= 5 {
+		h.Profile = float64(profile - 5)
+	}
+
+	var padding uint64 = synthDelay
+	if gapless>>31 == 1 {
+		padding = FrameSamples - uint64(gapless>>20&0x7FF)
+	}
+	if h.SampleCount > padding {
+		h.SampleCount -= padding
+	}
+	file.Header = h
+
+	// Gains are in 0.01 dB and peaks are 16-bit sample values
+	rg := &ReplayGain{}
+	if gain := int16(binary.LittleEndian.Uint16(b[14:16])); gain != 0 {
+		rg.TrackGain = float64(gain) / 100
+	}
+	if peak := binary.LittleEndian.Uint16(b[12:14]); peak != 0 {
+		rg.TrackPeak = float64(peak) / (1 << 15)
+	}
+	if gain := int16(binary.LittleEndian.Uint16(b[18:20])); gain != 0 {
+		rg.AlbumGain = float64(gain) / 100
+	}
+	if peak := binary.LittleEndian.Uint16(b[16:18]); peak != 0 {
+		rg.AlbumPeak = float64(peak) / (1 << 15)
+	}
+	if *rg != (ReplayGain{}) {
+		file.ReplayGain = rg
+	}
+
+	if version := b[27]; version != 0 {
+		file.Encoder = fmt.Sprintf("%d.%02d", version/100, version%100)
+	}
+}
+
+// Apply stream properties, ReplayGain, ID3v2 and APE tags to the track.
+// Keys match the ones, used by Vorbis comments.
+func (file *File) Apply(t *metadata.Track) {
+	h := file.Header
+	t.Properties = metadata.Properties{
+		Codec:        "Musepack",
+		SampleRate:   h.SampleRate,
+		Channels:     h.Channels,
+		TotalSamples: h.TotalSamples(),
+	}
+	t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, h.SampleRate)
+	if t.Properties.TotalSamples != 0 {
+		t.Properties.Bitrate = uint32(uint64(file.AudioSize) * 8 * uint64(h.SampleRate) / t.Properties.TotalSamples)
+	}
+
+	if t.Comments == nil {
+		t.Comments = map[string]string{}
+	}
+	if file.Encoder != "" {
+		t.Comments["encoder"] = file.Encoder
+	}
+	if rg := file.ReplayGain; rg != nil {
+		if rg.TrackGain != 0 {
+			t.Comments["replaygain_track_gain"] = fmt.Sprintf("%.2f dB", rg.TrackGain)
+		}
+		if rg.TrackPeak != 0 {
+			t.Comments["replaygain_track_peak"] = fmt.Sprintf("%.8f", rg.TrackPeak)
+		}
+		if rg.AlbumGain != 0 {
+			t.Comments["replaygain_album_gain"] = fmt.Sprintf("%.2f dB", rg.AlbumGain)
+		}
+		if rg.AlbumPeak != 0 {
+			t.Comments["replaygain_album_peak"] = fmt.Sprintf("%.8f", rg.AlbumPeak)
+		}
+	}
+
+	if file.ID3 != nil {
+		file.ID3.Apply(t)
+	}
+	if file.APE != nil {
+		file.APE.Apply(t)
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package musepack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// oldGainReference is the loudness in dB, relative to which
+// SV8 stores ReplayGain values.
+const oldGainReference = 64.82
+
+// readSV8 reads packets from offset up to the first audio packet.
+// Each packet is a 2-letter key and a variable-length size,
+// which includes the key and the size itself.
+func (file *File) readSV8(f io.ReadSeeker, offset int64) error {
+	hasHeader := false
+	for {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return errors.Wrap("could not seek to packet", err)
+		}
+		key, size, headerSize, err := readPacketHeader(f)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if key == KeyAudio || key == KeyStreamEnd {
+			break
+		}
+
+		switch key {
+		case KeyStreamHeader, KeyReplayGain, KeyEncoderInfo:
+			if size > maxPacketSize {
+				return errors.New("musepack packet is too large")
+			}
+			b := make([]byte, size-headerSize)
+			if _, err := io.ReadFull(f, b); err != nil {
+				return errors.Wrap("could not read packet", err)
+			}
+			if err := file.parsePacket(key, b); err != nil {
+				return err
+			}
+			hasHeader = hasHeader || key == KeyStreamHeader
+		}
+		offset += size
+	}
+
+	if !hasHeader {
+		return ErrNoStreamHeader
+	}
+	return nil
+}
+
+// readPacketHeader reads key and size of SV8 packet.
+// io.EOF is returned, when there are no more packets.
+func readPacketHeader(r io.Reader) (key string, size, headerSize int64, err error) {
+	var b [2]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		if err != io.EOF {
+			err = errors.Wrap("could not read packet key", err)
+		}
+		return "", 0, 0, err
+	}
+	if b[0] < 'A' || b[0] > 'Z' || b[1] < 'A' || b[1] > 'Z' {
+		return "", 0, 0, errors.New("invalid musepack packet key")
+	}
+
+	headerSize = 2
+	for n := 0; ; n++ {
+		if n == binary.MaxVarintLen64 {
+			return "", 0, 0, errors.New("invalid musepack packet size")
+		}
+		var c [1]byte
+		if _, err := io.ReadFull(r, c[:]); err != nil {
+			return "", 0, 0, errors.Wrap("could not read packet size", err)
+		}
+		headerSize++
+		size = size<<7 | int64(c[0]&0x7F)
+		if c[0]&0x80 == 0 {
+			break
+		}
+	}
+	if size < headerSize {
+		return "", 0, 0, errors.New("invalid musepack packet size")
+	}
+	return string(b[:]), size, headerSize, nil
+}
+
+// parseVarint parses variable-length number, where every byte holds
+// 7 bits and the most significant bit is set, if more bytes follow.
+// It returns the number of parsed bytes, or 0 if b is too short.
+func parseVarint(b []byte) (uint64, int) {
+	var x uint64
+	for i := 0; i < len(b) && i < binary.MaxVarintLen64; i++ {
+		x = x<<7 | uint64(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			return x, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func (file *File) parsePacket(key string, b []byte) error {
+	switch key {
+	case KeyStreamHeader:
+		return file.parseStreamHeader(b)
+	case KeyReplayGain:
+		file.parseReplayGain(b)
+	case KeyEncoderInfo:
+		file.parseEncoderInfo(b)
+	}
+	return nil
+}
+
+// parseStreamHeader parses SH packet: CRC, version, sample count,
+// beginning silence, sample frequency, bands, channels and stereo mode.
+func (file *File) parseStreamHeader(b []byte) error {
+	errInvalid := errors.New("invalid musepack stream header")
+	if len(b) < 5 {
+		return errInvalid
+	}
+	h := StreamHeader{Version: b[4], Profile: file.Header.Profile}
+	b = b[5:]
+
+	var n int
+	if h.SampleCount, n = parseVarint(b); n == 0 {
+		return errInvalid
+	}
+	b = b[n:]
+	if h.BeginningSilence, n = parseVarint(b); n == 0 {
+		return errInvalid
+	}
+	b = b[n:]
+	if len(b) < 2 {
+		return errInvalid
+	}
+
+	rate := b[0] >> 5
+	if int(rate) >= len(sampleRates) {
+		return errors.New("invalid musepack sample frequency")
+	}
+	h.SampleRate = sampleRates[rate]
+	h.Channels = b[1]>>4 + 1
+	h.MidSideStereo = b[1]>>3&0x1 == 1
+	file.Header = h
+	return nil
+}
+
+// parseReplayGain parses RG packet: version and 16-bit gains and peaks
+// of track and album. Gains are relative to 64.82 dB in 1/256 dB,
+// and peaks are 256 * 20 * log10 of 16-bit sample value.
+func (file *File) parseReplayGain(b []byte) {
+	if len(b) < 9 || b[0] != 1 {
+		return
+	}
+	gain := func(b []byte) float64 {
+		if x := int16(binary.BigEndian.Uint16(b)); x != 0 {
+			return oldGainReference - float64(x)/256
+		}
+		return 0
+	}
+	peak := func(b []byte) float64 {
+		if x := binary.BigEndian.Uint16(b); x != 0 {
+			return math.Pow(10, float64(x)/256/20) / (1 << 15)
+		}
+		return 0
+	}
+
+	rg := &ReplayGain{
+		TrackGain: gain(b[1:3]),
+		TrackPeak: peak(b[3:5]),
+		AlbumGain: gain(b[5:7]),
+		AlbumPeak: peak(b[7:9]),
+	}
+	if *rg != (ReplayGain{}) {
+		file.ReplayGain = rg
+	}
+}
+
+// parseEncoderInfo parses EI packet: 7 bits of profile in 1/8 units,
+// PNS flag and major, minor and build version of encoder.
+func (file *File) parseEncoderInfo(b []byte) {
+	if len(b) < 4 {
+		return
+	}
+	file.Header.Profile = float64(b[0]>>1) / 8
+	file.Encoder = fmt.Sprintf("%d.%d.%d", b[1], b[2], b[3])
+}
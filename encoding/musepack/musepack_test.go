@@ -0,0 +1,156 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package musepack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+)
+
+var id3 = []byte("ID3\x03\x00\x00\x00\x00\x00\x12TIT2\x00\x00\x00\x08\x00\x00\x00ID3 Tit")
+
+// mkape builds APEv2 tag with a footer only.
+func mkape(key, value string) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b, uint32(len(value)))
+	b = append(b, key...)
+	b = append(b, 0)
+	b = append(b, value...)
+	footer := make([]byte, 32)
+	copy(footer, "APETAGEX")
+	binary.LittleEndian.PutUint32(footer[8:], 2000)
+	binary.LittleEndian.PutUint32(footer[12:], uint32(len(b)+32))
+	binary.LittleEndian.PutUint32(footer[16:], 1)
+	return append(b, footer...)
+}
+
+func varint(x uint64) []byte {
+	b := []byte{byte(x & 0x7F)}
+	for x >>= 7; x != 0; x >>= 7 {
+		b = append([]byte{byte(x&0x7F) | 0x80}, b...)
+	}
+	return b
+}
+
+// packet builds SV8 packet, whose size includes the key and the size.
+func packet(key string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	size := uint64(2 + len(body))
+	for n := 1; ; n++ {
+		if len(varint(size+uint64(n))) == n {
+			size += uint64(n)
+			break
+		}
+	}
+	return bytes.Join([][]byte{[]byte(key), varint(size), body}, nil)
+}
+
+func be16(x uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, x)
+	return b
+}
+
+func sv8(audio []byte) []byte {
+	return bytes.Join([][]byte{
+		[]byte("MPCK"),
+		packet(KeyStreamHeader, []byte{0, 0, 0, 0, 8}, varint(88200+1000), varint(1000), []byte{0<<5 | 31, 1<<4 | 1<<3 | 2}),
+		packet(KeyReplayGain, []byte{1}, be16(17362), be16(21578), be16(0), be16(0)),
+		packet(KeyEncoderInfo, []byte{5 * 8 << 1, 1, 16, 2}),
+		packet(KeySeekTableOffset, varint(0)),
+		packet(KeyAudio, audio),
+		packet(KeyStreamEnd),
+	}, nil)
+}
+
+func TestDecodeSV8(t *testing.T) {
+	stream := sv8(make([]byte, 1000))
+	file := bytes.Join([][]byte{id3, stream, mkape("Artist", "APE Artist")}, nil)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "ID3 Tit" || track.Artist != "APE Artist" {
+		t.Errorf("expected title from ID3v2 and artist from APE, but got %q and %q", track.Title, track.Artist)
+	}
+	if x := track.Comments["encoder"]; x != "1.16.2" {
+		t.Errorf(`expected Comments[encoder] to be "1.16.2", but got %q`, x)
+	}
+	if x := track.Comments["replaygain_track_gain"]; x != "-3.00 dB" {
+		t.Errorf(`expected Comments[replaygain_track_gain] to be "-3.00 dB", but got %q`, x)
+	}
+	if track.Duration != 2*time.Second {
+		t.Errorf("expected duration 2s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "Musepack" || p.SampleRate != 44100 || p.Channels != 2 || p.TotalSamples != 88200 {
+		t.Errorf("expected 88200 samples of stereo Musepack at 44.1 kHz, but got %+v", p)
+	}
+	if p.Bitrate != uint32(len(stream)*8/2) {
+		t.Errorf("expected bitrate of %d bytes per 2 seconds, but got %d", len(stream), p.Bitrate)
+	}
+}
+
+func TestReadFileSV8(t *testing.T) {
+	file, err := ReadFile(bytes.NewReader(sv8(nil)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	h := file.Header
+	if h.Version != 8 || !h.MidSideStereo || h.Profile != 5 || h.BeginningSilence != 1000 {
+		t.Errorf("expected SV8 with mid-side stereo at profile 5, but got %+v", h)
+	}
+	rg := file.ReplayGain
+	if rg == nil || math.Abs(rg.TrackPeak-0.5) > 0.001 || rg.AlbumGain != 0 || rg.AlbumPeak != 0 {
+		t.Errorf("expected track peak 0.5 without album gain, but got %+v", rg)
+	}
+}
+
+func TestDecodeSV7(t *testing.T) {
+	b := make([]byte, SV7HeaderSize)
+	copy(b, "MP+\x17")
+	binary.LittleEndian.PutUint32(b[4:], 84)
+	binary.LittleEndian.PutUint32(b[8:], 1<<30|10<<20|1<<16)
+	binary.LittleEndian.PutUint16(b[12:], 1<<14)
+	binary.LittleEndian.PutUint16(b[14:], uint16(0x10000-650))
+	binary.LittleEndian.PutUint32(b[20:], 1<<31|384<<20)
+	b[27] = 115
+
+	track, err := Decode(bytes.NewReader(append(b, make([]byte, 1000)...)))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Duration != 2*time.Second {
+		t.Errorf("expected duration 2s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.SampleRate != 48000 || p.Channels != 2 || p.TotalSamples != 96000 {
+		t.Errorf("expected 96000 samples of stereo at 48 kHz, but got %+v", p)
+	}
+	if x := track.Comments["replaygain_track_gain"]; x != "-6.50 dB" {
+		t.Errorf(`expected Comments[replaygain_track_gain] to be "-6.50 dB", but got %q`, x)
+	}
+	if x := track.Comments["replaygain_track_peak"]; x != "0.50000000" {
+		t.Errorf(`expected Comments[replaygain_track_peak] to be "0.50000000", but got %q`, x)
+	}
+	if x := track.Comments["encoder"]; x != "1.15" {
+		t.Errorf(`expected Comments[encoder] to be "1.15", but got %q`, x)
+	}
+}
+
+func TestReadFileNotMusepack(t *testing.T) {
+	if _, err := ReadFile(bytes.NewReader([]byte("MP+\x04"))); err != ErrNotMusepack {
+		t.Errorf("expected ErrNotMusepack, but got %v", err)
+	}
+	if _, err := ReadFile(bytes.NewReader([]byte("MPCK"))); err != ErrNoStreamHeader {
+		t.Errorf("expected ErrNoStreamHeader, but got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"bytes"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/valyala/bytebufferpool"
+)
+
+// flacMagic starts the first packet of Ogg FLAC stream.
+//
+// ref: https://xiph.org/flac/ogg_mapping.html
+var flacMagic = []byte("\x7fFLAC")
+
+// maxFLACBlockSize is the largest length of FLAC metadata block.
+const maxFLACBlockSize = 1<<24 - 1
+
+// flacHeaderSize is the size of the first packet of Ogg FLAC stream:
+// mapping header, "fLaC" marker and STREAMINFO metadata block.
+const flacHeaderSize = 9 + 4 + 4 + 34
+
+func isFLACHeader(packet []byte) bool {
+	return bytes.HasPrefix(packet, flacMagic)
+}
+
+// ParseFLACStreamInfo parses STREAMINFO block of the first header packet.
+// Only mapping version 1 is supported.
+func ParseFLACStreamInfo(packet []byte) (*flac.StreamInfo, error) {
+	if !isFLACHeader(packet) || len(packet) < flacHeaderSize || string(packet[9:13]) != "fLaC" ||
+		flac.BlockType(packet[13]&0x7F) != flac.BlockTypeStreamInfo {
+		return nil, errors.New("invalid ogg flac identification header")
+	}
+	if packet[5] != 1 {
+		return nil, errors.New("unsupported ogg flac mapping version")
+	}
+
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+	block := &flac.MetadataBlock{}
+	if err := block.LoadStreamInfo(bytes.NewReader(packet[17:flacHeaderSize]), bb); err != nil {
+		return nil, errors.Wrap("could not read flac stream info", err)
+	}
+	return block.Data.(*flac.StreamInfo), nil
+}
+
+// decodeFLAC reads metadata blocks, which follow the first header packet,
+// one per packet, until the last one. The first of them is Vorbis comment.
+// Pictures are read too, other blocks are skipped.
+func decodeFLAC(pr *PacketReader, headPacket []byte) (*metadata.Track, error) {
+	stream, err := ParseFLACStreamInfo(headPacket)
+	if err != nil {
+		return nil, err
+	}
+	t := &metadata.Track{}
+	stream.Apply(t)
+
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+	for i := 0; ; i++ {
+		packet, err := pr.NextPacket()
+		if err != nil {
+			return nil, errors.Wrap("could not read flac metadata block", err)
+		}
+		if len(packet) < 4 {
+			return nil, errors.New("invalid flac metadata block")
+		}
+		blockType := flac.BlockType(packet[0] & 0x7F)
+		if i == 0 && blockType != flac.BlockTypeVorbisComment {
+			return nil, errors.New("ogg flac comment header is missing")
+		}
+		if blockType == flac.BlockTypeVorbisComment || blockType == flac.BlockTypePicture {
+			block := &flac.MetadataBlock{}
+			bb.Reset()
+			if blockType == flac.BlockTypeVorbisComment {
+				err = block.LoadVorbisComment(bytes.NewReader(packet[4:]), bb)
+			} else {
+				err = block.LoadPictureBlock(bytes.NewReader(packet[4:]), bb)
+			}
+			if err != nil {
+				return nil, errors.Wrap("could not read flac metadata block", err)
+			}
+			block.ApplyTo(t)
+		}
+
+		if packet[0]&0x80 != 0 {
+			return t, nil
+		}
+	}
+}
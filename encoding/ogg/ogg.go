@@ -0,0 +1,76 @@
+// Package ogg implements Ogg container and codecs stored in it.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package ogg
+
+import "github.com/audioid/audioid/errors"
+
+const (
+	// PageHeaderSize is the size of page header without segment table.
+	PageHeaderSize = 27
+	// MaxPageSize is the maximal size of a page,
+	// including header and segment table.
+	MaxPageSize = PageHeaderSize + 255 + 255*255
+)
+
+// Page header flags
+const (
+	FlagContinued = 0x1
+	FlagFirst     = 0x2
+	FlagLast      = 0x4
+)
+
+// NoGranule is a granule position of a page,
+// on which no packet ends.
+const NoGranule = -1
+
+var (
+	// ErrNoPage is returned when data does not start with "OggS".
+	ErrNoPage = errors.New("no ogg page")
+	// ErrBadCRC is returned when page checksum does not match its contents.
+	ErrBadCRC = errors.New("ogg page crc mismatch")
+	// ErrUnsupportedCodec is returned for Ogg streams of unknown codec.
+	ErrUnsupportedCodec = errors.New("unsupported ogg codec")
+)
+
+// Page is a single Ogg page.
+//
+// ref: https://xiph.org/ogg/doc/framing.html
+type Page struct {
+	Version uint8
+	Flags   uint8
+	// Granule is a codec-specific position of the last packet,
+	// which ends on this page. For audio codecs it's usually a sample number.
+	Granule  int64
+	Serial   uint32
+	Sequence uint32
+	CRC      uint32
+	// Segments is a segment table of lacing values.
+	Segments []byte
+	Data     []byte
+}
+
+// IsContinued reports whether the page starts with a continuation
+// of the packet from the previous page.
+func (p *Page) IsContinued() bool {
+	return p.Flags&FlagContinued != 0
+}
+
+// IsFirst reports whether the page is the first page of a logical stream.
+func (p *Page) IsFirst() bool {
+	return p.Flags&FlagFirst != 0
+}
+
+// IsLast reports whether the page is the last page of a logical stream.
+func (p *Page) IsLast() bool {
+	return p.Flags&FlagLast != 0
+}
+
+// Size returns the size of the page, including header.
+func (p *Page) Size() int {
+	return PageHeaderSize + len(p.Segments) + len(p.Data)
+}
@@ -0,0 +1,32 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+// crcTable is a table for CRC-32 with polynomial 0x04c11db7,
+// which is used by Ogg without bit reflection.
+var crcTable = func() (table [256]uint32) {
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}()
+
+// crcUpdate adds b to the running crc.
+func crcUpdate(crc uint32, b []byte) uint32 {
+	for _, x := range b {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^x]
+	}
+	return crc
+}
@@ -0,0 +1,104 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// Decode reads header packets of the first logical stream in f
+// into *metadata.Track. Duration is calculated from the granule
+// position of the last page. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	pr := NewPacketReader(bufio.NewReader(f))
+	packet, err := pr.NextPacket()
+	if err != nil {
+		return nil, errors.Wrap("could not read ogg identification header", err)
+	}
+
+	var t *metadata.Track
+	preSkip := uint64(0)
+	switch {
+	case isVorbisPacket(packet, vorbisPacketIdentification):
+		t, err = decodeVorbis(pr, packet)
+	case isOpusHead(packet):
+		t, preSkip, err = decodeOpus(pr, packet)
+	case isFLACHeader(packet):
+		t, err = decodeFLAC(pr, packet)
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+	if err != nil {
+		return nil, errors.Wrap("could not decode ogg", err)
+	}
+
+	granule, size, err := findLastGranule(f, pr.Serial())
+	if err != nil {
+		return nil, err
+	}
+	applyGranule(t, granule, preSkip, size)
+	return t, nil
+}
+
+// applyGranule calculates duration from the last granule position,
+// where the first preSkip samples are not played.
+// Bitrate is calculated from the stream size, if header had no hint.
+func applyGranule(t *metadata.Track, granule int64, preSkip uint64, size int64) {
+	if granule <= 0 || uint64(granule) <= preSkip || t.Properties.SampleRate == 0 {
+		t.Duration = -1
+		return
+	}
+
+	t.Properties.TotalSamples = uint64(granule) - preSkip
+	t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, t.Properties.SampleRate)
+	if t.Properties.Bitrate == 0 && t.Duration > 0 {
+		t.Properties.Bitrate = uint32(float64(size) * 8 / t.Duration.Seconds())
+	}
+}
+
+// maxGranuleWindow limits how much of the end of the file is read
+// to find the last page of the stream.
+const maxGranuleWindow = 64 << 20
+
+// findLastGranule returns the granule position of the last page
+// of given logical stream and the size of f.
+// The end of f is read in growing windows until such page is found.
+// NoGranule is returned, if there is no such page in the last
+// maxGranuleWindow bytes, so duration is unknown.
+func findLastGranule(f io.ReadSeeker, serial uint32) (int64, int64, error) {
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, errors.Wrap("could not seek to the end", err)
+	}
+	limit := end
+	if limit > maxGranuleWindow {
+		limit = maxGranuleWindow
+	}
+
+	for window := int64(MaxPageSize); ; window *= 2 {
+		if window > limit {
+			window = limit
+		}
+		if _, err := f.Seek(-window, io.SeekEnd); err != nil {
+			return 0, 0, errors.Wrap("could not seek to the last page", err)
+		}
+		buf := make([]byte, window)
+		if _, err := io.ReadFull(f, buf); err != nil {
+			return 0, 0, errors.Wrap("could not read the last page", err)
+		}
+		if granule, ok := lastGranule(buf, serial); ok {
+			return granule, end, nil
+		}
+		if window == limit {
+			return NoGranule, end, nil
+		}
+	}
+}
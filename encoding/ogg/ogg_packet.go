@@ -0,0 +1,98 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"io"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// PacketReader reads packets of a single logical stream,
+// joining packets, which span several pages.
+// The first page read determines the logical stream,
+// pages of other multiplexed streams are skipped.
+type PacketReader struct {
+	r       io.Reader
+	serial  uint32
+	started bool
+	// packets are complete packets of the current page
+	packets [][]byte
+	// partial is the beginning of a packet, which continues on the next page
+	partial []byte
+	// page is the last read page
+	page *Page
+}
+
+// NewPacketReader returns a PacketReader, reading pages from r.
+func NewPacketReader(r io.Reader) *PacketReader {
+	return &PacketReader{r: r}
+}
+
+// Serial returns the serial number of the logical stream.
+func (pr *PacketReader) Serial() uint32 {
+	return pr.serial
+}
+
+// Page returns the last page read.
+func (pr *PacketReader) Page() *Page {
+	return pr.page
+}
+
+// NextPacket returns the next complete packet.
+// io.EOF is returned after the last packet of the logical stream.
+func (pr *PacketReader) NextPacket() ([]byte, error) {
+	for len(pr.packets) == 0 {
+		if pr.page != nil && pr.page.IsLast() {
+			return nil, io.EOF
+		}
+		if err := pr.readPage(); err != nil {
+			return nil, err
+		}
+	}
+
+	packet := pr.packets[0]
+	pr.packets = pr.packets[1:]
+	return packet, nil
+}
+
+func (pr *PacketReader) readPage() error {
+	for {
+		page, err := ReadPage(pr.r)
+		if err != nil {
+			return err
+		}
+		if !pr.started {
+			pr.serial = page.Serial
+			pr.started = true
+		}
+		if page.Serial != pr.serial {
+			continue
+		}
+
+		if !page.IsContinued() && len(pr.partial) != 0 {
+			return errors.New("ogg packet was not continued on the next page")
+		}
+		pr.page = page
+		pr.splitPackets(page)
+		return nil
+	}
+}
+
+// splitPackets splits page data into packets using lacing values.
+// A packet ends on a lacing value less than 255.
+func (pr *PacketReader) splitPackets(page *Page) {
+	offset := 0
+	for _, lacing := range page.Segments {
+		pr.partial = append(pr.partial, page.Data[offset:offset+int(lacing)]...)
+		offset += int(lacing)
+		if lacing < 255 {
+			pr.packets = append(pr.packets, pr.partial)
+			pr.partial = nil
+		}
+	}
+}
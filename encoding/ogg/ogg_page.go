@@ -0,0 +1,126 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// ReadPage reads a single page from r and verifies its checksum.
+func ReadPage(r io.Reader) (*Page, error) {
+	var header [PageHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, errors.Wrap("could not read ogg page header", err)
+	}
+	if string(header[:4]) != "OggS" {
+		return nil, ErrNoPage
+	}
+
+	segmentsLen := int(header[26])
+	buf := make([]byte, PageHeaderSize+segmentsLen, MaxPageSize)
+	copy(buf, header[:])
+	if _, err := io.ReadFull(r, buf[PageHeaderSize:]); err != nil {
+		return nil, errors.Wrap("could not read ogg segment table", err)
+	}
+
+	dataLen := 0
+	for _, x := range buf[PageHeaderSize:] {
+		dataLen += int(x)
+	}
+	buf = buf[:len(buf)+dataLen]
+	if _, err := io.ReadFull(r, buf[PageHeaderSize+segmentsLen:]); err != nil {
+		return nil, errors.Wrap("could not read ogg page data", err)
+	}
+
+	page, _, err := ParsePage(buf)
+	return page, err
+}
+
+// ParsePage parses a page at the start of b and verifies its checksum.
+// It returns the page and its size.
+// Page data and segment table refer to b.
+func ParsePage(b []byte) (*Page, int, error) {
+	if len(b) < PageHeaderSize || string(b[:4]) != "OggS" {
+		return nil, 0, ErrNoPage
+	}
+	segmentsLen := int(b[26])
+	if len(b) < PageHeaderSize+segmentsLen {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+
+	page := &Page{
+		Version:  b[4],
+		Flags:    b[5],
+		Granule:  int64(binary.LittleEndian.Uint64(b[6:14])),
+		Serial:   binary.LittleEndian.Uint32(b[14:18]),
+		Sequence: binary.LittleEndian.Uint32(b[18:22]),
+		CRC:      binary.LittleEndian.Uint32(b[22:26]),
+		Segments: b[PageHeaderSize : PageHeaderSize+segmentsLen],
+	}
+
+	dataLen := 0
+	for _, x := range page.Segments {
+		dataLen += int(x)
+	}
+	size := PageHeaderSize + segmentsLen + dataLen
+	if len(b) < size {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	page.Data = b[PageHeaderSize+segmentsLen : size]
+
+	if page.checksum() != page.CRC {
+		return nil, 0, ErrBadCRC
+	}
+	return page, size, nil
+}
+
+// checksum calculates page CRC, treating the CRC field as zero.
+func (p *Page) checksum() uint32 {
+	var header [PageHeaderSize]byte
+	p.putHeader(header[:])
+	binary.LittleEndian.PutUint32(header[22:26], 0)
+
+	crc := crcUpdate(0, header[:])
+	crc = crcUpdate(crc, p.Segments)
+	return crcUpdate(crc, p.Data)
+}
+
+func (p *Page) putHeader(b []byte) {
+	copy(b, "OggS")
+	b[4] = p.Version
+	b[5] = p.Flags
+	binary.LittleEndian.PutUint64(b[6:14], uint64(p.Granule))
+	binary.LittleEndian.PutUint32(b[14:18], p.Serial)
+	binary.LittleEndian.PutUint32(b[18:22], p.Sequence)
+	binary.LittleEndian.PutUint32(b[22:26], p.CRC)
+	b[26] = byte(len(p.Segments))
+}
+
+// lastGranule scans b for pages of given serial and returns
+// the granule position of the last one, on which a packet ends.
+// Pages, which could not be parsed, are skipped.
+func lastGranule(b []byte, serial uint32) (int64, bool) {
+	granule, found := int64(0), false
+	for i := 0; i+PageHeaderSize <= len(b); {
+		page, size, err := ParsePage(b[i:])
+		if err != nil {
+			i++
+			continue
+		}
+		if page.Serial == serial && page.Granule != NoGranule {
+			granule, found = page.Granule, true
+		}
+		i += size
+	}
+	return granule, found
+}
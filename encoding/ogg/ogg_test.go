@@ -0,0 +1,350 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/metadata"
+)
+
+// buildPage serializes a page with a single packet,
+// which is split into lacing values. Unless complete is set,
+// the packet continues on the next page.
+func buildPage(flags uint8, granule int64, sequence uint32, packet []byte, complete bool) []byte {
+	page := &Page{Flags: flags, Granule: granule, Serial: 0x1234, Sequence: sequence, Data: packet}
+	n := len(packet)
+	for ; n >= 255; n -= 255 {
+		page.Segments = append(page.Segments, 255)
+	}
+	if complete {
+		page.Segments = append(page.Segments, byte(n))
+	}
+	page.CRC = page.checksum()
+
+	b := make([]byte, PageHeaderSize, page.Size())
+	page.putHeader(b)
+	b = append(b, page.Segments...)
+	return append(b, page.Data...)
+}
+
+func vorbisComment(prefix []byte, comments ...string) []byte {
+	b := append([]byte(nil), prefix...)
+	b = appendString(b, "test vendor")
+	b = appendUint32(b, uint32(len(comments)))
+	for _, comment := range comments {
+		b = appendString(b, comment)
+	}
+	return b
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	var n [4]byte
+	binary.LittleEndian.PutUint32(n[:], x)
+	return append(b, n[:]...)
+}
+
+func TestDecodeVorbis(t *testing.T) {
+	id := make([]byte, 30)
+	copy(id, "\x01vorbis")
+	id[11] = 2
+	binary.LittleEndian.PutUint32(id[12:], 44100)
+	binary.LittleEndian.PutUint32(id[20:], 128000)
+	id[28] = 0xB8
+	id[29] = 1
+
+	// The comment is large enough to span two pages
+	comment := vorbisComment([]byte("\x03vorbis"), "TITLE=Title", "ARTIST=a=b", "DESCRIPTION="+string(make([]byte, 70000)))
+	comment = append(comment, 1)
+
+	var file bytes.Buffer
+	file.Write(buildPage(FlagFirst, 0, 0, id, true))
+	file.Write(buildPage(0, NoGranule, 1, comment[:255*255], false))
+	file.Write(buildPage(FlagContinued, 0, 2, comment[255*255:], true))
+	file.Write(buildPage(FlagLast, 441000, 3, []byte("audio"), true))
+
+	track, err := Decode(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Title" {
+		t.Errorf(`expected Title to be "Title", but got %q`, track.Title)
+	}
+	if track.Artist != "a=b" {
+		t.Errorf(`expected Artist to be "a=b", but got %q`, track.Artist)
+	}
+	if track.Duration != 10*time.Second {
+		t.Errorf("expected duration 10s, but got %s", track.Duration)
+	}
+	if track.Properties.Channels != 2 || track.Properties.SampleRate != 44100 {
+		t.Errorf("expected 2 channels at 44100 Hz, but got %d at %d", track.Properties.Channels, track.Properties.SampleRate)
+	}
+}
+
+func TestBadCRC(t *testing.T) {
+	page := buildPage(FlagFirst, 0, 0, []byte("data"), true)
+	page[len(page)-1] ^= 0xFF
+	if _, err := ReadPage(bytes.NewReader(page)); err != ErrBadCRC {
+		t.Errorf("expected ErrBadCRC, but got %v", err)
+	}
+}
+
+func TestDecodeOpus(t *testing.T) {
+	head := []byte("OpusHead\x01\x02\x38\x01\x44\xac\x00\x00\x00\x01\x00")
+	tags := vorbisComment([]byte("OpusTags"), "title=Memo")
+
+	var file bytes.Buffer
+	file.Write(buildPage(FlagFirst, 0, 0, head, true))
+	file.Write(buildPage(0, 0, 1, tags, true))
+	file.Write(buildPage(0, 48000, 2, []byte("audio"), true))
+	file.Write(buildPage(FlagLast, 96000+312, 3, []byte("audio"), true))
+
+	track, err := Decode(bytes.NewReader(file.Bytes()))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Memo" {
+		t.Errorf(`expected Title to be "Memo", but got %q`, track.Title)
+	}
+	if track.Duration != 2*time.Second {
+		t.Errorf("expected duration 2s, but got %s", track.Duration)
+	}
+	if track.Properties.Codec != "Opus" || track.Properties.Channels != 2 {
+		t.Errorf("expected stereo Opus, but got %d channels of %q", track.Properties.Channels, track.Properties.Codec)
+	}
+
+	opusHead, ok := track.Extra["opus"].(*OpusHead)
+	if !ok {
+		t.Fatalf("expected opus head in extra, but got %v", track.Extra)
+	}
+	if opusHead.PreSkip != 312 {
+		t.Errorf("expected pre-skip 312, but got %d", opusHead.PreSkip)
+	}
+	if opusHead.InputSampleRate != 44100 || opusHead.OutputGain != 1 {
+		t.Errorf("expected 44100 Hz input and 1 dB gain, but got %d Hz and %f dB", opusHead.InputSampleRate, opusHead.OutputGain)
+	}
+}
+
+// zeros is a file of zero bytes, which records
+// the largest distance from the end, it was read from.
+type zeros struct {
+	size, pos, window int64
+}
+
+func (z *zeros) Read(b []byte) (int, error) {
+	if z.pos >= z.size {
+		return 0, io.EOF
+	}
+	n := int64(len(b))
+	if n > z.size-z.pos {
+		n = z.size - z.pos
+	}
+	for i := range b[:n] {
+		b[i] = 0
+	}
+	if z.size-z.pos > z.window {
+		z.window = z.size - z.pos
+	}
+	z.pos += n
+	return int(n), nil
+}
+
+func (z *zeros) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += z.pos
+	case io.SeekEnd:
+		offset += z.size
+	}
+	z.pos = offset
+	return offset, nil
+}
+
+func TestFindLastGranuleLimit(t *testing.T) {
+	f := &zeros{size: 1 << 30}
+	granule, size, err := findLastGranule(f, 0x1234)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if granule != NoGranule || size != f.size {
+		t.Errorf("expected no granule in %d bytes, but got %d in %d bytes", f.size, granule, size)
+	}
+	if f.window != maxGranuleWindow {
+		t.Errorf("expected the last %d bytes to be read, but got %d", maxGranuleWindow, f.window)
+	}
+}
+
+// writeFile writes the file, replaces its comment
+// and returns the packets of the result.
+func writeFile(t *testing.T, file []byte, comment *flac.VorbisComment) [][]byte {
+	dir, err := ioutil.TempDir("", "ogg")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.ogg")
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := WriteFile(path, comment, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	// Pages are checked by the reader, sequence numbers should be contiguous
+	r := bytes.NewReader(b)
+	for sequence := uint32(0); ; sequence++ {
+		page, err := ReadPage(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		if page.Sequence != sequence {
+			t.Fatalf("expected page sequence %d, but got %d", sequence, page.Sequence)
+		}
+	}
+
+	var packets [][]byte
+	pr := NewPacketReader(bytes.NewReader(b))
+	for {
+		packet, err := pr.NextPacket()
+		if err == io.EOF {
+			return packets
+		}
+		if err != nil {
+			t.Fatalf("%+v", err)
+		}
+		packets = append(packets, packet)
+	}
+}
+
+func TestWriteVorbis(t *testing.T) {
+	id := make([]byte, 30)
+	copy(id, "\x01vorbis")
+	id[11] = 2
+	binary.LittleEndian.PutUint32(id[12:], 44100)
+	comment := append(vorbisComment([]byte("\x03vorbis"), "TITLE=Old"), 1)
+	setup := append([]byte("\x05vorbis"), make([]byte, 300)...)
+
+	var file bytes.Buffer
+	file.Write(buildPage(FlagFirst, 0, 0, id, true))
+	file.Write(buildPage(0, 0, 1, comment, true))
+	file.Write(buildPage(0, 0, 2, setup, true))
+	file.Write(buildPage(0, 44100, 3, []byte("audio 1"), true))
+	file.Write(buildPage(FlagLast, 88200, 4, []byte("audio 2"), true))
+
+	// The comment is large enough to span two pages
+	packets := writeFile(t, file.Bytes(), &flac.VorbisComment{Comments: map[string]string{
+		"title":       "New",
+		"description": string(make([]byte, 70000)),
+	}})
+	if len(packets) != 5 {
+		t.Fatalf("expected 5 packets, but got %d", len(packets))
+	}
+	vc, err := ParseVorbisComment(packets[1])
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if vc.Vendor != "test vendor" {
+		t.Errorf(`expected vendor to be kept, but got %q`, vc.Vendor)
+	}
+	if vc.Comments["title"] != "New" || len(vc.Comments["description"]) != 70000 {
+		t.Errorf("expected new comments, but got title %q", vc.Comments["title"])
+	}
+	if !bytes.Equal(packets[2], setup) || string(packets[3]) != "audio 1" || string(packets[4]) != "audio 2" {
+		t.Errorf("expected setup header and audio packets to be kept")
+	}
+}
+
+func TestWriteOpus(t *testing.T) {
+	head := []byte("OpusHead\x01\x02\x38\x01\x44\xac\x00\x00\x00\x01\x00")
+	tags := append(vorbisComment([]byte("OpusTags"), "title=Memo", "artist=Someone"), "\x01binary"...)
+
+	var file bytes.Buffer
+	file.Write(buildPage(FlagFirst, 0, 0, head, true))
+	file.Write(buildPage(0, 0, 1, tags, true))
+	file.Write(buildPage(FlagLast, 48000, 2, []byte("audio"), true))
+
+	packets := writeFile(t, file.Bytes(), flac.NewVorbisComment(&metadata.Track{Title: "Note"}))
+	if len(packets) != 3 {
+		t.Fatalf("expected 3 packets, but got %d", len(packets))
+	}
+	vc, err := ParseOpusTags(packets[1])
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if len(vc.Comments) != 1 || vc.Comments["title"] != "Note" {
+		t.Errorf("expected only new title, but got %v", vc.Comments)
+	}
+	if !bytes.HasSuffix(packets[1], []byte("\x01binary")) {
+		t.Errorf("expected binary data after comments to be kept")
+	}
+}
+
+func TestWriteFLAC(t *testing.T) {
+	streamInfo := append([]byte("\x7fFLAC\x01\x00\x00\x02fLaC\x00\x00\x00\x22"), make([]byte, 34)...)
+	comment := vorbisComment([]byte("\x04\x00\x00\x00"), "TITLE=Old")
+	comment[3] = byte(len(comment) - 4)
+	picture := append([]byte("\x86\x00\x00\x04"), "data"...)
+
+	var file bytes.Buffer
+	file.Write(buildPage(FlagFirst, 0, 0, streamInfo, true))
+	file.Write(buildPage(0, 0, 1, comment, true))
+	file.Write(buildPage(0, 0, 2, picture, true))
+	file.Write(buildPage(FlagLast, 4096, 3, []byte("frame"), true))
+
+	packets := writeFile(t, file.Bytes(), &flac.VorbisComment{Comments: map[string]string{"title": "New"}})
+	if len(packets) != 4 {
+		t.Fatalf("expected 4 packets, but got %d", len(packets))
+	}
+	b := packets[1]
+	if b[0] != 0x04 || int(b[1])<<16|int(b[2])<<8|int(b[3]) != len(b)-4 {
+		t.Errorf("expected vorbis comment block header, but got %x", b[:4])
+	}
+	vc, err := parseComment(b[4:])
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if vc.Comments["title"] != "New" {
+		t.Errorf(`expected title to be "New", but got %q`, vc.Comments["title"])
+	}
+	if !bytes.Equal(packets[2], picture) || string(packets[3]) != "frame" {
+		t.Errorf("expected picture block and audio to be kept")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	pages := paginate([][]byte{make([]byte, 255*255), []byte("setup")}, 1, 1)
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 pages, but got %d", len(pages))
+	}
+	// The first packet fills the page and ends on the next one with zero lacing value
+	if pages[0].Granule != NoGranule || pages[1].Flags != FlagContinued || pages[1].Granule != 0 {
+		t.Errorf("expected continued packet, but got %+v", pages[1])
+	}
+	if !bytes.Equal(pages[1].Segments, []byte{0, 5}) || pages[1].Sequence != 2 {
+		t.Errorf("expected segments [0 5], but got %v", pages[1].Segments)
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/utils"
+)
+
+// WriteFile replaces the comment header of the first logical stream
+// of Ogg Vorbis, Opus or Ogg FLAC file. The vendor of the file is kept,
+// if comment has no vendor. Header packets after the identification
+// header are repaginated, and the following pages of the stream
+// are renumbered with recomputed checksums, so audio packets are
+// kept byte by byte. Pages of other logical streams are copied as is.
+//
+// The file is rewritten with utils.ReplaceFile,
+// so the original stays intact on failure. opts may be nil.
+func WriteFile(path string, comment *flac.VorbisComment, opts *utils.WriteOptions) error {
+	if err := utils.RecoverFile(path); err != nil {
+		return err
+	}
+	return utils.ReplaceFile(path, opts, func(w io.Writer, original *os.File) error {
+		bw := bufio.NewWriter(w)
+		if err := rewriteStream(bw, bufio.NewReader(original), comment); err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return errors.Wrap("could not write ogg pages", err)
+		}
+		return nil
+	})
+}
+
+// rewriteStream copies pages from r to w, replacing the comment
+// header of the first logical stream.
+func rewriteStream(w io.Writer, r io.Reader, comment *flac.VorbisComment) error {
+	first, err := ReadPage(r)
+	if err != nil {
+		return errors.Wrap("could not read ogg identification header", err)
+	}
+	// Header packets are collected with the reader of a single stream,
+	// while pages of other streams are delayed after the new headers
+	pr := &PacketReader{serial: first.Serial, started: true}
+	pr.splitPackets(first)
+	if !first.IsFirst() || len(pr.packets) != 1 || len(pr.partial) != 0 {
+		return errors.New("ogg identification header does not fill the first page")
+	}
+	if err := writePage(w, first); err != nil {
+		return err
+	}
+	isComplete, err := headersComplete(pr.packets[0])
+	if err != nil {
+		return err
+	}
+	var others []*Page
+	var last *Page
+	oldPages := uint32(1)
+	for !isComplete(pr.packets) {
+		page, err := ReadPage(r)
+		if err != nil {
+			return errors.Wrap("could not read ogg header page", err)
+		}
+		if page.Serial != first.Serial {
+			others = append(others, page)
+			continue
+		}
+		if !page.IsContinued() && len(pr.partial) != 0 {
+			return errors.New("ogg packet was not continued on the next page")
+		}
+		pr.splitPackets(page)
+		last = page
+		oldPages++
+	}
+	// Audio packets start on a fresh page
+	if len(pr.partial) != 0 || isComplete(pr.packets[:len(pr.packets)-1]) {
+		return errors.New("ogg header packets do not end on a page boundary")
+	}
+
+	headers, err := replaceComment(pr.packets[1:], comment)
+	if err != nil {
+		return err
+	}
+	pages := paginate(headers, first.Serial, first.Sequence+1)
+	if len(pages) != 0 && last.IsLast() {
+		pages[len(pages)-1].Flags |= FlagLast
+	}
+	for _, page := range append(pages, others...) {
+		if err := writePage(w, page); err != nil {
+			return err
+		}
+	}
+
+	delta := uint32(len(pages)+1) - oldPages
+	for {
+		page, err := ReadPage(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap("could not read ogg page", err)
+		}
+		if page.Serial == first.Serial {
+			page.Sequence += delta
+		}
+		if err := writePage(w, page); err != nil {
+			return err
+		}
+	}
+}
+
+// headersComplete returns a function, which reports whether
+// all header packets of the stream are read, by its first packet.
+func headersComplete(packet []byte) (func(packets [][]byte) bool, error) {
+	switch {
+	case isVorbisPacket(packet, vorbisPacketIdentification):
+		return func(packets [][]byte) bool {
+			return len(packets) >= 3
+		}, nil
+	case isOpusHead(packet):
+		return func(packets [][]byte) bool {
+			return len(packets) >= 2
+		}, nil
+	case isFLACHeader(packet):
+		// Each header packet is a metadata block, the last one is flagged
+		return func(packets [][]byte) bool {
+			n := len(packets)
+			return n >= 2 && len(packets[n-1]) != 0 && packets[n-1][0]&0x80 != 0
+		}, nil
+	}
+	return nil, ErrUnsupportedCodec
+}
+
+// replaceComment returns header packets after the identification header
+// with the comment header replaced. The comment header is always
+// the first of them.
+func replaceComment(packets [][]byte, comment *flac.VorbisComment) ([][]byte, error) {
+	packet := packets[0]
+	var prefix, suffix []byte
+	var old *flac.VorbisComment
+	var err error
+	isBlock := false
+	switch {
+	case isVorbisPacket(packet, vorbisPacketComment):
+		old, err = ParseVorbisComment(packet)
+		prefix = packet[:7:7]
+		// Framing bit
+		suffix = []byte{1}
+	case bytes.HasPrefix(packet, opusTagsMagic):
+		old, err = ParseOpusTags(packet)
+		prefix = opusTagsMagic
+		// Binary data after the comments is kept
+		if n, ok := commentSize(packet[len(opusTagsMagic):]); ok {
+			suffix = packet[len(opusTagsMagic)+n:]
+		}
+	case len(packet) >= 4 && flac.BlockType(packet[0]&0x7F) == flac.BlockTypeVorbisComment:
+		old, err = parseComment(packet[4:])
+		prefix = packet[:4]
+		isBlock = true
+	default:
+		return nil, errors.New("ogg comment header is missing")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	vc := *comment
+	if vc.Vendor == "" {
+		vc.Vendor = old.Vendor
+	}
+	b := append(append(append([]byte(nil), prefix...), vc.Encode()...), suffix...)
+	if isBlock {
+		size := len(b) - 4
+		if size > maxFLACBlockSize {
+			return nil, errors.New("flac vorbis comment is too large")
+		}
+		b[1], b[2], b[3] = byte(size>>16), byte(size>>8), byte(size)
+	}
+
+	return append([][]byte{b}, packets[1:]...), nil
+}
+
+// commentSize returns the size of Vorbis comment structure at the start of b.
+func commentSize(b []byte) (int, bool) {
+	offset := 0
+	next := func() (int, bool) {
+		if len(b)-offset < 4 {
+			return 0, false
+		}
+		n := binary.LittleEndian.Uint32(b[offset:])
+		offset += 4
+		return int(n), true
+	}
+	skip := func() bool {
+		n, ok := next()
+		if !ok || n > len(b)-offset {
+			return false
+		}
+		offset += n
+		return true
+	}
+
+	if !skip() {
+		return 0, false
+	}
+	count, ok := next()
+	if !ok {
+		return 0, false
+	}
+	for i := 0; i < count; i++ {
+		if !skip() {
+			return 0, false
+		}
+	}
+	return offset, true
+}
+
+// paginate packs header packets into pages starting with given sequence.
+// Pages, on which a packet ends, have zero granule position.
+func paginate(packets [][]byte, serial uint32, sequence uint32) []*Page {
+	var pages []*Page
+	page := &Page{Serial: serial, Sequence: sequence, Granule: NoGranule}
+	flush := func(continued bool) {
+		pages = append(pages, page)
+		page = &Page{Serial: serial, Sequence: page.Sequence + 1, Granule: NoGranule}
+		if continued {
+			page.Flags = FlagContinued
+		}
+	}
+
+	for _, packet := range packets {
+		for n := len(packet); ; n -= 255 {
+			if len(page.Segments) == 255 {
+				flush(true)
+			}
+			if n < 255 {
+				page.Segments = append(page.Segments, byte(n))
+				page.Data = append(page.Data, packet[len(packet)-n:]...)
+				page.Granule = 0
+				break
+			}
+			page.Segments = append(page.Segments, 255)
+			page.Data = append(page.Data, packet[len(packet)-n:len(packet)-n+255]...)
+		}
+		// A packet ending the page does not continue on the next one
+		if len(page.Segments) == 255 {
+			flush(false)
+		}
+	}
+	if len(page.Segments) != 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// writePage serializes the page with recomputed checksum.
+func writePage(w io.Writer, page *Page) error {
+	page.CRC = page.checksum()
+	b := make([]byte, PageHeaderSize, page.Size())
+	page.putHeader(b)
+	b = append(b, page.Segments...)
+	if _, err := w.Write(append(b, page.Data...)); err != nil {
+		return errors.Wrap("could not write ogg page", err)
+	}
+	return nil
+}
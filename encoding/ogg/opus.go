@@ -0,0 +1,125 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// OpusSampleRate is the rate of Opus granule positions.
+// Opus is always decoded at 48 kHz, regardless of the input sample rate.
+const OpusSampleRate = 48000
+
+var (
+	opusHeadMagic = []byte("OpusHead")
+	opusTagsMagic = []byte("OpusTags")
+)
+
+// OpusHead is the identification header of Opus stream.
+//
+// ref: https://tools.ietf.org/html/rfc7845#section-5.1
+type OpusHead struct {
+	Version  uint8
+	Channels uint8
+	// PreSkip is the number of samples at 48 kHz
+	// to discard from the decoder output when starting playback.
+	PreSkip uint16
+	// InputSampleRate is the sample rate of the original input.
+	// It's informational only, zero implies unspecified.
+	InputSampleRate uint32
+	// OutputGain in dB to apply when decoding.
+	OutputGain float64
+	// MappingFamily describes the order and semantic meaning of channels.
+	MappingFamily uint8
+	// StreamCount, CoupledCount and Mapping are only present
+	// for mapping families other than 0.
+	StreamCount  uint8
+	CoupledCount uint8
+	Mapping      []byte
+}
+
+func isOpusHead(packet []byte) bool {
+	return bytes.HasPrefix(packet, opusHeadMagic)
+}
+
+// ParseOpusHead parses the identification header packet.
+func ParseOpusHead(packet []byte) (*OpusHead, error) {
+	if !isOpusHead(packet) || len(packet) < 19 {
+		return nil, errors.New("invalid opus identification header")
+	}
+	b := packet[8:]
+	head := &OpusHead{
+		Version:         b[0],
+		Channels:        b[1],
+		PreSkip:         binary.LittleEndian.Uint16(b[2:4]),
+		InputSampleRate: binary.LittleEndian.Uint32(b[4:8]),
+		// Q7.8 fixed-point number
+		OutputGain:    float64(int16(binary.LittleEndian.Uint16(b[8:10]))) / 256,
+		MappingFamily: b[10],
+	}
+	// Major version is stored in upper 4 bits, only version 0 is defined
+	if head.Version>>4 != 0 || head.Channels == 0 {
+		return nil, errors.New("unsupported opus version")
+	}
+
+	if head.MappingFamily != 0 {
+		b = b[11:]
+		if len(b) < 2+int(head.Channels) {
+			return nil, errors.New("invalid opus channel mapping table")
+		}
+		head.StreamCount = b[0]
+		head.CoupledCount = b[1]
+		head.Mapping = append([]byte(nil), b[2:2+int(head.Channels)]...)
+	}
+	return head, nil
+}
+
+// ParseOpusTags parses the comment header packet.
+// Unlike Vorbis, OpusTags has no framing bit,
+// and may have binary data after the comments, which is ignored.
+func ParseOpusTags(packet []byte) (*flac.VorbisComment, error) {
+	if !bytes.HasPrefix(packet, opusTagsMagic) {
+		return nil, errors.New("invalid opus comment header")
+	}
+	return parseComment(packet[len(opusTagsMagic):])
+}
+
+// Apply stream properties to the track.
+// The head itself is kept as "opus" extra,
+// because pre-skip, input sample rate and gain have no track fields.
+func (head *OpusHead) Apply(t *metadata.Track) {
+	t.Properties.Codec = "Opus"
+	t.Properties.SampleRate = OpusSampleRate
+	t.Properties.Channels = head.Channels
+	t.SetExtra("opus", head)
+}
+
+func decodeOpus(pr *PacketReader, headPacket []byte) (*metadata.Track, uint64, error) {
+	head, err := ParseOpusHead(headPacket)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	packet, err := pr.NextPacket()
+	if err != nil {
+		return nil, 0, errors.Wrap("could not read opus comment header", err)
+	}
+	comment, err := ParseOpusTags(packet)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	t := &metadata.Track{}
+	head.Apply(t)
+	comment.Apply(t)
+	return t, uint64(head.PreSkip), nil
+}
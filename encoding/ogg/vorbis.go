@@ -0,0 +1,117 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/valyala/bytebufferpool"
+)
+
+// Vorbis header packet types
+const (
+	vorbisPacketIdentification = 1
+	vorbisPacketComment        = 3
+	vorbisPacketSetup          = 5
+)
+
+var vorbisMagic = []byte("vorbis")
+
+// VorbisIdentification is the first header packet of Vorbis stream.
+//
+// ref: https://xiph.org/vorbis/doc/Vorbis_I_spec.html#x1-630004.2.2
+type VorbisIdentification struct {
+	Version    uint32
+	Channels   uint8
+	SampleRate uint32
+	// Bitrates in bits per second. Zero or negative values imply unset hints.
+	BitrateMaximum int32
+	BitrateNominal int32
+	BitrateMinimum int32
+	BlockSize0     uint16
+	BlockSize1     uint16
+}
+
+func isVorbisPacket(packet []byte, packetType byte) bool {
+	return len(packet) > 7 && packet[0] == packetType && bytes.Equal(packet[1:7], vorbisMagic)
+}
+
+// ParseVorbisIdentification parses the identification header packet.
+func ParseVorbisIdentification(packet []byte) (*VorbisIdentification, error) {
+	if !isVorbisPacket(packet, vorbisPacketIdentification) || len(packet) < 30 {
+		return nil, errors.New("invalid vorbis identification header")
+	}
+	b := packet[7:]
+	id := &VorbisIdentification{
+		Version:        binary.LittleEndian.Uint32(b[0:4]),
+		Channels:       b[4],
+		SampleRate:     binary.LittleEndian.Uint32(b[5:9]),
+		BitrateMaximum: int32(binary.LittleEndian.Uint32(b[9:13])),
+		BitrateNominal: int32(binary.LittleEndian.Uint32(b[13:17])),
+		BitrateMinimum: int32(binary.LittleEndian.Uint32(b[17:21])),
+		BlockSize0:     1 << (b[21] & 0xF),
+		BlockSize1:     1 << (b[21] >> 4),
+	}
+	if id.Version != 0 || id.Channels == 0 || id.SampleRate == 0 {
+		return nil, errors.New("invalid vorbis identification header")
+	}
+	return id, nil
+}
+
+// ParseVorbisComment parses the comment header packet.
+func ParseVorbisComment(packet []byte) (*flac.VorbisComment, error) {
+	if !isVorbisPacket(packet, vorbisPacketComment) {
+		return nil, errors.New("invalid vorbis comment header")
+	}
+	return parseComment(packet[7:])
+}
+
+func parseComment(b []byte) (*flac.VorbisComment, error) {
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+
+	comment, err := flac.ReadVorbisComment(bytes.NewReader(b), bb)
+	if err != nil {
+		return nil, errors.Wrap("could not read vorbis comment", err)
+	}
+	return comment, nil
+}
+
+// Apply stream properties to the track.
+func (id *VorbisIdentification) Apply(t *metadata.Track) {
+	t.Properties.Codec = "Vorbis"
+	t.Properties.SampleRate = id.SampleRate
+	t.Properties.Channels = id.Channels
+	if id.BitrateNominal > 0 {
+		t.Properties.Bitrate = uint32(id.BitrateNominal)
+	}
+}
+
+func decodeVorbis(pr *PacketReader, idPacket []byte) (*metadata.Track, error) {
+	id, err := ParseVorbisIdentification(idPacket)
+	if err != nil {
+		return nil, err
+	}
+
+	packet, err := pr.NextPacket()
+	if err != nil {
+		return nil, errors.Wrap("could not read vorbis comment header", err)
+	}
+	comment, err := ParseVorbisComment(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &metadata.Track{}
+	id.Apply(t)
+	comment.Apply(t)
+	return t, nil
+}
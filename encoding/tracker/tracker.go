@@ -0,0 +1,66 @@
+// Package tracker implements tracker module formats: ProTracker (MOD),
+// Scream Tracker 3 (S3M), FastTracker 2 (XM) and Impulse Tracker (IT).
+// Modules hold patterns and samples instead of rendered audio,
+// so only their song structure is read.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package tracker
+
+import (
+	"github.com/audioid/audioid/errors"
+)
+
+// Format of the module.
+type Format uint8
+
+const (
+	FormatMOD Format = 1
+	FormatS3M Format = 2
+	FormatXM  Format = 3
+	FormatIT  Format = 4
+)
+
+func (format Format) String() string {
+	switch format {
+	case FormatMOD:
+		return "MOD"
+	case FormatS3M:
+		return "S3M"
+	case FormatXM:
+		return "XM"
+	case FormatIT:
+		return "IT"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrNotModule is returned when file is not a supported tracker module.
+	ErrNotModule = errors.New("not a tracker module")
+)
+
+// Module describes the song structure of a tracker module.
+type Module struct {
+	Format Format
+	Title  string
+	// Tracker is the name and version of the tracker, which saved the module,
+	// as far as it can be told from the header.
+	Tracker string
+	// Channels is the number of pattern channels, not output channels.
+	Channels uint16
+	Patterns uint16
+	// Orders is the song length in entries of the pattern order list.
+	Orders uint16
+	// Instruments names in the order of instrument numbers.
+	// MOD and S3M modules have samples only.
+	Instruments []string
+	// Samples names in the order of sample numbers.
+	// Trackers often use them to store text, e.g. greetings.
+	Samples []string
+	// Message is the song message of IT module.
+	Message string
+}
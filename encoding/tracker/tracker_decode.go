@@ -0,0 +1,122 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tracker
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// detectionSize covers MOD signature, which is the farthest from the start.
+const detectionSize = modSignatureOffset + 4
+
+// Decode reads tracker module into *metadata.Track.
+// f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	m, err := ReadModule(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode tracker module", err)
+	}
+
+	t := &metadata.Track{}
+	m.Apply(t)
+	return t, nil
+}
+
+// Detect returns the format of the module, or zero if f is not a module.
+// Signatures of MOD and S3M are not at the start of file,
+// so f is read from its start and rewound afterwards.
+func Detect(f io.ReadSeeker) (Format, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, errors.Wrap("could not seek to the start", err)
+	}
+	b := make([]byte, detectionSize)
+	n, err := io.ReadFull(f, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return 0, errors.Wrap("could not read header", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, errors.Wrap("could not seek back", err)
+	}
+	return detect(b[:n]), nil
+}
+
+func detect(b []byte) Format {
+	switch {
+	case bytes.HasPrefix(b, []byte(xmMagic)):
+		return FormatXM
+	case bytes.HasPrefix(b, []byte(itMagic)):
+		return FormatIT
+	case len(b) >= s3mHeaderSize && string(b[44:48]) == s3mMagic && b[29] == s3mType:
+		return FormatS3M
+	case len(b) >= detectionSize && modChannels(string(b[modSignatureOffset:])) != 0:
+		return FormatMOD
+	}
+	return 0
+}
+
+// ReadModule reads the header of tracker module with names
+// of its instruments and samples.
+// f must be positioned at the start of the file.
+func ReadModule(f io.ReadSeeker) (*Module, error) {
+	format, err := Detect(f)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatMOD:
+		return readMOD(f)
+	case FormatS3M:
+		return readS3M(f)
+	case FormatXM:
+		return readXM(f)
+	case FormatIT:
+		return readIT(f)
+	}
+	return nil, ErrNotModule
+}
+
+// Apply module title and song message to the track.
+// Duration of a module depends on playback of its patterns, so it is unknown.
+// Module is stored in Track.Extra under "module".
+func (m *Module) Apply(t *metadata.Track) {
+	if m.Title != "" {
+		t.Title = m.Title
+	}
+	if m.Message != "" {
+		if t.Comments == nil {
+			t.Comments = map[string]string{}
+		}
+		t.Comments["comment"] = m.Message
+	}
+	t.Properties = metadata.Properties{Codec: m.Format.String()}
+	t.Duration = -1
+	t.SetExtra("module", m)
+}
+
+// readAt reads size bytes at offset of f.
+func readAt(f io.ReadSeeker, offset int64, size int) ([]byte, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek", err)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read", err)
+	}
+	return b, nil
+}
+
+// text decodes fixed-size name field. Trackers of the DOS era
+// wrote code page 437, but its printable ASCII subset dominates,
+// so Latin-1 is used as for other legacy formats.
+func text(b []byte) string {
+	return utils.TrimFixed(utils.DecodeLatin1(b))
+}
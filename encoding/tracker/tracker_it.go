@@ -0,0 +1,100 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tracker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/audioid/audioid/utils"
+)
+
+// Layout of IT header.
+//
+// ref: https://github.com/schismtracker/schismtracker/wiki/ITTECH.TXT
+const (
+	itMagic      = "IMPM"
+	itHeaderSize = 192
+	// itInstrumentNameOffset and itSampleNameOffset are the offsets
+	// of 26-byte names in instrument and sample headers.
+	itInstrumentNameOffset = 0x20
+	itSampleNameOffset     = 0x14
+	// itSpecialMessage flag tells that the song message is present.
+	itSpecialMessage = 0x1
+)
+
+func readIT(f io.ReadSeeker) (*Module, error) {
+	b, err := readAt(f, 0, itHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := binary.LittleEndian.Uint16(b[0x20:])
+	instruments := binary.LittleEndian.Uint16(b[0x22:])
+	samples := binary.LittleEndian.Uint16(b[0x24:])
+	m := &Module{
+		Format:   FormatIT,
+		Title:    text(b[4:30]),
+		Tracker:  itTracker(binary.LittleEndian.Uint16(b[0x28:])),
+		Patterns: binary.LittleEndian.Uint16(b[0x26:]),
+		Orders:   orders,
+	}
+	// Channel pannings with the high bit set are disabled
+	for _, pan := range b[0x40:0x80] {
+		if pan&0x80 == 0 {
+			m.Channels++
+		}
+	}
+
+	// Offsets of instrument and sample headers follow the order list
+	pointers, err := readAt(f, itHeaderSize+int64(orders), (int(instruments)+int(samples))*4)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < int(instruments)+int(samples); i++ {
+		offset := int64(binary.LittleEndian.Uint32(pointers[i*4:]))
+		if i < int(instruments) {
+			name, err := readAt(f, offset+itInstrumentNameOffset, 26)
+			if err != nil {
+				return nil, err
+			}
+			m.Instruments = append(m.Instruments, text(name))
+		} else {
+			name, err := readAt(f, offset+itSampleNameOffset, 26)
+			if err != nil {
+				return nil, err
+			}
+			m.Samples = append(m.Samples, text(name))
+		}
+	}
+
+	if binary.LittleEndian.Uint16(b[0x2E:])&itSpecialMessage != 0 {
+		size := binary.LittleEndian.Uint16(b[0x36:])
+		message, err := readAt(f, int64(binary.LittleEndian.Uint32(b[0x38:])), int(size))
+		if err != nil {
+			return nil, err
+		}
+		// Lines of the message are separated by CR
+		m.Message = strings.Replace(utils.TrimFixed(utils.DecodeLatin1(message)), "\r", "\n", -1)
+	}
+	return m, nil
+}
+
+// itTracker decodes "created with tracker / version" field.
+func itTracker(cwtv uint16) string {
+	switch cwtv >> 12 {
+	case 0:
+		return fmt.Sprintf("Impulse Tracker %X.%02X", cwtv>>8, cwtv&0xFF)
+	case 1:
+		return "Schism Tracker"
+	case 5:
+		return "OpenMPT"
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tracker
+
+import (
+	"io"
+)
+
+// Layout of 31-sample MOD header. Older 15-sample modules
+// have no signature, so they are not detected.
+//
+// ref: https://www.aes.id.au/modformat.html
+const (
+	modSamples         = 31
+	modSampleSize      = 30
+	modOrdersOffset    = 950
+	modSignatureOffset = 1080
+)
+
+func readMOD(f io.ReadSeeker) (*Module, error) {
+	b, err := readAt(f, 0, detectionSize)
+	if err != nil {
+		return nil, err
+	}
+
+	signature := string(b[modSignatureOffset:])
+	m := &Module{
+		Format:   FormatMOD,
+		Title:    text(b[:20]),
+		Tracker:  modTracker(signature),
+		Channels: modChannels(signature),
+		Orders:   uint16(b[modOrdersOffset]),
+	}
+	for i := 0; i < modSamples; i++ {
+		m.Samples = append(m.Samples, text(b[20+i*modSampleSize:][:22]))
+	}
+	// Patterns are stored up to the highest number in the whole order list,
+	// even past the song length.
+	for _, pattern := range b[modOrdersOffset+2 : modSignatureOffset] {
+		if uint16(pattern) >= m.Patterns {
+			m.Patterns = uint16(pattern) + 1
+		}
+	}
+	return m, nil
+}
+
+// modChannels returns the number of channels given by MOD signature,
+// or zero if the signature is unknown.
+func modChannels(signature string) uint16 {
+	switch signature {
+	case "M.K.", "M!K!", "FLT4", "4CHN":
+		return 4
+	case "FLT8", "CD81", "OKTA", "OCTA":
+		return 8
+	}
+	if isDigit(signature[0]) && signature[1:] == "CHN" {
+		return uint16(signature[0] - '0')
+	}
+	if isDigit(signature[0]) && isDigit(signature[1]) && (signature[2:] == "CH" || signature[2:] == "CN") {
+		return uint16(signature[0]-'0')*10 + uint16(signature[1]-'0')
+	}
+	return 0
+}
+
+// modTracker guesses the tracker by MOD signature.
+func modTracker(signature string) string {
+	switch {
+	case signature == "M.K." || signature == "M!K!":
+		return "ProTracker"
+	case signature == "FLT4" || signature == "FLT8":
+		return "StarTrekker"
+	case signature == "OKTA" || signature == "OCTA":
+		return "Oktalyzer"
+	case signature[1:] == "CHN" || signature[2:] == "CH":
+		return "FastTracker"
+	case signature[2:] == "CN":
+		return "TakeTracker"
+	}
+	return ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,81 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tracker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Layout of S3M header.
+//
+// ref: https://wiki.multimedia.cx/index.php/Scream_Tracker_3_Module
+const (
+	s3mHeaderSize = 96
+	s3mMagic      = "SCRM"
+	s3mType       = 16
+	// s3mSampleNameOffset is the offset of the name in sample header.
+	s3mSampleNameOffset = 48
+)
+
+func readS3M(f io.ReadSeeker) (*Module, error) {
+	b, err := readAt(f, 0, s3mHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := binary.LittleEndian.Uint16(b[32:])
+	samples := binary.LittleEndian.Uint16(b[34:])
+	m := &Module{
+		Format:   FormatS3M,
+		Title:    text(b[:28]),
+		Tracker:  s3mTracker(binary.LittleEndian.Uint16(b[40:])),
+		Patterns: binary.LittleEndian.Uint16(b[36:]),
+		Orders:   orders,
+	}
+	// Channel settings with the high bit set are disabled
+	for _, setting := range b[64:96] {
+		if setting&0x80 == 0 {
+			m.Channels++
+		}
+	}
+
+	// Sample headers are addressed by paragraphs of 16 bytes,
+	// their pointers follow the order list
+	pointers, err := readAt(f, s3mHeaderSize+int64(orders), int(samples)*2)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < int(samples); i++ {
+		offset := int64(binary.LittleEndian.Uint16(pointers[i*2:])) * 16
+		name, err := readAt(f, offset+s3mSampleNameOffset, 28)
+		if err != nil {
+			return nil, err
+		}
+		m.Samples = append(m.Samples, text(name))
+	}
+	return m, nil
+}
+
+// s3mTracker decodes "created with tracker / version" field.
+func s3mTracker(cwtv uint16) string {
+	version := fmt.Sprintf("%X.%02X", cwtv>>8&0xF, cwtv&0xFF)
+	switch cwtv >> 12 {
+	case 1:
+		return "Scream Tracker " + version
+	case 2:
+		return "Imago Orpheus " + version
+	case 3:
+		return "Impulse Tracker " + version
+	case 4:
+		return "Schism Tracker"
+	case 5:
+		return "OpenMPT"
+	}
+	return ""
+}
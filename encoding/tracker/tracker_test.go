@@ -0,0 +1,220 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tracker
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeMOD(t *testing.T) {
+	b := make([]byte, detectionSize+1024)
+	copy(b, "Space Debris")
+	copy(b[20:], "first sample")
+	copy(b[20+30*modSampleSize:], "last sample")
+	b[modOrdersOffset] = 3
+	copy(b[modOrdersOffset+2:], []byte{0, 2, 1})
+	copy(b[modSignatureOffset:], "M.K.")
+
+	track, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Space Debris" {
+		t.Errorf(`expected Title to be "Space Debris", but got %q`, track.Title)
+	}
+	if track.Properties.Codec != "MOD" || track.Duration != -1 {
+		t.Errorf("expected MOD of unknown duration, but got %+v, %s", track.Properties, track.Duration)
+	}
+	m, ok := track.Extra["module"].(*Module)
+	if !ok {
+		t.Fatalf("expected module in Extra, but got %+v", track.Extra)
+	}
+	if m.Tracker != "ProTracker" || m.Channels != 4 || m.Patterns != 3 || m.Orders != 3 {
+		t.Errorf("expected 4-channel ProTracker module with 3 patterns, but got %+v", m)
+	}
+	if len(m.Samples) != 31 || m.Samples[0] != "first sample" || m.Samples[30] != "last sample" {
+		t.Errorf("expected 31 sample names, but got %q", m.Samples)
+	}
+
+	if channels := modChannels("12CH"); channels != 12 {
+		t.Errorf("expected 12 channels, but got %d", channels)
+	}
+}
+
+func TestDecodeS3M(t *testing.T) {
+	b := make([]byte, 256)
+	copy(b, "Second Reality")
+	b[28] = 0x1A
+	b[29] = s3mType
+	binary.LittleEndian.PutUint16(b[32:], 4)
+	binary.LittleEndian.PutUint16(b[34:], 2)
+	binary.LittleEndian.PutUint16(b[36:], 3)
+	binary.LittleEndian.PutUint16(b[40:], 0x1320)
+	copy(b[44:], s3mMagic)
+	for i := range b[64:96] {
+		b[64+i] = 0xFF
+	}
+	copy(b[64:], []byte{0, 8, 1, 9, 0x80 | 2})
+	// Sample pointers after 4 orders, samples at 0x80 and 0xC0
+	binary.LittleEndian.PutUint16(b[100:], 0x08)
+	binary.LittleEndian.PutUint16(b[102:], 0x0C)
+	b = append(b, make([]byte, 0x100)...)
+	copy(b[0x80+s3mSampleNameOffset:], "Bass")
+	copy(b[0xC0+s3mSampleNameOffset:], "Snare")
+
+	m, err := ReadModule(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := &Module{
+		Format:   FormatS3M,
+		Title:    "Second Reality",
+		Tracker:  "Scream Tracker 3.20",
+		Channels: 4,
+		Patterns: 3,
+		Orders:   4,
+		Samples:  []string{"Bass", "Snare"},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, m)
+	}
+}
+
+func xmInstrument(name string, samples ...string) []byte {
+	h := make([]byte, 263)
+	binary.LittleEndian.PutUint32(h, uint32(len(h)))
+	copy(h[4:], name)
+	binary.LittleEndian.PutUint16(h[27:], uint16(len(samples)))
+	binary.LittleEndian.PutUint32(h[29:], 40)
+	if len(samples) == 0 {
+		// Instruments without samples are stored without the rest of header
+		binary.LittleEndian.PutUint32(h, xmInstrumentSize)
+		return h[:xmInstrumentSize]
+	}
+	for _, sample := range samples {
+		s := make([]byte, 40)
+		binary.LittleEndian.PutUint32(s, 16)
+		copy(s[18:], sample)
+		h = append(h, s...)
+	}
+	return append(h, make([]byte, 16*len(samples))...)
+}
+
+func TestDecodeXM(t *testing.T) {
+	b := make([]byte, 336)
+	copy(b, xmMagic)
+	copy(b[17:], "Unreal II")
+	b[37] = 0x1A
+	copy(b[38:], "FastTracker v2.00")
+	binary.LittleEndian.PutUint16(b[58:], 0x0104)
+	binary.LittleEndian.PutUint32(b[60:], 276)
+	binary.LittleEndian.PutUint16(b[64:], 5)
+	binary.LittleEndian.PutUint16(b[68:], 8)
+	binary.LittleEndian.PutUint16(b[70:], 2)
+	binary.LittleEndian.PutUint16(b[72:], 3)
+
+	// Patterns with packed data of 0 and 5 bytes
+	pattern := []byte{9, 0, 0, 0, 0, 64, 0, 0, 0}
+	b = append(b, pattern...)
+	pattern[7] = 5
+	b = append(b, pattern...)
+	b = append(b, 1, 2, 3, 4, 5)
+
+	b = bytes.Join([][]byte{
+		b,
+		xmInstrument("Piano", "Piano C4", "Piano C5"),
+		xmInstrument("Empty"),
+		xmInstrument("Drums", "Kick"),
+	}, nil)
+
+	m, err := ReadModule(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	expected := &Module{
+		Format:      FormatXM,
+		Title:       "Unreal II",
+		Tracker:     "FastTracker v2.00 (XM 1.04)",
+		Channels:    8,
+		Patterns:    2,
+		Orders:      5,
+		Instruments: []string{"Piano", "Empty", "Drums"},
+		Samples:     []string{"Piano C4", "Piano C5", "Kick"},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, m)
+	}
+}
+
+func TestDecodeIT(t *testing.T) {
+	b := make([]byte, itHeaderSize)
+	copy(b, itMagic)
+	copy(b[4:], "Into the Void")
+	binary.LittleEndian.PutUint16(b[0x20:], 2)
+	binary.LittleEndian.PutUint16(b[0x22:], 1)
+	binary.LittleEndian.PutUint16(b[0x24:], 1)
+	binary.LittleEndian.PutUint16(b[0x26:], 1)
+	binary.LittleEndian.PutUint16(b[0x28:], 0x0214)
+	binary.LittleEndian.PutUint16(b[0x2E:], itSpecialMessage)
+	for i := range b[0x40:0x80] {
+		b[0x40+i] = 0xA0
+	}
+	copy(b[0x40:], []byte{32, 32, 0, 64, 100})
+
+	message := "Greetings\rto all\x00"
+	binary.LittleEndian.PutUint16(b[0x36:], uint16(len(message)))
+	binary.LittleEndian.PutUint32(b[0x38:], 0x200)
+
+	pointers := make([]byte, 2+8)
+	binary.LittleEndian.PutUint32(pointers[2:], 0x100)
+	binary.LittleEndian.PutUint32(pointers[6:], 0x180)
+	b = append(b, pointers...)
+	b = append(b, make([]byte, 0x200+len(message)-len(b))...)
+	copy(b[0x100:], "IMPI")
+	copy(b[0x100+itInstrumentNameOffset:], "Strings")
+	copy(b[0x180:], "IMPS")
+	copy(b[0x180+itSampleNameOffset:], "Violin")
+	copy(b[0x200:], message)
+
+	track, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if x := track.Comments["comment"]; x != "Greetings\nto all" {
+		t.Errorf(`expected Comments[comment] to be "Greetings\nto all", but got %q`, x)
+	}
+	expected := &Module{
+		Format:      FormatIT,
+		Title:       "Into the Void",
+		Tracker:     "Impulse Tracker 2.14",
+		Channels:    5,
+		Patterns:    1,
+		Orders:      2,
+		Instruments: []string{"Strings"},
+		Samples:     []string{"Violin"},
+		Message:     "Greetings\nto all",
+	}
+	if m := track.Extra["module"]; !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %+v, but got %+v", expected, m)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	format, err := Detect(bytes.NewReader([]byte("RIFF\x00\x00\x00\x00WAVE")))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if format != 0 {
+		t.Errorf("expected no format, but got %s", format)
+	}
+	if _, err := ReadModule(bytes.NewReader(make([]byte, 2000))); err != ErrNotModule {
+		t.Errorf("expected ErrNotModule, but got %v", err)
+	}
+}
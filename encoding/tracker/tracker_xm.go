@@ -0,0 +1,87 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tracker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// Layout of XM header. Patterns and instruments have variable size,
+// so instrument names are found by skipping the preceding parts.
+//
+// ref: https://github.com/milkytracker/MilkyTracker/blob/master/resources/reference/xm-form.txt
+const (
+	xmMagic      = "Extended Module: "
+	xmHeaderSize = 80
+	// xmHeaderSizeOffset is the offset of header size,
+	// which is counted from the field itself.
+	xmHeaderSizeOffset = 60
+	xmInstrumentSize   = 29
+)
+
+func readXM(f io.ReadSeeker) (*Module, error) {
+	b, err := readAt(f, 0, xmHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+
+	version := binary.LittleEndian.Uint16(b[58:])
+	m := &Module{
+		Format:   FormatXM,
+		Title:    text(b[17:37]),
+		Tracker:  fmt.Sprintf("%s (XM %d.%02d)", text(b[38:58]), version>>8, version&0xFF),
+		Orders:   binary.LittleEndian.Uint16(b[64:]),
+		Channels: binary.LittleEndian.Uint16(b[68:]),
+		Patterns: binary.LittleEndian.Uint16(b[70:]),
+	}
+	instruments := binary.LittleEndian.Uint16(b[72:])
+
+	offset := xmHeaderSizeOffset + int64(binary.LittleEndian.Uint32(b[xmHeaderSizeOffset:]))
+	for i := 0; i < int(m.Patterns); i++ {
+		h, err := readAt(f, offset, 9)
+		if err != nil {
+			return nil, errors.Wrap("could not read pattern header", err)
+		}
+		offset += int64(binary.LittleEndian.Uint32(h)) + int64(binary.LittleEndian.Uint16(h[7:]))
+	}
+
+	for i := 0; i < int(instruments); i++ {
+		h, err := readAt(f, offset, xmInstrumentSize)
+		if err != nil {
+			return nil, errors.Wrap("could not read instrument header", err)
+		}
+		m.Instruments = append(m.Instruments, text(h[4:26]))
+
+		samples := int(binary.LittleEndian.Uint16(h[27:]))
+		var sampleHeaderSize int64
+		if samples != 0 {
+			// Sample header size is only present in instruments with samples
+			s, err := readAt(f, offset+xmInstrumentSize, 4)
+			if err != nil {
+				return nil, errors.Wrap("could not read sample header size", err)
+			}
+			sampleHeaderSize = int64(binary.LittleEndian.Uint32(s))
+		}
+		offset += int64(binary.LittleEndian.Uint32(h))
+
+		var dataSize int64
+		for j := 0; j < samples; j++ {
+			s, err := readAt(f, offset+int64(j)*sampleHeaderSize, 40)
+			if err != nil {
+				return nil, errors.Wrap("could not read sample header", err)
+			}
+			dataSize += int64(binary.LittleEndian.Uint32(s))
+			m.Samples = append(m.Samples, text(s[18:40]))
+		}
+		offset += int64(samples)*sampleHeaderSize + dataSize
+	}
+	return m, nil
+}
@@ -0,0 +1,69 @@
+// Package tta implements True Audio (TTA1) file headers.
+// Tags of TTA files are read by packages apetag, id3v2 and id3v1.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package tta
+
+import (
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+)
+
+// HeaderSize is the size of TTA1 header including its CRC.
+const HeaderSize = 22
+
+// Format of the audio data.
+type Format uint16
+
+const (
+	FormatSimple    Format = 1
+	FormatEncrypted Format = 2
+)
+
+func (format Format) String() string {
+	switch format {
+	case FormatSimple:
+		return "simple"
+	case FormatEncrypted:
+		return "encrypted"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrNotTTA is returned when file is not a True Audio file.
+	ErrNotTTA = errors.New("not a tta file")
+	// ErrInvalidHeader is returned when CRC of the header does not match.
+	ErrInvalidHeader = errors.New("invalid tta header checksum")
+)
+
+// Header of TTA1 file.
+//
+// ref: https://tausoft.org/wiki/True_Audio_Codec_Format
+type Header struct {
+	Format        Format
+	Channels      uint16
+	BitsPerSample uint16
+	SampleRate    uint32
+	// SampleCount is the number of samples per channel.
+	SampleCount uint32
+	CRC         uint32
+}
+
+// File describes the parts of TTA file relevant to metadata.
+type File struct {
+	Header Header
+	// Offset of the header from the start of file,
+	// which is not zero if the file has ID3v2 tag.
+	Offset int64
+	// AudioSize is the size of the header, seek table
+	// and audio frames without tags.
+	AudioSize int64
+	ID3       *id3v2.Tag
+	APE       *apetag.Tag
+}
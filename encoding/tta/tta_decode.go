@@ -0,0 +1,129 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tta
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// Decode reads TTA stream properties, ID3v2, APE and ID3v1 tags
+// into *metadata.Track. f must be positioned at the start of the file.
+// APE is the tag, written by TTA tools, so it overrides ID3v2 tag.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode tta", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+
+	tag, err := id3v1.Read(f)
+	if err == nil {
+		tag.Apply(t)
+	} else if err != id3v1.ErrNoTag {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ReadFile reads header of TTA file and its tags.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	start, err := id3v2.Skip(f)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{Offset: start}
+	if start != 0 {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to id3v2 tag", err)
+		}
+		tag, err := id3v2.Read(f)
+		if err != nil {
+			return nil, errors.Wrap("could not read id3v2 tag", err)
+		}
+		file.ID3 = tag
+		if _, err := f.Seek(start, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek to header", err)
+		}
+	}
+
+	var b [HeaderSize]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotTTA
+		}
+		return nil, errors.Wrap("could not read header", err)
+	}
+	header, err := parseHeader(b[:])
+	if err != nil {
+		return nil, err
+	}
+	file.Header = header
+
+	ape, end, err := apetag.ReadTrailing(f)
+	if err != nil {
+		return nil, err
+	}
+	file.APE = ape
+	file.AudioSize = end - start
+	return file, nil
+}
+
+func parseHeader(b []byte) (Header, error) {
+	if string(b[:4]) != "TTA1" {
+		return Header{}, ErrNotTTA
+	}
+	h := Header{
+		Format:        Format(binary.LittleEndian.Uint16(b[4:6])),
+		Channels:      binary.LittleEndian.Uint16(b[6:8]),
+		BitsPerSample: binary.LittleEndian.Uint16(b[8:10]),
+		SampleRate:    binary.LittleEndian.Uint32(b[10:14]),
+		SampleCount:   binary.LittleEndian.Uint32(b[14:18]),
+		CRC:           binary.LittleEndian.Uint32(b[18:22]),
+	}
+	if crc32.ChecksumIEEE(b[:18]) != h.CRC {
+		return Header{}, ErrInvalidHeader
+	}
+	if h.Channels == 0 || h.SampleRate == 0 {
+		return Header{}, errors.New("invalid tta header")
+	}
+	return h, nil
+}
+
+// Apply stream properties, ID3v2 and APE tags to the track.
+func (file *File) Apply(t *metadata.Track) {
+	h := file.Header
+	t.Properties = metadata.Properties{
+		Codec:         "TTA",
+		SampleRate:    h.SampleRate,
+		Channels:      uint8(h.Channels),
+		BitsPerSample: uint8(h.BitsPerSample),
+		TotalSamples:  uint64(h.SampleCount),
+	}
+	t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, h.SampleRate)
+	if h.SampleCount != 0 {
+		t.Properties.Bitrate = uint32(uint64(file.AudioSize) * 8 * uint64(h.SampleRate) / uint64(h.SampleCount))
+	}
+
+	if file.ID3 != nil {
+		file.ID3.Apply(t)
+	}
+	if file.APE != nil {
+		file.APE.Apply(t)
+	}
+}
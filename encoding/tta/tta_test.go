@@ -0,0 +1,78 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package tta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+	"time"
+)
+
+var id3 = []byte("ID3\x03\x00\x00\x00\x00\x00\x12TIT2\x00\x00\x00\x08\x00\x00\x00ID3 Tit")
+
+// mkape builds APEv2 tag with a footer only.
+func mkape(key, value string) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b, uint32(len(value)))
+	b = append(b, key...)
+	b = append(b, 0)
+	b = append(b, value...)
+	footer := make([]byte, 32)
+	copy(footer, "APETAGEX")
+	binary.LittleEndian.PutUint32(footer[8:], 2000)
+	binary.LittleEndian.PutUint32(footer[12:], uint32(len(b)+32))
+	binary.LittleEndian.PutUint32(footer[16:], 1)
+	return append(b, footer...)
+}
+
+func mkheader(channels, bits uint16, rate, samples uint32) []byte {
+	b := make([]byte, HeaderSize)
+	copy(b, "TTA1")
+	binary.LittleEndian.PutUint16(b[4:], uint16(FormatSimple))
+	binary.LittleEndian.PutUint16(b[6:], channels)
+	binary.LittleEndian.PutUint16(b[8:], bits)
+	binary.LittleEndian.PutUint32(b[10:], rate)
+	binary.LittleEndian.PutUint32(b[14:], samples)
+	binary.LittleEndian.PutUint32(b[18:], crc32.ChecksumIEEE(b[:18]))
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	stream := append(mkheader(2, 24, 96000, 96000*3), make([]byte, 3000)...)
+	file := bytes.Join([][]byte{id3, stream, mkape("Artist", "APE Artist")}, nil)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "ID3 Tit" || track.Artist != "APE Artist" {
+		t.Errorf("expected title from ID3v2 and artist from APE, but got %q and %q", track.Title, track.Artist)
+	}
+	if track.Duration != 3*time.Second {
+		t.Errorf("expected duration 3s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "TTA" || p.SampleRate != 96000 || p.Channels != 2 || p.BitsPerSample != 24 || p.TotalSamples != 288000 {
+		t.Errorf("expected 288000 samples of 24-bit stereo at 96 kHz, but got %+v", p)
+	}
+	if p.Bitrate != uint32(len(stream)*8/3) {
+		t.Errorf("expected bitrate of %d bytes per 3 seconds, but got %d", len(stream), p.Bitrate)
+	}
+}
+
+func TestReadFileInvalid(t *testing.T) {
+	b := mkheader(2, 16, 44100, 44100)
+	b[18] ^= 0xFF
+	if _, err := ReadFile(bytes.NewReader(b)); err != ErrInvalidHeader {
+		t.Errorf("expected ErrInvalidHeader, but got %v", err)
+	}
+	if _, err := ReadFile(bytes.NewReader([]byte("TTA2"))); err != ErrNotTTA {
+		t.Errorf("expected ErrNotTTA, but got %v", err)
+	}
+}
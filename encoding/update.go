@@ -0,0 +1,282 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package encoding
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/audioid/audioid/encoding/aiff"
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/encoding/mp4"
+	"github.com/audioid/audioid/encoding/ogg"
+	"github.com/audioid/audioid/encoding/wav"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// ErrUnsupportedFormat is returned by DetectTagger and UpdateFile
+// for formats, which can not be written.
+var ErrUnsupportedFormat = errors.New("unsupported format for writing")
+
+// PicturesField is reported by Tagger.Unsupported,
+// when the format does not store pictures.
+const PicturesField = "pictures"
+
+// Mode defines how UpdateFile treats fields of the file.
+type Mode uint8
+
+const (
+	// Merge keeps fields and pictures of the file,
+	// which are empty in the track.
+	Merge Mode = iota
+	// Replace removes fields and pictures of the file,
+	// which are empty in the track.
+	Replace
+)
+
+// UpdateOptions are options of UpdateFile.
+type UpdateOptions struct {
+	Mode Mode
+	// Write defines backups of the file, it may be nil.
+	Write *utils.WriteOptions
+}
+
+// Tagger writes tracks into files of one format.
+type Tagger interface {
+	// Unsupported returns Vorbis comment keys of the track,
+	// which the format can not represent, and PicturesField,
+	// if the track has pictures, which are not written.
+	Unsupported(t *metadata.Track) []string
+	// WriteFile replaces tags of the file at path with the track.
+	// Fields, which are empty in the track, are removed.
+	// opts may be nil.
+	WriteFile(path string, t *metadata.Track, opts *utils.WriteOptions) error
+}
+
+// DetectTagger returns Tagger for the format of r.
+// Formats with writers are MPEG audio (MP3), raw AAC (ADTS), AC-3
+// and other files, which start with ID3v2 tag, MP4, Ogg Vorbis, Opus,
+// Ogg FLAC, RIFF WAVE, AIFF and AIFF-C.
+// r must be positioned at the start of the file.
+func DetectTagger(r io.ReadSeeker) (Tagger, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return nil, errors.Wrap("could not read magic", err)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek back", err)
+	}
+
+	switch {
+	case string(b[:3]) == "ID3", isAC3(b[:]), isADTS(b[:]), isMPEGFrame(b[:]):
+		return id3Tagger{}, nil
+	case string(b[4:8]) == "ftyp":
+		return mp4Tagger{}, nil
+	case string(b[:4]) == "OggS":
+		return oggTagger{}, nil
+	case string(b[:4]) == "RIFF":
+		return wavTagger{}, nil
+	case string(b[:4]) == "FORM":
+		return aiffTagger{}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// UpdateFile detects the format of the file at path and writes the track
+// to it. With Merge mode, the track is merged into the track decoded
+// from the file first. Writers rebuild tags from the track,
+// so tag frames, which Decode does not read, are not kept.
+// It returns fields, which the format can not represent,
+// see Tagger.Unsupported. opts may be nil for defaults.
+//
+// Files are either rewritten atomically or patched in place with a journal,
+// see utils.ReplaceFile and utils.PatchFile, so an interrupted write
+// is rolled back by the next UpdateFile or utils.RecoverFile.
+func UpdateFile(path string, t *metadata.Track, opts *UpdateOptions) ([]string, error) {
+	if opts == nil {
+		opts = &UpdateOptions{}
+	}
+	// Merge must not read the file, which was left half written
+	if err := utils.RecoverFile(path); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap("could not open file", err)
+	}
+	tagger, err := DetectTagger(f)
+	if err == nil && opts.Mode == Merge {
+		var old *metadata.Track
+		old, err = Decode(f)
+		t = merge(t, old)
+	}
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	unsupported := tagger.Unsupported(t)
+	if err := tagger.WriteFile(path, t, opts.Write); err != nil {
+		return nil, err
+	}
+	return unsupported, nil
+}
+
+// merge returns a track with fields of t, where fields,
+// which are empty in t, are taken from old.
+func merge(t, old *metadata.Track) *metadata.Track {
+	comments := flac.TrackComments(t)
+	for key, value := range flac.TrackComments(old) {
+		if _, ok := comments[key]; !ok {
+			comments[key] = value
+		}
+	}
+
+	merged := &metadata.Track{Pictures: t.Pictures}
+	(&flac.VorbisComment{Comments: comments}).Apply(merged)
+	if len(merged.Pictures) == 0 {
+		merged.Pictures = old.Pictures
+	}
+	for key, value := range old.Extra {
+		merged.SetExtra(key, value)
+	}
+	for key, value := range t.Extra {
+		merged.SetExtra(key, value)
+	}
+	return merged
+}
+
+// id3Tagger writes ID3v2 tag at the start of the file.
+// Decoders fill empty fields from trailing APE and ID3v1 tags,
+// so they are updated by the same write, see trailingTags.
+type id3Tagger struct{}
+
+func (id3Tagger) Unsupported(t *metadata.Track) []string {
+	return nil
+}
+
+func (id3Tagger) WriteFile(path string, t *metadata.Track, opts *utils.WriteOptions) error {
+	tag, err := id3v2.NewTag(t, 4, id3v2.EncodingUTF8)
+	if err != nil {
+		return err
+	}
+	return id3v2.WriteFileWithTrailer(path, tag, id3v2.DefaultPadding, trailingTags(t), opts)
+}
+
+// trailingTags returns id3v2.Trailer, which removes APE tag, which has
+// no writer, and replaces ID3v1 tag with the track. "TAG+" tag is dropped.
+// Files without trailing tags keep their end.
+func trailingTags(t *metadata.Track) id3v2.Trailer {
+	return func(f *os.File, size int64) (int64, []byte, error) {
+		end := size
+		v1, err := id3v1.Read(f)
+		if err == nil {
+			end -= v1.Size()
+		} else if err != id3v1.ErrNoTag {
+			return 0, nil, err
+		}
+		// Broken APE tag is left to be skipped by decoders
+		ape, err := apetag.ReadAt(f, end)
+		if err == nil {
+			end = ape.Offset
+		} else if err != apetag.ErrNoTag && err != apetag.ErrInvalidTag {
+			return 0, nil, errors.Wrap("could not read ape tag", err)
+		}
+
+		if v1 == nil {
+			return end, nil, nil
+		}
+		return end, id3v1.NewTag(t).Encode(), nil
+	}
+}
+
+type mp4Tagger struct{}
+
+// Unsupported returns numbers, which are not integers,
+// because iTunes stores them as binary values.
+func (mp4Tagger) Unsupported(t *metadata.Track) []string {
+	var keys []string
+	comments := flac.TrackComments(t)
+	for _, key := range []string{"tracknumber", "tracktotal", "discnumber", "disctotal"} {
+		if value, ok := comments[key]; ok {
+			if _, err := strconv.ParseUint(value, 10, 16); err != nil {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func (mp4Tagger) WriteFile(path string, t *metadata.Track, opts *utils.WriteOptions) error {
+	return mp4.WriteFile(path, mp4.NewItems(t), mp4.DefaultPadding, opts)
+}
+
+type oggTagger struct{}
+
+// Unsupported returns keys, which are not valid Vorbis comment field names,
+// and pictures, which are only kept as METADATA_BLOCK_PICTURE comments.
+func (oggTagger) Unsupported(t *metadata.Track) []string {
+	var keys []string
+	for key := range flac.TrackComments(t) {
+		for _, c := range key {
+			// ASCII 0x20 through 0x7D, except "="
+			if c < 0x20 || c > 0x7D || c == '=' {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	if len(t.Pictures) != 0 {
+		keys = append(keys, PicturesField)
+	}
+	return keys
+}
+
+func (oggTagger) WriteFile(path string, t *metadata.Track, opts *utils.WriteOptions) error {
+	return ogg.WriteFile(path, flac.NewVorbisComment(t), opts)
+}
+
+// wavTagger writes LIST/INFO and ID3 chunks,
+// so ID3 keeps fields, which INFO can not represent.
+type wavTagger struct{}
+
+func (wavTagger) Unsupported(t *metadata.Track) []string {
+	return nil
+}
+
+func (wavTagger) WriteFile(path string, t *metadata.Track, opts *utils.WriteOptions) error {
+	tags, err := wav.NewTags(t)
+	if err != nil {
+		return err
+	}
+	return wav.WriteFile(path, tags, opts)
+}
+
+// aiffTagger writes text and ID3 chunks,
+// so ID3 keeps fields, which text chunks can not represent.
+type aiffTagger struct{}
+
+func (aiffTagger) Unsupported(t *metadata.Track) []string {
+	return nil
+}
+
+func (aiffTagger) WriteFile(path string, t *metadata.Track, opts *utils.WriteOptions) error {
+	tags, err := aiff.NewTags(t)
+	if err != nil {
+		return err
+	}
+	return aiff.WriteFile(path, tags, opts)
+}
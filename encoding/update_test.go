@@ -0,0 +1,248 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+func riffChunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, 8, 8+len(body)+1)
+	copy(b, id)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)))
+	b = append(b, body...)
+	if len(body)&1 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// testWave builds 16-bit mono PCM wave with LIST/INFO title and artist.
+func testWave() []byte {
+	format := make([]byte, 16)
+	binary.LittleEndian.PutUint16(format[0:], 1)
+	binary.LittleEndian.PutUint16(format[2:], 1)
+	binary.LittleEndian.PutUint32(format[4:], 8000)
+	binary.LittleEndian.PutUint32(format[8:], 16000)
+	binary.LittleEndian.PutUint16(format[12:], 2)
+	binary.LittleEndian.PutUint16(format[14:], 16)
+	return riffChunk("RIFF", []byte("WAVE"),
+		riffChunk("fmt ", format),
+		riffChunk("data", make([]byte, 16000)),
+		riffChunk("LIST", []byte("INFO"),
+			riffChunk("INAM", []byte("Old title\x00")),
+			riffChunk("IART", []byte("Artist\x00"))),
+	)
+}
+
+func updateFile(t *testing.T, file []byte, track *metadata.Track, opts *UpdateOptions) (*metadata.Track, []string) {
+	dir, err := ioutil.TempDir("", "encoding")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	unsupported, err := UpdateFile(path, track, opts)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	written, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return written, unsupported
+}
+
+func TestUpdateFile(t *testing.T) {
+	track, unsupported := updateFile(t, testWave(), &metadata.Track{Title: "Title", Genre: "Jazz"}, nil)
+	if track.Title != "Title" || track.Genre != "Jazz" || track.Artist != "Artist" {
+		t.Errorf("expected title and genre to be merged with artist, but got %+v", track)
+	}
+	if len(unsupported) != 0 {
+		t.Errorf("expected all fields to be supported, but got %v", unsupported)
+	}
+	if track.Properties.TotalSamples != 8000 {
+		t.Errorf("expected audio to be kept, but got %d samples", track.Properties.TotalSamples)
+	}
+
+	track, _ = updateFile(t, testWave(), &metadata.Track{Title: "Title"}, &UpdateOptions{Mode: Replace})
+	if track.Title != "Title" || track.Artist != "" {
+		t.Errorf("expected artist to be removed, but got %+v", track)
+	}
+}
+
+// testMP3 builds MPEG audio with APE and ID3v1 tags at the end.
+func testMP3() []byte {
+	var file []byte
+	for i := 0; i < 10; i++ {
+		// MPEG-1 Layer III, 128 kbps, 44100 Hz, stereo
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		file = append(file, frame...)
+	}
+
+	item := append([]byte{9, 0, 0, 0, 0, 0, 0, 0}, "Title\x00APE title"...)
+	footer := make([]byte, 32)
+	copy(footer, "APETAGEX")
+	binary.LittleEndian.PutUint32(footer[8:], 2000)
+	binary.LittleEndian.PutUint32(footer[12:], uint32(len(item)+32))
+	binary.LittleEndian.PutUint32(footer[16:], 1)
+	file = append(append(file, item...), footer...)
+
+	v1 := make([]byte, 128)
+	copy(v1, "TAGv1 title")
+	copy(v1[33:], "v1 artist")
+	copy(v1[97:], "v1 comment")
+	v1[127] = 255
+	return append(file, v1...)
+}
+
+func TestUpdateFileTrailingTags(t *testing.T) {
+	track, _ := updateFile(t, testMP3(), &metadata.Track{Album: "Album"}, &UpdateOptions{Mode: Replace})
+	if track.Album != "Album" || track.Title != "" || track.Artist != "" || track.Comments["comment"] != "" {
+		t.Errorf("expected fields of trailing tags to be removed, but got %+v", track)
+	}
+	if track.Properties.TotalSamples != 10*1152 {
+		t.Errorf("expected audio to be kept, but got %d samples", track.Properties.TotalSamples)
+	}
+
+	track, _ = updateFile(t, testMP3(), &metadata.Track{Title: "Title"}, nil)
+	if track.Title != "Title" || track.Artist != "v1 artist" {
+		t.Errorf(`expected title "Title" merged with artist "v1 artist", but got %+v`, track)
+	}
+}
+
+func TestUpdateFileTrailingTagsInPlace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encoding")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.mp3")
+
+	tag, err := id3v2.NewTag(&metadata.Track{Title: "Old title"}, 4, id3v2.EncodingUTF8)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	head, err := tag.Encode(1024)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	file := append(head, testMP3()...)
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	opts := &UpdateOptions{Mode: Replace, Write: &utils.WriteOptions{Backup: utils.SuffixBackup}}
+	if _, err := UpdateFile(path, &metadata.Track{Title: "Title"}, opts); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	audio := file[len(head) : len(head)+10*417]
+	if !bytes.Equal(b[len(head):len(b)-id3v1.TagSize], audio) {
+		t.Error("expected audio to stay in place and ape tag to be removed")
+	}
+	if v1 := id3v1.NewTag(&metadata.Track{Title: "Title"}).Encode(); !bytes.HasSuffix(b, v1) {
+		t.Errorf("expected id3v1 tag to be %q, but got %q", v1, b[len(b)-id3v1.TagSize:])
+	}
+	if backup, err := ioutil.ReadFile(path + ".bak"); err != nil || !bytes.Equal(backup, file) {
+		t.Errorf("expected backup of the original, but got %v", err)
+	}
+}
+
+func TestUpdateFileUnsupported(t *testing.T) {
+	head := []byte("OpusHead\x01\x01\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+	tags := []byte("OpusTags\x06\x00\x00\x00vendor\x00\x00\x00\x00")
+	var file []byte
+	for i, packet := range [][]byte{head, tags, []byte("audio")} {
+		file = append(file, oggPage(uint8(i), packet)...)
+	}
+
+	track := &metadata.Track{
+		Title:    "Title",
+		Comments: map[string]string{"a=b": "c"},
+		Pictures: []metadata.Picture{{MIME: "image/png"}},
+	}
+	written, unsupported := updateFile(t, file, track, &UpdateOptions{Mode: Replace})
+	if !reflect.DeepEqual(unsupported, []string{"a=b", PicturesField}) {
+		t.Errorf("expected invalid key and pictures to be unsupported, but got %v", unsupported)
+	}
+	if written.Title != "Title" {
+		t.Errorf(`expected Title to be "Title", but got %q`, written.Title)
+	}
+}
+
+func TestUpdateFileOggFLAC(t *testing.T) {
+	info := make([]byte, 34)
+	binary.BigEndian.PutUint16(info[0:], 4096)
+	binary.BigEndian.PutUint16(info[2:], 4096)
+	// 44100 Hz, stereo, 16 bits, 44100 samples
+	binary.BigEndian.PutUint64(info[10:], 44100<<44|1<<41|15<<36|44100)
+	head := append([]byte("\x7fFLAC\x01\x00\x00\x01fLaC\x00\x00\x00\x22"), info...)
+	comment := []byte("\x00\x00\x00\x00\x01\x00\x00\x00\x0d\x00\x00\x00ARTIST=Artist")
+	block := append([]byte{0x84, 0, 0, byte(len(comment))}, comment...)
+	var file []byte
+	for i, packet := range [][]byte{head, block, []byte("audio")} {
+		file = append(file, oggPage(uint8(i), packet)...)
+	}
+
+	track, _ := updateFile(t, file, &metadata.Track{Title: "Title"}, nil)
+	if track.Title != "Title" || track.Artist != "Artist" {
+		t.Errorf(`expected title "Title" merged with artist "Artist", but got %+v`, track)
+	}
+	if track.Properties.Codec != "FLAC" || track.Properties.SampleRate != 44100 {
+		t.Errorf("expected 44.1 kHz FLAC, but got %+v", track.Properties)
+	}
+}
+
+// oggPage builds a page of a single packet shorter than 255 bytes.
+// The first page starts the stream and the third one ends it.
+func oggPage(sequence uint8, packet []byte) []byte {
+	flags := map[uint8]byte{0: 2, 2: 4}[sequence]
+	b := []byte{'O', 'g', 'g', 'S', 0, flags}
+	b = append(b, make([]byte, 8)...)
+	b = append(b, 1, 0, 0, 0, sequence, 0, 0, 0, 0, 0, 0, 0, 1, byte(len(packet)))
+	b = append(b, packet...)
+
+	// CRC-32 with polynomial 0x04c11db7 without bit reflection
+	crc := uint32(0)
+	for _, x := range b {
+		crc ^= uint32(x) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	binary.LittleEndian.PutUint32(b[22:], crc)
+	return b
+}
@@ -0,0 +1,129 @@
+// Package wav implements RIFF WAVE files.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package wav
+
+import (
+	"fmt"
+
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+)
+
+// ChunkHeaderSize is the size of chunk identifier and size.
+const ChunkHeaderSize = 8
+
+var (
+	// ErrNotWave is returned when file is not a RIFF WAVE file.
+	ErrNotWave = errors.New("not a wave file")
+	// ErrNoFormat is returned when file has no fmt chunk.
+	ErrNoFormat = errors.New("no wave format chunk")
+)
+
+// FormatTag is a wave format code.
+//
+// ref: https://docs.microsoft.com/en-us/windows/win32/api/mmreg/ns-mmreg-waveformatex
+type FormatTag uint16
+
+const (
+	FormatPCM        FormatTag = 0x0001
+	FormatADPCM      FormatTag = 0x0002
+	FormatIEEEFloat  FormatTag = 0x0003
+	FormatALaw       FormatTag = 0x0006
+	FormatMULaw      FormatTag = 0x0007
+	FormatIMAADPCM   FormatTag = 0x0011
+	FormatGSM610     FormatTag = 0x0031
+	FormatMPEG       FormatTag = 0x0050
+	FormatMPEGLayer3 FormatTag = 0x0055
+	FormatExtensible FormatTag = 0xFFFE
+)
+
+func (tag FormatTag) String() string {
+	switch tag {
+	case FormatPCM:
+		return "PCM"
+	case FormatADPCM:
+		return "MS ADPCM"
+	case FormatIEEEFloat:
+		return "IEEE float"
+	case FormatALaw:
+		return "A-law"
+	case FormatMULaw:
+		return "µ-law"
+	case FormatIMAADPCM:
+		return "IMA ADPCM"
+	case FormatGSM610:
+		return "GSM 6.10"
+	case FormatMPEG:
+		return "MP2"
+	case FormatMPEGLayer3:
+		return "MP3"
+	case FormatExtensible:
+		return "extensible"
+	}
+	return fmt.Sprintf("unknown<%#04x>", uint16(tag))
+}
+
+// Format is a content of fmt chunk, WAVEFORMATEX
+// optionally extended by WAVEFORMATEXTENSIBLE.
+type Format struct {
+	Tag           FormatTag
+	Channels      uint16
+	SampleRate    uint32
+	ByteRate      uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+	// ValidBitsPerSample, ChannelMask and SubFormat
+	// are only set for FormatExtensible.
+	ValidBitsPerSample uint16
+	ChannelMask        uint32
+	SubFormat          [16]byte
+}
+
+// Chunk is a chunk header of RIFF file.
+type Chunk struct {
+	// ID is a chunk identifier. Wave64 GUIDs of known chunks
+	// are converted to RIFF identifiers, e.g. "fmt ".
+	ID string
+	// Offset of the chunk data from the start of file.
+	Offset int64
+	// Size of the chunk data without header and padding.
+	Size int64
+	// HeaderSize is 8 bytes for RIFF, RF64 and BW64, and 24 bytes for Wave64.
+	HeaderSize int64
+}
+
+// File describes the parts of WAVE file relevant to metadata.
+type File struct {
+	Form   Form
+	Format *Format
+	// DataSize64 is a ds64 chunk of RF64 and BW64 files.
+	DataSize64 *DataSize64
+	// Chunks are all top-level chunks in the order of the file.
+	Chunks []*Chunk
+	// DataSize is the size of data chunk.
+	DataSize int64
+	// Info holds LIST/INFO values by their identifiers, e.g. "INAM".
+	Info map[string]string
+	// ID3 is a tag of "id3 " or "ID3 " chunk.
+	ID3 *id3v2.Tag
+	// Broadcast is a bext chunk of Broadcast WAV.
+	Broadcast *BroadcastExtension
+	// IXML is an iXML chunk.
+	IXML *IXML
+	// Cart is a cart chunk.
+	Cart *Cart
+}
+
+// Codec returns the actual format tag.
+// For FormatExtensible the tag is taken from the sub-format GUID.
+func (format *Format) Codec() FormatTag {
+	if format.Tag == FormatExtensible {
+		return FormatTag(uint16(format.SubFormat[0]) | uint16(format.SubFormat[1])<<8)
+	}
+	return format.Tag
+}
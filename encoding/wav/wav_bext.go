@@ -0,0 +1,147 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// bextSize is the size of bext chunk without coding history.
+const bextSize = 602
+
+// LoudnessUnset marks loudness values, which were not measured.
+const LoudnessUnset = 0x7FFF
+
+// BroadcastExtension is a content of bext chunk of Broadcast WAV.
+//
+// ref: https://tech.ebu.ch/docs/tech/tech3285.pdf
+type BroadcastExtension struct {
+	Description         string
+	Originator          string
+	OriginatorReference string
+	// OriginationDate is formatted as "yyyy-mm-dd".
+	OriginationDate string
+	// OriginationTime is formatted as "hh:mm:ss" or "hh-mm-ss".
+	OriginationTime string
+	// TimeReference is the number of samples since midnight
+	// of the first sample.
+	TimeReference uint64
+	Version       uint16
+	// UMID is SMPTE 330M unique material identifier.
+	// Basic UMID takes the first 32 bytes, the rest is zero.
+	UMID [64]byte
+	// Loudness values since version 2, in hundredths of LU, LUFS or dBTP.
+	// LoudnessUnset implies the value was not measured.
+	LoudnessValue        int16
+	LoudnessRange        int16
+	MaxTruePeakLevel     int16
+	MaxMomentaryLoudness int16
+	MaxShortTermLoudness int16
+	// CodingHistory is a list of coding processes
+	// applied to the audio, one per line.
+	CodingHistory string
+}
+
+// Timecode is a time of day in hours, minutes, seconds and frames,
+// with a sample-accurate offset from the start of the frame.
+type Timecode struct {
+	Hours   uint32
+	Minutes uint32
+	Seconds uint32
+	Frames  uint32
+	// Samples is the offset of the sample from the start of the frame.
+	Samples uint32
+}
+
+func (tc Timecode) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d:%02d+%d", tc.Hours, tc.Minutes, tc.Seconds, tc.Frames, tc.Samples)
+}
+
+// parseBroadcastExtension parses bext chunk.
+func parseBroadcastExtension(b []byte) (*BroadcastExtension, error) {
+	if len(b) < bextSize {
+		return nil, errors.New("bext chunk is too short")
+	}
+	bext := &BroadcastExtension{
+		Description:         decodeText(b[0:256]),
+		Originator:          decodeText(b[256:288]),
+		OriginatorReference: decodeText(b[288:320]),
+		OriginationDate:     decodeText(b[320:330]),
+		OriginationTime:     decodeText(b[330:338]),
+		TimeReference:       binary.LittleEndian.Uint64(b[338:346]),
+		Version:             binary.LittleEndian.Uint16(b[346:348]),
+		CodingHistory:       decodeText(b[bextSize:]),
+	}
+	copy(bext.UMID[:], b[348:412])
+
+	bext.LoudnessValue, bext.LoudnessRange = LoudnessUnset, LoudnessUnset
+	bext.MaxTruePeakLevel, bext.MaxMomentaryLoudness, bext.MaxShortTermLoudness = LoudnessUnset, LoudnessUnset, LoudnessUnset
+	if bext.Version >= 2 {
+		bext.LoudnessValue = int16(binary.LittleEndian.Uint16(b[412:414]))
+		bext.LoudnessRange = int16(binary.LittleEndian.Uint16(b[414:416]))
+		bext.MaxTruePeakLevel = int16(binary.LittleEndian.Uint16(b[416:418]))
+		bext.MaxMomentaryLoudness = int16(binary.LittleEndian.Uint16(b[418:420]))
+		bext.MaxShortTermLoudness = int16(binary.LittleEndian.Uint16(b[420:422]))
+	}
+	return bext, nil
+}
+
+// Bytes serializes bext chunk data.
+// Text fields longer than their fixed size are truncated.
+func (bext *BroadcastExtension) Bytes() []byte {
+	b := make([]byte, bextSize, bextSize+len(bext.CodingHistory))
+	copy(b[0:256], bext.Description)
+	copy(b[256:288], bext.Originator)
+	copy(b[288:320], bext.OriginatorReference)
+	copy(b[320:330], bext.OriginationDate)
+	copy(b[330:338], bext.OriginationTime)
+	binary.LittleEndian.PutUint64(b[338:346], bext.TimeReference)
+	binary.LittleEndian.PutUint16(b[346:348], bext.Version)
+	copy(b[348:412], bext.UMID[:])
+	if bext.Version >= 2 {
+		binary.LittleEndian.PutUint16(b[412:414], uint16(bext.LoudnessValue))
+		binary.LittleEndian.PutUint16(b[414:416], uint16(bext.LoudnessRange))
+		binary.LittleEndian.PutUint16(b[416:418], uint16(bext.MaxTruePeakLevel))
+		binary.LittleEndian.PutUint16(b[418:420], uint16(bext.MaxMomentaryLoudness))
+		binary.LittleEndian.PutUint16(b[420:422], uint16(bext.MaxShortTermLoudness))
+	}
+	return append(b, bext.CodingHistory...)
+}
+
+// TimeReferenceDuration returns time since midnight
+// of the first sample at given sample rate.
+func (bext *BroadcastExtension) TimeReferenceDuration(sampleRate uint32) time.Duration {
+	return metadata.SamplesDuration(bext.TimeReference, sampleRate)
+}
+
+// Timecode converts time reference into timecode at given frame rate.
+// Only integer non-drop frame rates are supported, e.g. 24, 25 or 30.
+func (bext *BroadcastExtension) Timecode(sampleRate, fps uint32) Timecode {
+	if sampleRate == 0 || fps == 0 {
+		return Timecode{}
+	}
+	rate := uint64(sampleRate)
+	seconds := bext.TimeReference / rate
+	samples := bext.TimeReference % rate
+
+	frame := samples * uint64(fps) / rate
+	// When sample rate is not a multiple of frame rate, e.g. 44100 Hz at 24 fps,
+	// a frame starts at the first whole sample after its exact start.
+	frameStart := (frame*rate + uint64(fps) - 1) / uint64(fps)
+	return Timecode{
+		Hours:   uint32(seconds / 3600 % 24),
+		Minutes: uint32(seconds / 60 % 60),
+		Seconds: uint32(seconds % 60),
+		Frames:  uint32(frame),
+		Samples: uint32(samples - frameStart),
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wav
+
+import (
+	"encoding/binary"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// cartSize is the size of cart chunk without tag text.
+const cartSize = 2048
+
+// Cart is a content of cart chunk, used by broadcast automation systems.
+//
+// ref: AES46-2002, http://www.cartchunk.org
+type Cart struct {
+	// Version is formatted as "0101" for version 1.01.
+	Version        string
+	Title          string
+	Artist         string
+	CutID          string
+	ClientID       string
+	Category       string
+	Classification string
+	OutCue         string
+	// StartDate and EndDate are formatted as "yyyy-mm-dd",
+	// StartTime and EndTime as "hh:mm:ss".
+	StartDate          string
+	StartTime          string
+	EndDate            string
+	EndTime            string
+	ProducerAppID      string
+	ProducerAppVersion string
+	UserDef            string
+	// LevelReference is the sample value of 0 dB reference.
+	LevelReference int32
+	PostTimers     [8]CartTimer
+	URL            string
+	// TagText is a free-form text.
+	TagText string
+}
+
+// CartTimer is a marker in samples from the start of the audio.
+type CartTimer struct {
+	// Usage is a 4-character code, e.g. "SEG1" or "INT "
+	Usage string
+	Value uint32
+}
+
+// cartFields lists offsets and sizes of fixed text fields.
+var cartFields = [...]struct{ offset, size int }{
+	{0, 4}, {4, 64}, {68, 64}, {132, 64}, {196, 64}, {260, 64}, {324, 64}, {388, 64},
+	{452, 10}, {462, 8}, {470, 10}, {480, 8}, {488, 64}, {552, 64}, {616, 64},
+}
+
+const (
+	cartLevelOffset  = 680
+	cartTimersOffset = 684
+	cartURLOffset    = 1024
+	cartURLSize      = 1024
+)
+
+func (cart *Cart) textFields() [len(cartFields)]*string {
+	return [...]*string{
+		&cart.Version, &cart.Title, &cart.Artist, &cart.CutID, &cart.ClientID,
+		&cart.Category, &cart.Classification, &cart.OutCue,
+		&cart.StartDate, &cart.StartTime, &cart.EndDate, &cart.EndTime,
+		&cart.ProducerAppID, &cart.ProducerAppVersion, &cart.UserDef,
+	}
+}
+
+// parseCart parses cart chunk.
+func parseCart(b []byte) (*Cart, error) {
+	if len(b) < cartSize {
+		return nil, errors.New("cart chunk is too short")
+	}
+	cart := &Cart{
+		LevelReference: int32(binary.LittleEndian.Uint32(b[cartLevelOffset:])),
+		URL:            decodeText(b[cartURLOffset : cartURLOffset+cartURLSize]),
+		TagText:        decodeText(b[cartSize:]),
+	}
+	for i, field := range cart.textFields() {
+		*field = decodeText(b[cartFields[i].offset : cartFields[i].offset+cartFields[i].size])
+	}
+	for i := range cart.PostTimers {
+		timer := b[cartTimersOffset+i*8:]
+		cart.PostTimers[i] = CartTimer{
+			Usage: decodeText(timer[:4]),
+			Value: binary.LittleEndian.Uint32(timer[4:8]),
+		}
+	}
+	return cart, nil
+}
+
+// Bytes serializes cart chunk data.
+// Text fields longer than their fixed size are truncated.
+func (cart *Cart) Bytes() []byte {
+	b := make([]byte, cartSize, cartSize+len(cart.TagText))
+	for i, field := range cart.textFields() {
+		copy(b[cartFields[i].offset:cartFields[i].offset+cartFields[i].size], *field)
+	}
+	binary.LittleEndian.PutUint32(b[cartLevelOffset:], uint32(cart.LevelReference))
+	for i, timer := range cart.PostTimers {
+		copy(b[cartTimersOffset+i*8:cartTimersOffset+i*8+4], timer.Usage)
+		binary.LittleEndian.PutUint32(b[cartTimersOffset+i*8+4:], timer.Value)
+	}
+	copy(b[cartURLOffset:cartURLOffset+cartURLSize], cart.URL)
+	return append(b, cart.TagText...)
+}
@@ -0,0 +1,370 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"unicode/utf8"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+// maxChunkSize limits LIST, id3, bext and other chunks, which are read whole,
+// so a corrupted size does not allocate gigabytes.
+const maxChunkSize = 64 << 20
+
+// infoKeys maps LIST/INFO identifiers to Vorbis comment keys.
+//
+// ref: https://www.exiftool.org/TagNames/RIFF.html#Info
+var infoKeys = map[string]string{
+	"INAM": "title",
+	"IART": "artist",
+	"IPRD": "album",
+	"ICRD": "date",
+	"IGNR": "genre",
+	"ICMT": "comment",
+	"ITRK": "tracknumber",
+	"IPRT": "tracknumber",
+	"ICOP": "copyright",
+	"ISFT": "encoder",
+	"IENG": "engineer",
+	"ISBJ": "description",
+	"ILNG": "language",
+	"IMUS": "composer",
+	"IWRI": "lyricist",
+}
+
+// Decode reads format, LIST/INFO and ID3 chunks
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode wave", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+	return t, nil
+}
+
+// ReadFile walks chunks of RIFF WAVE, RF64, BW64 or Wave64 file.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	var header [40]byte
+	if _, err := io.ReadFull(f, header[:12]); err != nil {
+		return nil, errors.Wrap("could not read riff header", err)
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap("could not seek to the end", err)
+	}
+
+	file := &File{}
+	switch string(header[:4]) {
+	case "RIFF":
+		file.Form = FormRIFF
+	case "RF64":
+		file.Form = FormRF64
+	case "BW64":
+		file.Form = FormBW64
+	case "riff":
+		file.Form = FormWave64
+		if _, err := f.Seek(12, io.SeekStart); err != nil {
+			return nil, errors.Wrap("could not seek back", err)
+		}
+		if _, err := io.ReadFull(f, header[12:]); err != nil {
+			return nil, errors.Wrap("could not read wave64 header", err)
+		}
+		if !bytes.Equal(header[:16], wave64RIFF) || !bytes.Equal(header[24:40], wave64WAVE) {
+			return nil, ErrNotWave
+		}
+		if riffEnd := int64(binary.LittleEndian.Uint64(header[16:24])); riffEnd < end && riffEnd > 40 {
+			end = riffEnd
+		}
+		return file, file.readChunks(f, 40, end)
+	default:
+		return nil, ErrNotWave
+	}
+	if string(header[8:12]) != "WAVE" {
+		return nil, ErrNotWave
+	}
+
+	// Trust the file size, if RIFF size is broken.
+	// RF64 size is a placeholder, the actual one is stored in ds64 chunk.
+	if riffEnd := int64(binary.LittleEndian.Uint32(header[4:8])) + 8; riffEnd < end && riffEnd > 12 && file.Form == FormRIFF {
+		end = riffEnd
+	}
+	return file, file.readChunks(f, 12, end)
+}
+
+// readChunks reads chunks from offset to end.
+func (file *File) readChunks(f io.ReadSeeker, offset, end int64) error {
+	for offset+ChunkHeaderSize <= end {
+		var chunk *Chunk
+		var err error
+		if file.Form == FormWave64 {
+			chunk, err = readWave64ChunkHeader(f, offset, end)
+		} else {
+			chunk, err = file.readChunkHeader(f, offset, end)
+		}
+		if err != nil {
+			return err
+		}
+
+		file.Chunks = append(file.Chunks, chunk)
+		if err := file.readChunk(f, chunk); err != nil {
+			return err
+		}
+
+		next := chunk.Offset + chunk.Size + chunk.Size&1
+		if file.Form == FormWave64 {
+			next = align8(chunk.Offset + chunk.Size)
+		}
+		if next <= offset {
+			return errors.New("invalid size of chunk " + chunk.ID)
+		}
+		offset = next
+	}
+
+	if file.Format == nil {
+		return ErrNoFormat
+	}
+	return nil
+}
+
+// readChunkHeader reads chunk header at given offset.
+// Size of the last chunk is truncated to the end of file,
+// because recorders often leave data chunk size unfinished.
+func (file *File) readChunkHeader(f io.ReadSeeker, offset, end int64) (*Chunk, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk", err)
+	}
+	var b [ChunkHeaderSize]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return nil, errors.Wrap("could not read chunk header", err)
+	}
+	chunk := &Chunk{
+		ID:         string(b[:4]),
+		Offset:     offset + ChunkHeaderSize,
+		Size:       int64(binary.LittleEndian.Uint32(b[4:8])),
+		HeaderSize: ChunkHeaderSize,
+	}
+	if chunk.Size == sizePlaceholder && file.DataSize64 != nil {
+		if size, ok := file.DataSize64.chunkSize(chunk.ID); ok {
+			// Unlike 32-bit sizes, ds64 sizes past the end are not truncated,
+			// as they may not even fit into int64
+			if size > uint64(end-chunk.Offset) {
+				return nil, errors.New("invalid ds64 size of chunk " + chunk.ID)
+			}
+			chunk.Size = int64(size)
+		}
+	}
+	if chunk.Offset+chunk.Size > end {
+		chunk.Size = end - chunk.Offset
+	}
+	return chunk, nil
+}
+
+func (file *File) readChunk(f io.ReadSeeker, chunk *Chunk) error {
+	switch chunk.ID {
+	case "ds64":
+		if file.Form != FormRF64 && file.Form != FormBW64 {
+			return nil
+		}
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		file.DataSize64, err = parseDataSize64(b)
+		return err
+	case "fmt ":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		file.Format, err = ParseFormat(b)
+		return err
+	case "data":
+		file.DataSize = chunk.Size
+	case "LIST":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		if len(b) >= 4 && string(b[:4]) == "INFO" {
+			file.Info = parseInfo(b[4:])
+		}
+	case "id3 ", "ID3 ":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		// A broken tag is kept as unknown chunk, format and INFO are still useful
+		if tag, err := id3v2.Parse(b); err == nil {
+			file.ID3 = tag
+		}
+	case "bext":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		// Like id3, a broken production chunk does not hide the format
+		if bext, err := parseBroadcastExtension(b); err == nil {
+			file.Broadcast = bext
+		}
+	case "iXML":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		file.IXML = parseIXML(b)
+	case "cart":
+		b, err := readChunkData(f, chunk)
+		if err != nil {
+			return err
+		}
+		if cart, err := parseCart(b); err == nil {
+			file.Cart = cart
+		}
+	}
+	return nil
+}
+
+func readChunkData(f io.ReadSeeker, chunk *Chunk) ([]byte, error) {
+	if chunk.Size > maxChunkSize {
+		return nil, errors.New("wave chunk " + chunk.ID + " is too large")
+	}
+	if _, err := f.Seek(chunk.Offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk "+chunk.ID, err)
+	}
+	b := make([]byte, chunk.Size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, errors.Wrap("could not read chunk "+chunk.ID, err)
+	}
+	return b, nil
+}
+
+// ParseFormat parses fmt chunk, which is also used
+// as type-specific data of other containers, e.g. ASF.
+func ParseFormat(b []byte) (*Format, error) {
+	if len(b) < 16 {
+		return nil, errors.New("wave format chunk is too short")
+	}
+	format := &Format{
+		Tag:           FormatTag(binary.LittleEndian.Uint16(b[0:2])),
+		Channels:      binary.LittleEndian.Uint16(b[2:4]),
+		SampleRate:    binary.LittleEndian.Uint32(b[4:8]),
+		ByteRate:      binary.LittleEndian.Uint32(b[8:12]),
+		BlockAlign:    binary.LittleEndian.Uint16(b[12:14]),
+		BitsPerSample: binary.LittleEndian.Uint16(b[14:16]),
+	}
+
+	// WAVE_FORMAT_EXTENSIBLE has 22 bytes of extension
+	if format.Tag == FormatExtensible && len(b) >= 40 && binary.LittleEndian.Uint16(b[16:18]) >= 22 {
+		format.ValidBitsPerSample = binary.LittleEndian.Uint16(b[18:20])
+		format.ChannelMask = binary.LittleEndian.Uint32(b[20:24])
+		copy(format.SubFormat[:], b[24:40])
+	}
+	return format, nil
+}
+
+// parseInfo parses subchunks of LIST/INFO chunk.
+// Values are NUL-terminated strings, which are usually
+// either UTF-8 or Latin-1.
+func parseInfo(b []byte) map[string]string {
+	info := map[string]string{}
+	for len(b) >= ChunkHeaderSize {
+		id := string(b[:4])
+		size := int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[ChunkHeaderSize:]
+		if size > len(b) {
+			size = len(b)
+		}
+		info[id] = decodeText(b[:size])
+		b = b[size:]
+		if size&1 == 1 && len(b) > 0 {
+			b = b[1:]
+		}
+	}
+	return info
+}
+
+func decodeText(b []byte) string {
+	if utf8.Valid(b) {
+		return utils.TrimFixed(string(b))
+	}
+	return utils.TrimFixed(utils.DecodeLatin1(b))
+}
+
+// Apply format properties, LIST/INFO and ID3 tags to the track.
+// ID3 is richer than LIST/INFO, so it overrides the same fields.
+// Broadcast chunks are stored in Track.Extra by their chunk identifiers,
+// and only fill fields, which are still empty.
+func (file *File) Apply(t *metadata.Track) {
+	format := file.Format
+	t.Properties = metadata.Properties{
+		Codec:         format.Codec().String(),
+		SampleRate:    format.SampleRate,
+		Channels:      uint8(format.Channels),
+		BitsPerSample: uint8(format.BitsPerSample),
+		Bitrate:       format.ByteRate * 8,
+	}
+	if format.ValidBitsPerSample != 0 {
+		t.Properties.BitsPerSample = uint8(format.ValidBitsPerSample)
+	}
+
+	t.Duration = -1
+	switch format.Codec() {
+	case FormatPCM, FormatIEEEFloat, FormatALaw, FormatMULaw:
+		if format.BlockAlign != 0 {
+			t.Properties.TotalSamples = uint64(file.DataSize) / uint64(format.BlockAlign)
+			t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, format.SampleRate)
+		}
+	default:
+		// Compressed formats have no bit depth, but have a constant byte rate
+		t.Properties.BitsPerSample = 0
+		if format.ByteRate != 0 {
+			t.Duration = metadata.SamplesDuration(uint64(file.DataSize), format.ByteRate)
+		}
+	}
+
+	if len(file.Info) != 0 {
+		comments := map[string]string{}
+		for id, value := range file.Info {
+			if key, ok := infoKeys[id]; ok && value != "" {
+				comments[key] = value
+			}
+		}
+		comment := &flac.VorbisComment{Comments: comments}
+		comment.Apply(t)
+	}
+
+	if file.ID3 != nil {
+		file.ID3.Apply(t)
+	}
+
+	if bext := file.Broadcast; bext != nil {
+		t.SetExtra("bext", bext)
+		utils.Fill(&t.Description, bext.Description)
+		utils.Fill(&t.Date, bext.OriginationDate)
+	}
+	if cart := file.Cart; cart != nil {
+		t.SetExtra("cart", cart)
+		utils.Fill(&t.Title, cart.Title)
+		utils.Fill(&t.Artist, cart.Artist)
+	}
+	if file.IXML != nil {
+		t.SetExtra("iXML", file.IXML)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wav
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// IXML is a content of iXML chunk, used by field recorders
+// for production metadata.
+// Only commonly used elements are parsed, Raw keeps the whole document.
+//
+// ref: http://www.gallery.co.uk/ixml/
+type IXML struct {
+	XMLName xml.Name    `xml:"BWFXML"`
+	Version string      `xml:"IXML_VERSION"`
+	Project string      `xml:"PROJECT"`
+	Scene   string      `xml:"SCENE"`
+	Take    string      `xml:"TAKE"`
+	Tape    string      `xml:"TAPE"`
+	Circled string      `xml:"CIRCLED"`
+	FileUID string      `xml:"FILE_UID"`
+	UBits   string      `xml:"UBITS"`
+	Note    string      `xml:"NOTE"`
+	Speed   IXMLSpeed   `xml:"SPEED"`
+	Tracks  []IXMLTrack `xml:"TRACK_LIST>TRACK"`
+	Raw     []byte      `xml:"-"`
+}
+
+// IXMLSpeed describes timecode and sample rates of the recording.
+type IXMLSpeed struct {
+	Note           string `xml:"NOTE"`
+	MasterSpeed    string `xml:"MASTER_SPEED"`
+	CurrentSpeed   string `xml:"CURRENT_SPEED"`
+	TimecodeRate   string `xml:"TIMECODE_RATE"`
+	TimecodeFlag   string `xml:"TIMECODE_FLAG"`
+	FileSampleRate string `xml:"FILE_SAMPLE_RATE"`
+	AudioBitDepth  string `xml:"AUDIO_BIT_DEPTH"`
+	DigitizerRate  string `xml:"DIGITIZER_SAMPLE_RATE"`
+	TimestampLo    string `xml:"TIMESTAMP_SAMPLES_SINCE_MIDNIGHT_LO"`
+	TimestampHi    string `xml:"TIMESTAMP_SAMPLES_SINCE_MIDNIGHT_HI"`
+	TimestampRate  string `xml:"TIMESTAMP_SAMPLE_RATE"`
+}
+
+// IXMLTrack describes a channel of the recording.
+type IXMLTrack struct {
+	ChannelIndex  string `xml:"CHANNEL_INDEX"`
+	InterleaveIdx string `xml:"INTERLEAVE_INDEX"`
+	Name          string `xml:"NAME"`
+	Function      string `xml:"FUNCTION"`
+}
+
+// IsCircled reports whether the take was marked as a good one.
+func (ixml *IXML) IsCircled() bool {
+	return ixml.Circled == "TRUE"
+}
+
+// parseIXML parses iXML chunk. Documents, which are not valid XML,
+// are kept as Raw only, so they are still preserved on write.
+func parseIXML(b []byte) *IXML {
+	raw := append([]byte(nil), bytes.TrimRight(b, "\x00")...)
+	ixml := &IXML{}
+	if err := xml.Unmarshal(raw, ixml); err != nil {
+		ixml = &IXML{}
+	}
+	ixml.Raw = raw
+	return ixml
+}
+
+// Bytes returns iXML chunk data.
+// The original document is preserved as is.
+func (ixml *IXML) Bytes() []byte {
+	return ixml.Raw
+}
@@ -0,0 +1,151 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// Form is a variant of WAVE file container.
+type Form uint8
+
+const (
+	// FormRIFF is a classic RIFF WAVE file with 32-bit sizes.
+	FormRIFF Form = iota
+	// FormRF64 is EBU RF64 with 64-bit sizes in ds64 chunk.
+	FormRF64
+	// FormBW64 is ITU-R BS.2088 BW64, which is RF64 with another identifier.
+	FormBW64
+	// FormWave64 is Sony Wave64 with GUID chunk identifiers and 64-bit sizes.
+	FormWave64
+)
+
+func (form Form) String() string {
+	switch form {
+	case FormRIFF:
+		return "RIFF"
+	case FormRF64:
+		return "RF64"
+	case FormBW64:
+		return "BW64"
+	case FormWave64:
+		return "Wave64"
+	}
+	return "unknown"
+}
+
+// sizePlaceholder replaces 32-bit chunk sizes, which are stored in ds64 chunk.
+const sizePlaceholder = 0xFFFFFFFF
+
+// DataSize64 is a content of ds64 chunk of RF64 and BW64 files.
+//
+// ref: https://tech.ebu.ch/docs/tech/tech3306v1_1.pdf
+type DataSize64 struct {
+	RIFFSize    uint64
+	DataSize    uint64
+	SampleCount uint64
+	// Table holds 64-bit sizes of other chunks by their identifiers.
+	Table map[string]uint64
+}
+
+// parseDataSize64 parses ds64 chunk.
+func parseDataSize64(b []byte) (*DataSize64, error) {
+	if len(b) < 28 {
+		return nil, errors.New("ds64 chunk is too short")
+	}
+	ds64 := &DataSize64{
+		RIFFSize:    binary.LittleEndian.Uint64(b[0:8]),
+		DataSize:    binary.LittleEndian.Uint64(b[8:16]),
+		SampleCount: binary.LittleEndian.Uint64(b[16:24]),
+		Table:       map[string]uint64{},
+	}
+	entries := int(binary.LittleEndian.Uint32(b[24:28]))
+	b = b[28:]
+	for i := 0; i < entries && len(b) >= 12; i++ {
+		ds64.Table[string(b[:4])] = binary.LittleEndian.Uint64(b[4:12])
+		b = b[12:]
+	}
+	return ds64, nil
+}
+
+// chunkSize returns 64-bit size of the chunk with size placeholder.
+func (ds64 *DataSize64) chunkSize(id string) (uint64, bool) {
+	if id == "data" {
+		return ds64.DataSize, true
+	}
+	size, ok := ds64.Table[id]
+	return size, ok
+}
+
+// Wave64 chunk header is a GUID and 64-bit size, which includes the header.
+const wave64HeaderSize = 24
+
+var (
+	wave64RIFF = []byte{'r', 'i', 'f', 'f', 0x2E, 0x91, 0xCF, 0x11, 0xA5, 0xD6, 0x28, 0xDB, 0x04, 0xC1, 0x00, 0x00}
+	wave64LIST = []byte{'l', 'i', 's', 't', 0x2F, 0x91, 0xCF, 0x11, 0xA5, 0xD6, 0x28, 0xDB, 0x04, 0xC1, 0x00, 0x00}
+	wave64WAVE = []byte{'w', 'a', 'v', 'e', 0xF3, 0xAC, 0xD3, 0x11, 0x8C, 0xD1, 0x00, 0xC0, 0x4F, 0x8E, 0xDB, 0x8A}
+	// wave64Suffix is shared by GUIDs of chunks,
+	// which start with a RIFF chunk identifier, e.g. "fmt " or "data"
+	wave64Suffix = wave64WAVE[4:]
+)
+
+// wave64ChunkID converts Wave64 GUID to RIFF chunk identifier.
+// Unknown GUIDs are returned as hexadecimal strings.
+func wave64ChunkID(guid []byte) string {
+	switch {
+	case bytes.Equal(guid, wave64LIST):
+		return "LIST"
+	case bytes.Equal(guid[4:], wave64Suffix):
+		return string(guid[:4])
+	}
+	return string(guid)
+}
+
+// wave64GUID converts RIFF chunk identifier back to Wave64 GUID.
+func wave64GUID(id string) []byte {
+	switch {
+	case id == "LIST":
+		return wave64LIST
+	case len(id) == 4:
+		return append([]byte(id), wave64Suffix...)
+	}
+	return []byte(id)
+}
+
+// readWave64ChunkHeader reads Wave64 chunk header at given offset.
+func readWave64ChunkHeader(f io.ReadSeeker, offset, end int64) (*Chunk, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to chunk", err)
+	}
+	var b [wave64HeaderSize]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return nil, errors.Wrap("could not read chunk header", err)
+	}
+	size := int64(binary.LittleEndian.Uint64(b[16:24]))
+	if size < wave64HeaderSize {
+		return nil, errors.New("invalid wave64 chunk size")
+	}
+	chunk := &Chunk{
+		ID:         wave64ChunkID(b[:16]),
+		Offset:     offset + wave64HeaderSize,
+		Size:       size - wave64HeaderSize,
+		HeaderSize: wave64HeaderSize,
+	}
+	if chunk.Offset+chunk.Size > end {
+		chunk.Size = end - chunk.Offset
+	}
+	return chunk, nil
+}
+
+// align8 rounds size up to 8 bytes, as Wave64 chunks are aligned.
+func align8(size int64) int64 {
+	return (size + 7) &^ 7
+}
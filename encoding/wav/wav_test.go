@@ -0,0 +1,407 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mkchunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, ChunkHeaderSize, ChunkHeaderSize+len(body)+1)
+	copy(b, id)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)))
+	b = append(b, body...)
+	if len(body)&1 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func mkriff(form string, chunks ...[]byte) []byte {
+	return mkchunk("RIFF", []byte(form), bytes.Join(chunks, nil))
+}
+
+// extensibleFormat is 24-bit stereo PCM at 48 kHz, stored in 32-bit containers.
+func extensibleFormat() []byte {
+	b := make([]byte, 40)
+	binary.LittleEndian.PutUint16(b[0:], uint16(FormatExtensible))
+	binary.LittleEndian.PutUint16(b[2:], 2)
+	binary.LittleEndian.PutUint32(b[4:], 48000)
+	binary.LittleEndian.PutUint32(b[8:], 48000*8)
+	binary.LittleEndian.PutUint16(b[12:], 8)
+	binary.LittleEndian.PutUint16(b[14:], 32)
+	binary.LittleEndian.PutUint16(b[16:], 22)
+	binary.LittleEndian.PutUint16(b[18:], 24)
+	binary.LittleEndian.PutUint32(b[20:], 3)
+	b[24] = byte(FormatPCM)
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	id3 := []byte("ID3\x03\x00\x00\x00\x00\x00\x12TIT2\x00\x00\x00\x08\x00\x00\x00ID3 Tit")
+	file := mkriff("WAVE",
+		mkchunk("fmt ", extensibleFormat()),
+		mkchunk("LIST", []byte("INFO"),
+			mkchunk("INAM", []byte("Info Title\x00")),
+			mkchunk("IART", []byte("Artist\x00")),
+			mkchunk("ITRK", []byte("5\x00"))),
+		mkchunk("data", make([]byte, 48000*8*2)),
+		mkchunk("id3 ", id3),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if track.Title != "ID3 Tit" {
+		t.Errorf(`expected Title from ID3 to be "ID3 Tit", but got %q`, track.Title)
+	}
+	if track.Artist != "Artist" {
+		t.Errorf(`expected Artist to be "Artist", but got %q`, track.Artist)
+	}
+	if track.TrackNumber != "5" {
+		t.Errorf(`expected TrackNumber to be "5", but got %q`, track.TrackNumber)
+	}
+	if track.Duration != 2*time.Second {
+		t.Errorf("expected duration 2s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "PCM" || p.BitsPerSample != 24 || p.Channels != 2 || p.TotalSamples != 96000 {
+		t.Errorf("expected 96000 samples of 24-bit stereo PCM, but got %+v", p)
+	}
+}
+
+func TestDecodeInvalidID3(t *testing.T) {
+	file := mkriff("WAVE",
+		mkchunk("fmt ", pcmFormat()),
+		mkchunk("LIST", []byte("INFO"), mkchunk("INAM", []byte("Info Title\x00"))),
+		mkchunk("data", make([]byte, 48000*2)),
+		mkchunk("id3 ", []byte("ID3\x09broken")),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Info Title" {
+		t.Errorf(`expected Title to be "Info Title", but got %q`, track.Title)
+	}
+	if track.Duration != time.Second {
+		t.Errorf("expected duration 1s, but got %s", track.Duration)
+	}
+}
+
+func TestDecodeInvalidProductionChunks(t *testing.T) {
+	file := mkriff("WAVE",
+		mkchunk("fmt ", pcmFormat()),
+		mkchunk("bext", []byte("short")),
+		mkchunk("cart", []byte("0101")),
+		mkchunk("LIST", []byte("INFO"), mkchunk("INAM", []byte("Info Title\x00"))),
+		mkchunk("data", make([]byte, 48000*2)),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "Info Title" {
+		t.Errorf(`expected Title to be "Info Title", but got %q`, track.Title)
+	}
+	if track.Duration != time.Second {
+		t.Errorf("expected duration 1s, but got %s", track.Duration)
+	}
+	if _, ok := track.Extra["bext"]; ok {
+		t.Error("expected broken bext chunk to be ignored")
+	}
+}
+
+func pcmFormat() []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint16(b[0:], uint16(FormatPCM))
+	binary.LittleEndian.PutUint16(b[2:], 1)
+	binary.LittleEndian.PutUint32(b[4:], 48000)
+	binary.LittleEndian.PutUint32(b[8:], 48000*2)
+	binary.LittleEndian.PutUint16(b[12:], 2)
+	binary.LittleEndian.PutUint16(b[14:], 16)
+	return b
+}
+
+func TestDecodeBroadcast(t *testing.T) {
+	bext := &BroadcastExtension{
+		Description:     "Morning show",
+		Originator:      "Studio A",
+		OriginationDate: "2019-09-02",
+		OriginationTime: "10:00:00",
+		// 10:00:00 and 12 frames at 25 fps, plus 7 samples
+		TimeReference: 36000*48000 + 12*1920 + 7,
+		Version:       2,
+		LoudnessValue: -2300,
+		CodingHistory: "A=PCM,F=48000,W=16,M=mono\r\n",
+	}
+	cart := &Cart{Version: "0101", Title: "Jingle", CutID: "1234"}
+	cart.PostTimers[0] = CartTimer{Usage: "SEG1", Value: 48000}
+	ixml := []byte("<BWFXML><PROJECT>Doc</PROJECT><SCENE>12</SCENE><TAKE>3</TAKE><CIRCLED>TRUE</CIRCLED>" +
+		"<TRACK_LIST><TRACK><CHANNEL_INDEX>1</CHANNEL_INDEX><NAME>Boom</NAME></TRACK></TRACK_LIST></BWFXML>")
+
+	file := mkriff("WAVE",
+		mkchunk("fmt ", pcmFormat()),
+		mkchunk("bext", bext.Bytes()),
+		mkchunk("cart", cart.Bytes()),
+		mkchunk("iXML", ixml),
+		mkchunk("data", make([]byte, 96000)),
+	)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Description != "Morning show" || track.Title != "Jingle" {
+		t.Errorf("expected description and title from bext and cart, but got %q and %q", track.Description, track.Title)
+	}
+
+	decoded := track.Extra["bext"].(*BroadcastExtension)
+	if *decoded != *bext {
+		t.Errorf("expected bext to survive a round trip, but got %+v", decoded)
+	}
+	if tc := decoded.Timecode(48000, 25).String(); tc != "10:00:00:12+7" {
+		t.Errorf(`expected timecode "10:00:00:12+7", but got %q`, tc)
+	}
+
+	if x := track.Extra["cart"].(*Cart); x.CutID != "1234" || x.PostTimers[0] != cart.PostTimers[0] {
+		t.Errorf("expected cart to survive a round trip, but got %+v", x)
+	}
+
+	decodedIXML := track.Extra["iXML"].(*IXML)
+	if decodedIXML.Scene != "12" || !decodedIXML.IsCircled() || len(decodedIXML.Tracks) != 1 || decodedIXML.Tracks[0].Name != "Boom" {
+		t.Errorf("expected iXML scene 12 with a circled take and a track, but got %+v", decodedIXML)
+	}
+	if !bytes.Equal(decodedIXML.Bytes(), ixml) {
+		t.Error("expected iXML document to be preserved")
+	}
+}
+
+func TestDecodeRF64(t *testing.T) {
+	const dataSize = 48000 * 2
+	ds64 := make([]byte, 28+12)
+	binary.LittleEndian.PutUint64(ds64[8:], dataSize)
+	binary.LittleEndian.PutUint64(ds64[16:], dataSize/2)
+	binary.LittleEndian.PutUint32(ds64[24:], 1)
+	copy(ds64[28:], "junk")
+
+	data := mkchunk("data", make([]byte, dataSize))
+	binary.LittleEndian.PutUint32(data[4:], sizePlaceholder)
+	file := mkriff("WAVE",
+		mkchunk("ds64", ds64),
+		mkchunk("fmt ", pcmFormat()),
+		data,
+		mkchunk("LIST", []byte("INFO"), mkchunk("INAM", []byte("Large\x00"))),
+	)
+	copy(file, "BW64")
+	binary.LittleEndian.PutUint32(file[4:], sizePlaceholder)
+
+	f, err := ReadFile(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if f.Form != FormBW64 || f.DataSize != dataSize || f.DataSize64.SampleCount != dataSize/2 {
+		t.Errorf("expected BW64 with data size from ds64, but got %s with %d", f.Form, f.DataSize)
+	}
+	if _, ok := f.DataSize64.Table["junk"]; !ok {
+		t.Error("expected ds64 table entry for junk chunk")
+	}
+	if f.Info["INAM"] != "Large" {
+		t.Errorf(`expected title "Large" after 64-bit data chunk, but got %q`, f.Info["INAM"])
+	}
+}
+
+func TestDecodeRF64InvalidSize(t *testing.T) {
+	ds64 := make([]byte, 28+12)
+	binary.LittleEndian.PutUint32(ds64[24:], 1)
+	copy(ds64[28:], "junk")
+	binary.LittleEndian.PutUint64(ds64[32:], 0xFFFFFFFFFFFFFFF8)
+
+	junk := mkchunk("junk")
+	binary.LittleEndian.PutUint32(junk[4:], sizePlaceholder)
+	file := mkriff("WAVE",
+		mkchunk("ds64", ds64),
+		mkchunk("fmt ", pcmFormat()),
+		junk,
+		mkchunk("data", make([]byte, 8)),
+	)
+	copy(file, "RF64")
+
+	if _, err := ReadFile(bytes.NewReader(file)); err == nil {
+		t.Error("expected error for negative ds64 chunk size")
+	}
+}
+
+func mkwave64chunk(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	b := make([]byte, wave64HeaderSize, align8(int64(wave64HeaderSize+len(body))))
+	copy(b, wave64GUID(id))
+	binary.LittleEndian.PutUint64(b[16:], uint64(wave64HeaderSize+len(body)))
+	b = append(b, body...)
+	return b[:cap(b)]
+}
+
+func TestDecodeWave64(t *testing.T) {
+	body := bytes.Join([][]byte{
+		wave64WAVE,
+		mkwave64chunk("fmt ", pcmFormat()),
+		mkwave64chunk("data", make([]byte, 48000*2+2)),
+		mkwave64chunk("LIST", []byte("INFO"), mkchunk("IART", []byte("Sony\x00"))),
+	}, nil)
+	file := make([]byte, wave64HeaderSize, wave64HeaderSize+len(body))
+	copy(file, wave64RIFF)
+	binary.LittleEndian.PutUint64(file[16:], uint64(wave64HeaderSize+len(body)))
+	file = append(file, body...)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Artist != "Sony" {
+		t.Errorf(`expected artist to be "Sony", but got %q`, track.Artist)
+	}
+	if track.Properties.TotalSamples != 48001 || track.Properties.SampleRate != 48000 {
+		t.Errorf("expected 48001 samples at 48 kHz, but got %+v", track.Properties)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wav")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.wav")
+
+	bext := &BroadcastExtension{Description: "Take 1", OriginationDate: "2019-09-02", Version: 1}
+	data := mkchunk("data", bytes.Repeat([]byte{1, 2}, 48000))
+	file := mkriff("WAVE",
+		mkchunk("fmt ", pcmFormat()),
+		mkchunk("bext", bext.Bytes()),
+		mkchunk("LIST", []byte("INFO"),
+			mkchunk("INAM", []byte("A rather long title\x00")),
+			mkchunk("ICMT", []byte("A comment, which will be dropped, because the track has none\x00"))),
+		data,
+		mkchunk("smpl", []byte("unknown chunk")),
+	)
+	if err := ioutil.WriteFile(path, file, 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	track, err := Decode(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	track.Title = "Title"
+	track.Description = "Take 2"
+	track.Date = "2020-01-31"
+	track.Comments = map[string]string{"composer": "Composer"}
+
+	tags, err := NewTags(track)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := WriteFile(path, tags, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if i := bytes.Index(b, data); i != bytes.Index(file, data) {
+		t.Errorf("expected data chunk to stay in place, but got offset %d", i)
+	}
+	written, err := ReadFile(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	var ids []string
+	for _, chunk := range written.Chunks {
+		ids = append(ids, chunk.ID)
+	}
+	if x := strings.Join(ids, ","); x != "fmt ,bext,LIST,JUNK,data,smpl,id3 " {
+		t.Errorf("expected chunks to keep their order, but got %s", x)
+	}
+	if written.Info["INAM"] != "Title" || written.Info["IMUS"] != "Composer" || written.Info["ICMT"] != "" {
+		t.Errorf("expected new INFO values, but got %v", written.Info)
+	}
+	if written.Broadcast.Description != "Take 2" || written.Broadcast.OriginationDate != "2020-01-31" {
+		t.Errorf("expected bext to be updated, but got %+v", written.Broadcast)
+	}
+	if len(written.ID3.Frames) == 0 {
+		t.Error("expected id3 chunk to be written")
+	}
+	if size := binary.LittleEndian.Uint32(b[4:]); int(size) != len(b)-8 {
+		t.Errorf("expected riff size %d, but got %d", len(b)-8, size)
+	}
+}
+
+func TestWriteFileJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wav")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "file.wav")
+
+	data := mkchunk("data", bytes.Repeat([]byte{1, 2}, 4800))
+	file := mkriff("WAVE", mkchunk("fmt ", pcmFormat()), data, mkchunk("smpl", []byte("unknown chunk")))
+	// Simulate a write, which was interrupted after the file was truncated
+	end := len(file) - 100
+	if err := ioutil.WriteFile(path, file[:end], 0644); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	var journal []byte
+	for _, n := range []int{len(file), end, 100} {
+		b := make([]byte, 8)
+		binary.LittleEndian.PutUint64(b, uint64(n))
+		journal = append(journal, b...)
+	}
+	journal = append([]byte("AIDJRNL1"), append(journal, file[end:]...)...)
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, crc32.ChecksumIEEE(journal))
+	if err := ioutil.WriteFile(filepath.Join(dir, ".file.wav.journal"), append(journal, b...), 0600); err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	if err := WriteFile(path, &Tags{Info: map[string]string{"INAM": "Title"}}, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	b, err = ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if !bytes.Contains(b, data) || !bytes.Contains(b, mkchunk("smpl", []byte("unknown chunk"))) {
+		t.Error("expected the file to be recovered before it is written")
+	}
+	written, err := ReadFile(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if written.Info["INAM"] != "Title" {
+		t.Errorf(`expected INAM to be "Title", but got %q`, written.Info["INAM"])
+	}
+}
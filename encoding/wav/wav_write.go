@@ -0,0 +1,160 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wav
+
+import (
+	"encoding/binary"
+	"os"
+	"sort"
+
+	"github.com/audioid/audioid/encoding/flac"
+	"github.com/audioid/audioid/encoding/id3v2"
+	"github.com/audioid/audioid/encoding/iff"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+	"github.com/audioid/audioid/utils"
+)
+
+var riffForm = &iff.Form{ID: "RIFF", Type: "WAVE", Order: binary.LittleEndian, Filler: "JUNK"}
+
+// Tags are metadata chunks written by WriteFile.
+// Nil fields keep the chunks of the file as is.
+type Tags struct {
+	// Info holds LIST/INFO values by their identifiers, e.g. "INAM".
+	// Empty Info removes LIST/INFO chunk.
+	Info map[string]string
+	// ID3 is written as "id3 " chunk.
+	ID3 *id3v2.Tag
+	// Broadcast is written as bext chunk.
+	Broadcast *BroadcastExtension
+}
+
+// infoID returns LIST/INFO identifier of given Vorbis comment key,
+// reversing infoKeys.
+func infoID(key string) (string, bool) {
+	for id, k := range infoKeys {
+		if k == key && id != "IPRT" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// NewTags converts the track into LIST/INFO and ID3v2.3 tag.
+// Fields without INFO identifiers are only written to ID3.
+// bext chunk is only written, if the track was read with it,
+// with description and origination date of the track.
+func NewTags(t *metadata.Track) (*Tags, error) {
+	tags := &Tags{Info: map[string]string{}}
+	for key, value := range flac.TrackComments(t) {
+		if id, ok := infoID(key); ok {
+			tags.Info[id] = value
+		}
+	}
+
+	var err error
+	tags.ID3, err = id3v2.NewTag(t, 3, id3v2.EncodingLatin1)
+	if err != nil {
+		return nil, err
+	}
+
+	if bext, ok := t.Extra["bext"].(*BroadcastExtension); ok {
+		b := *bext
+		b.Description = t.Description
+		if len(t.Date) >= 10 && t.Date[4] == '-' && t.Date[7] == '-' {
+			b.OriginationDate = t.Date[:10]
+		}
+		tags.Broadcast = &b
+	}
+	return tags, nil
+}
+
+// WriteFile replaces LIST/INFO, "id3 " and bext chunks of RIFF WAVE file.
+// The first chunk of each kind is replaced in place and duplicates are dropped,
+// missing chunks are appended. Other chunks keep their order.
+// Data chunk is not rewritten, unless preceding chunks grow, see iff.WriteFile.
+// RF64, BW64 and Wave64 files are not supported. opts may be nil.
+func WriteFile(path string, tags *Tags, opts *utils.WriteOptions) error {
+	// The chunks are read before iff.WriteFile, so the file is recovered first
+	if err := utils.RecoverFile(path); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	defer f.Close()
+	file, err := ReadFile(f)
+	if err != nil {
+		return err
+	}
+	if file.Form != FormRIFF {
+		return errors.New("only riff wave files can be written")
+	}
+
+	updates := map[string]*iff.Chunk{}
+	if tags.Info != nil {
+		updates["INFO"] = nil
+		if len(tags.Info) != 0 {
+			updates["INFO"] = iff.New("LIST", encodeInfo(tags.Info))
+		}
+	}
+	if tags.ID3 != nil {
+		b, err := tags.ID3.Encode(0)
+		if err != nil {
+			return err
+		}
+		updates["id3 "] = iff.New("id3 ", b)
+	}
+	if tags.Broadcast != nil {
+		updates["bext"] = iff.New("bext", tags.Broadcast.Bytes())
+	}
+
+	chunks := make([]*iff.Chunk, len(file.Chunks))
+	kinds := make([]string, len(file.Chunks))
+	for i, c := range file.Chunks {
+		chunks[i] = &iff.Chunk{ID: c.ID, Offset: c.Offset, Size: c.Size}
+		switch c.ID {
+		case "LIST":
+			var b [4]byte
+			if _, err := f.ReadAt(b[:], c.Offset); err == nil && string(b[:]) == "INFO" {
+				kinds[i] = "INFO"
+			}
+		case "id3 ", "ID3 ":
+			kinds[i] = "id3 "
+		case "bext":
+			kinds[i] = "bext"
+		}
+	}
+	f.Close()
+
+	chunks = iff.Replace(chunks, kinds, []string{"bext", "INFO", "id3 "}, updates)
+	return iff.WriteFile(path, riffForm, chunks, "data", opts)
+}
+
+// encodeInfo serializes LIST/INFO chunk data.
+// Values are written as NUL-terminated strings.
+func encodeInfo(info map[string]string) []byte {
+	ids := make([]string, 0, len(info))
+	for id := range info {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	b := []byte("INFO")
+	for _, id := range ids {
+		value := append([]byte(info[id]), 0)
+		var h [ChunkHeaderSize]byte
+		copy(h[:], id)
+		binary.LittleEndian.PutUint32(h[4:], uint32(len(value)))
+		b = append(append(b, h[:]...), value...)
+		if len(value)&1 == 1 {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
@@ -0,0 +1,139 @@
+// Package wavpack implements WavPack block headers and metadata.
+//
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+package wavpack
+
+import (
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/errors"
+)
+
+// BlockHeaderSize is the size of WavPack block header.
+const BlockHeaderSize = 32
+
+var (
+	// ErrNotWavPack is returned when file does not start with WavPack block.
+	ErrNotWavPack = errors.New("not a wavpack file")
+)
+
+// Flags of WavPack block header.
+//
+// ref: https://www.wavpack.com/WavPack5FileFormat.pdf
+const (
+	FlagBytesStored   = 0x3
+	FlagMono          = 0x4
+	FlagHybrid        = 0x8
+	FlagJointStereo   = 0x10
+	FlagCrossDecorr   = 0x20
+	FlagHybridShape   = 0x40
+	FlagFloatData     = 0x80
+	FlagInt32Data     = 0x100
+	FlagHybridBitrate = 0x200
+	FlagHybridBalance = 0x400
+	FlagInitialBlock  = 0x800
+	FlagFinalBlock    = 0x1000
+	FlagFalseStereo   = 0x40000000
+	FlagDSD           = 0x80000000
+
+	shiftMask      = 0x3E000
+	shiftLSB       = 13
+	sampleRateMask = 0x7800000
+	sampleRateLSB  = 23
+)
+
+// Identifiers of metadata sub-blocks. Only the lower 6 bits identify
+// the sub-block, the upper bits describe its size.
+const (
+	IDEncoderInfo = 0x01
+	IDChannelInfo = 0x0D
+	IDDSDBlock    = 0x0E
+	IDRIFFHeader  = 0x21
+	IDRIFFTrailer = 0x22
+	IDConfigBlock = 0x25
+	IDMD5Checksum = 0x26
+	IDSampleRate  = 0x27
+
+	idUniqueMask = 0x3F
+	idOddSize    = 0x40
+	idLarge      = 0x80
+)
+
+// sampleRates are indexed by bits 23-26 of block flags.
+// Index 15 means the rate is stored in IDSampleRate sub-block.
+var sampleRates = [...]uint32{
+	6000, 8000, 9600, 11025, 12000, 16000, 22050,
+	24000, 32000, 44100, 48000, 64000, 88200, 96000, 192000,
+}
+
+// BlockHeader is a header of WavPack block.
+type BlockHeader struct {
+	// Size of the block without the first 8 bytes.
+	Size    uint32
+	Version uint16
+	// TotalSamples is the number of samples in the file,
+	// or -1 if unknown.
+	TotalSamples int64
+	BlockIndex   int64
+	BlockSamples uint32
+	Flags        uint32
+	CRC          uint32
+}
+
+// SubBlock is a metadata sub-block of WavPack block.
+type SubBlock struct {
+	ID   uint8
+	Data []byte
+}
+
+// File describes the parts of WavPack file relevant to metadata.
+type File struct {
+	// Header of the first block.
+	Header     *BlockHeader
+	SampleRate uint32
+	Channels   uint8
+	// BitsPerSample is 1 for DSD audio.
+	BitsPerSample uint8
+	// TotalSamples is the number of inter-channel samples,
+	// or -1 if unknown.
+	TotalSamples int64
+	// MD5 is a checksum of the original audio data,
+	// empty if the encoder has not stored it.
+	MD5 []byte
+	// AudioSize is the size of all blocks without tags.
+	AudioSize int64
+	APE       *apetag.Tag
+}
+
+// BytesPerSample is the number of bytes of stored samples.
+func (h *BlockHeader) BytesPerSample() int {
+	return int(h.Flags&FlagBytesStored) + 1
+}
+
+// IsHybrid reports whether the block is encoded in hybrid mode,
+// which is lossy unless accompanied by a correction file.
+func (h *BlockHeader) IsHybrid() bool {
+	return h.Flags&FlagHybrid != 0
+}
+
+// IsLossless reports whether the block alone decodes to the original audio.
+func (h *BlockHeader) IsLossless() bool {
+	return !h.IsHybrid()
+}
+
+// IsDSD reports whether the block contains DSD audio.
+func (h *BlockHeader) IsDSD() bool {
+	return h.Flags&FlagDSD != 0
+}
+
+// SampleRate of the block, or 0 if it is stored in a sub-block.
+func (h *BlockHeader) SampleRate() uint32 {
+	index := (h.Flags & sampleRateMask) >> sampleRateLSB
+	if int(index) < len(sampleRates) {
+		return sampleRates[index]
+	}
+	return 0
+}
@@ -0,0 +1,255 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wavpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/audioid/audioid/encoding/apetag"
+	"github.com/audioid/audioid/encoding/id3v1"
+	"github.com/audioid/audioid/errors"
+	"github.com/audioid/audioid/metadata"
+)
+
+// maxBlockSize limits the size of blocks, which are read into memory.
+// WavPack itself never writes blocks larger than 1 MB.
+const maxBlockSize = 16 << 20
+
+// Decode reads WavPack stream properties, APE and ID3v1 tags
+// into *metadata.Track. f must be positioned at the start of the file.
+func Decode(f io.ReadSeeker) (*metadata.Track, error) {
+	file, err := ReadFile(f)
+	if err != nil {
+		return nil, errors.Wrap("could not decode wavpack", err)
+	}
+
+	t := &metadata.Track{}
+	file.Apply(t)
+
+	tag, err := id3v1.Read(f)
+	if err == nil {
+		tag.Apply(t)
+	} else if err != id3v1.ErrNoTag {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ReadFile reads the first frame of WavPack file and its APE tag.
+// f must be positioned at the start of the file.
+func ReadFile(f io.ReadSeeker) (*File, error) {
+	start, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap("could not get current position", err)
+	}
+
+	header, subBlocks, err := readBlock(f)
+	if err == ErrNotWavPack {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.Wrap("could not read the first block", err)
+	}
+
+	file := &File{
+		Header:        header,
+		SampleRate:    header.SampleRate(),
+		BitsPerSample: uint8(header.BytesPerSample()*8) - uint8((header.Flags&shiftMask)>>shiftLSB),
+		TotalSamples:  header.TotalSamples,
+	}
+	if header.Flags&FlagFloatData != 0 {
+		file.BitsPerSample = 32
+	}
+
+	var dsdMultiplier uint32
+	for _, sub := range subBlocks {
+		switch sub.ID {
+		case IDSampleRate:
+			if len(sub.Data) >= 3 {
+				file.SampleRate = uint32(sub.Data[0]) | uint32(sub.Data[1])<<8 | uint32(sub.Data[2])<<16
+				if len(sub.Data) >= 4 {
+					file.SampleRate |= uint32(sub.Data[3]) << 24
+				}
+			}
+		case IDChannelInfo:
+			if len(sub.Data) >= 1 {
+				file.Channels = sub.Data[0]
+			}
+		case IDMD5Checksum:
+			if len(sub.Data) >= 16 {
+				file.MD5 = sub.Data[:16]
+			}
+		case IDDSDBlock:
+			if len(sub.Data) >= 1 && sub.Data[0] < 32 {
+				dsdMultiplier = 1 << sub.Data[0]
+			}
+		}
+	}
+
+	// Multichannel audio is stored in several blocks of the same frame
+	if file.Channels == 0 {
+		if err := file.countChannels(f, header); err != nil {
+			return nil, err
+		}
+	}
+
+	// DSD audio is stored as bytes of 8 samples
+	if header.IsDSD() {
+		file.BitsPerSample = 1
+		if dsdMultiplier != 0 {
+			file.SampleRate *= dsdMultiplier
+			if file.TotalSamples > 0 {
+				file.TotalSamples *= int64(dsdMultiplier)
+			}
+		}
+	}
+
+	ape, end, err := apetag.ReadTrailing(f)
+	if err != nil {
+		return nil, err
+	}
+	file.APE = ape
+	file.AudioSize = end - start
+	return file, nil
+}
+
+// countChannels walks blocks of the first frame up to its final block.
+// Each block holds either one or two channels.
+func (file *File) countChannels(f io.ReadSeeker, header *BlockHeader) error {
+	for {
+		if header.Flags&FlagMono != 0 {
+			file.Channels++
+		} else {
+			file.Channels += 2
+		}
+		if header.Flags&FlagFinalBlock != 0 {
+			return nil
+		}
+
+		var err error
+		header, err = ReadBlockHeader(f)
+		if err != nil {
+			return errors.Wrap("could not read block of the first frame", err)
+		}
+		if _, err := f.Seek(int64(header.Size)+8-BlockHeaderSize, io.SeekCurrent); err != nil {
+			return errors.Wrap("could not skip block", err)
+		}
+	}
+}
+
+// ReadBlockHeader reads WavPack block header at the current position.
+// ErrNotWavPack is returned if there is no block.
+func ReadBlockHeader(r io.Reader) (*BlockHeader, error) {
+	var b [BlockHeaderSize]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotWavPack
+		}
+		return nil, errors.Wrap("could not read block header", err)
+	}
+	return parseBlockHeader(b[:])
+}
+
+func parseBlockHeader(b []byte) (*BlockHeader, error) {
+	if string(b[:4]) != "wvpk" {
+		return nil, ErrNotWavPack
+	}
+	h := &BlockHeader{
+		Size:         binary.LittleEndian.Uint32(b[4:8]),
+		Version:      binary.LittleEndian.Uint16(b[8:10]),
+		BlockIndex:   int64(b[10])<<32 | int64(binary.LittleEndian.Uint32(b[16:20])),
+		BlockSamples: binary.LittleEndian.Uint32(b[20:24]),
+		Flags:        binary.LittleEndian.Uint32(b[24:28]),
+		CRC:          binary.LittleEndian.Uint32(b[28:32]),
+	}
+	if h.Size < BlockHeaderSize-8 || h.Size > maxBlockSize {
+		return nil, errors.New("invalid wavpack block size")
+	}
+
+	// The upper byte of sample count is subtracted,
+	// because 0xFFFFFFFF is reserved for unknown count.
+	total := binary.LittleEndian.Uint32(b[12:16])
+	if total == 0xFFFFFFFF {
+		h.TotalSamples = -1
+	} else {
+		h.TotalSamples = int64(total) + int64(b[11])<<32 - int64(b[11])
+	}
+	return h, nil
+}
+
+// readBlock reads block header and its metadata sub-blocks.
+// Audio sub-blocks are returned too, but are not decoded.
+func readBlock(f io.ReadSeeker) (*BlockHeader, []SubBlock, error) {
+	header, err := ReadBlockHeader(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	b := make([]byte, int(header.Size)+8-BlockHeaderSize)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, nil, errors.Wrap("could not read block", err)
+	}
+	return header, parseSubBlocks(b), nil
+}
+
+// parseSubBlocks parses sub-blocks: identifier, size in 16-bit words,
+// which takes 3 bytes for large sub-blocks, and data.
+func parseSubBlocks(b []byte) []SubBlock {
+	var subBlocks []SubBlock
+	for len(b) >= 2 {
+		id := b[0]
+		size := int(b[1]) * 2
+		b = b[2:]
+		if id&idLarge != 0 {
+			if len(b) < 2 {
+				break
+			}
+			size += (int(b[0]) | int(b[1])<<8) << 9
+			b = b[2:]
+		}
+		if size > len(b) {
+			break
+		}
+		data := b[:size]
+		if id&idOddSize != 0 && size > 0 {
+			data = data[:size-1]
+		}
+		subBlocks = append(subBlocks, SubBlock{ID: id & idUniqueMask, Data: data})
+		b = b[size:]
+	}
+	return subBlocks
+}
+
+// Apply stream properties and APE tag to the track.
+func (file *File) Apply(t *metadata.Track) {
+	t.Properties = metadata.Properties{
+		Codec:         "WavPack",
+		SampleRate:    file.SampleRate,
+		Channels:      file.Channels,
+		BitsPerSample: file.BitsPerSample,
+	}
+
+	t.Duration = -1
+	if file.TotalSamples >= 0 {
+		t.Properties.TotalSamples = uint64(file.TotalSamples)
+		t.Duration = metadata.SamplesDuration(t.Properties.TotalSamples, file.SampleRate)
+		if file.TotalSamples != 0 {
+			t.Properties.Bitrate = uint32(uint64(file.AudioSize) * 8 * uint64(file.SampleRate) / uint64(file.TotalSamples))
+		}
+	}
+
+	if file.MD5 != nil {
+		t.Checksum = metadata.Checksum{
+			Algorithm: metadata.AlgoMD5,
+			Sum:       fmt.Sprintf("%x", file.MD5),
+		}
+	}
+
+	if file.APE != nil {
+		file.APE.Apply(t)
+	}
+}
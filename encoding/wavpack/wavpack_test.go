@@ -0,0 +1,129 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package wavpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func mkblock(total uint32, flags uint32, subBlocks ...[]byte) []byte {
+	body := bytes.Join(subBlocks, nil)
+	b := make([]byte, BlockHeaderSize, BlockHeaderSize+len(body))
+	copy(b, "wvpk")
+	binary.LittleEndian.PutUint32(b[4:], uint32(BlockHeaderSize-8+len(body)))
+	binary.LittleEndian.PutUint16(b[8:], 0x410)
+	binary.LittleEndian.PutUint32(b[12:], total)
+	binary.LittleEndian.PutUint32(b[20:], 4096)
+	binary.LittleEndian.PutUint32(b[24:], flags)
+	return append(b, body...)
+}
+
+func mksub(id byte, data []byte) []byte {
+	size := len(data)
+	if size&1 == 1 {
+		id |= idOddSize
+		data = append(data, 0)
+	}
+	words := (size + 1) / 2
+	if words > 0xFF {
+		return append([]byte{id | idLarge, byte(words), byte(words >> 8), byte(words >> 16)}, data...)
+	}
+	return append([]byte{id, byte(words)}, data...)
+}
+
+// mkape builds APEv2 tag with a footer only.
+func mkape(key, value string) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b, uint32(len(value)))
+	b = append(b, key...)
+	b = append(b, 0)
+	b = append(b, value...)
+	footer := make([]byte, 32)
+	copy(footer, "APETAGEX")
+	binary.LittleEndian.PutUint32(footer[8:], 2000)
+	binary.LittleEndian.PutUint32(footer[12:], uint32(len(b)+32))
+	binary.LittleEndian.PutUint32(footer[16:], 1)
+	return append(b, footer...)
+}
+
+const (
+	rate44100 = 9 << sampleRateLSB
+	rate48000 = 10 << sampleRateLSB
+)
+
+func TestDecode(t *testing.T) {
+	md5 := []byte("0123456789abcdef")
+	audio := bytes.Join([][]byte{
+		mkblock(44100*2, 1|FlagJointStereo|FlagInitialBlock|FlagFinalBlock|rate44100,
+			mksub(IDMD5Checksum, md5),
+			mksub(0x0a, make([]byte, 1000))),
+		mkblock(44100*2, 1|FlagJointStereo|FlagInitialBlock|FlagFinalBlock|rate44100,
+			mksub(0x0a, make([]byte, 1000))),
+	}, nil)
+	file := append(audio, mkape("Title", "WavPack Title")...)
+
+	track, err := Decode(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if track.Title != "WavPack Title" {
+		t.Errorf(`expected Title from APE tag to be "WavPack Title", but got %q`, track.Title)
+	}
+	if track.Duration != 2*time.Second {
+		t.Errorf("expected duration 2s, but got %s", track.Duration)
+	}
+	p := track.Properties
+	if p.Codec != "WavPack" || p.SampleRate != 44100 || p.Channels != 2 || p.BitsPerSample != 16 || p.TotalSamples != 88200 {
+		t.Errorf("expected 88200 samples of 16-bit stereo at 44.1 kHz, but got %+v", p)
+	}
+	if p.Bitrate != uint32(len(audio)*8/2) {
+		t.Errorf("expected bitrate of %d bytes per 2 seconds, but got %d", len(audio), p.Bitrate)
+	}
+	if track.Checksum.Sum != "30313233343536373839616263646566" {
+		t.Errorf("expected checksum from MD5 sub-block, but got %q", track.Checksum.Sum)
+	}
+}
+
+func TestReadFileMultichannel(t *testing.T) {
+	file := bytes.Join([][]byte{
+		mkblock(48000, 2|FlagInitialBlock|rate48000, mksub(0x0a, make([]byte, 10))),
+		mkblock(48000, 2|FlagMono|rate48000, mksub(0x0a, make([]byte, 10))),
+		mkblock(48000, 2|FlagHybrid|FlagFinalBlock|rate48000, mksub(0x0a, make([]byte, 10))),
+	}, nil)
+
+	f, err := ReadFile(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if f.Channels != 5 || f.BitsPerSample != 24 || f.SampleRate != 48000 {
+		t.Errorf("expected 24-bit 5 channels at 48 kHz, but got %+v", f)
+	}
+	if !f.Header.IsLossless() || f.MD5 != nil || f.AudioSize != int64(len(file)) {
+		t.Errorf("expected lossless first block without MD5, but got %+v", f.Header)
+	}
+}
+
+func TestReadFileCustomRate(t *testing.T) {
+	rate := []byte{0x00, 0x77, 0x01}
+	file := mkblock(0xFFFFFFFF, 1|FlagMono|FlagHybrid|FlagInitialBlock|FlagFinalBlock|sampleRateMask,
+		mksub(IDSampleRate, rate), mksub(IDChannelInfo, []byte{1, 4, 0, 0}))
+
+	f, err := ReadFile(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if f.SampleRate != 96000 || f.Channels != 1 || f.TotalSamples != -1 || f.Header.IsLossless() {
+		t.Errorf("expected hybrid mono at 96 kHz of unknown length, but got %+v", f)
+	}
+
+	if _, err := ReadFile(bytes.NewReader([]byte("RIFF"))); err != ErrNotWavPack {
+		t.Errorf("expected ErrNotWavPack, but got %v", err)
+	}
+}
@@ -45,6 +45,25 @@ type Picture struct {
 	IsPictureLink bool
 }
 
+// Properties holds technical information about the audio stream.
+// Zero value of any field means it is unknown.
+type Properties struct {
+	// Codec is a short codec name, e.g. "FLAC" or "MP3".
+	Codec string `json:"codec,omitempty"`
+	// SampleRate in Hz.
+	SampleRate uint32 `json:"sampleRate,omitempty"`
+	// Channels is the number of channels.
+	Channels uint8 `json:"channels,omitempty"`
+	// BitsPerSample is the bit depth of decoded audio.
+	// Lossy codecs have no bit depth, so it stays zero for them.
+	BitsPerSample uint8 `json:"bitsPerSample,omitempty"`
+	// Bitrate in bits per second.
+	// For variable bitrate streams it is the average bitrate.
+	Bitrate uint32 `json:"bitrate,omitempty"`
+	// TotalSamples is the number of inter-channel samples.
+	TotalSamples uint64 `json:"totalSamples,omitempty"`
+}
+
 // Track holds basic track information
 //
 // ref: https://www.xiph.org/vorbis/doc/v-comment.html
@@ -100,10 +119,23 @@ type Track struct {
 	Duration time.Duration `json:"duration,omitempty"`
 	// Checksum is the checksum of contents
 	Checksum Checksum
+	// Properties of the audio stream
+	Properties Properties `json:"properties"`
+	// Extra holds format-specific structures, which have no dedicated
+	// fields in Track, keyed by their names, e.g. "bext" of Broadcast WAV.
+	Extra map[string]interface{} `json:"extra,omitempty"`
 
 	Pictures []Picture
 }
 
+// SetExtra stores format-specific structure under given key.
+func (t *Track) SetExtra(key string, value interface{}) {
+	if t.Extra == nil {
+		t.Extra = map[string]interface{}{}
+	}
+	t.Extra[key] = value
+}
+
 // ParseDate for getting date in time format.
 func (t *Track) ParseDate() (*time.Time, error) {
 	date, err := time.Parse("2006", t.Date)
@@ -143,3 +175,14 @@ func (t *Track) TrackNum() int {
 
 	return trackNumber
 }
+
+// SamplesDuration returns duration of given number of samples
+// played at given sample rate. Zero sample rate implies unknown duration.
+func SamplesDuration(samples uint64, sampleRate uint32) time.Duration {
+	if sampleRate == 0 {
+		return -1
+	}
+	rate := uint64(sampleRate)
+	return time.Duration(samples/rate)*time.Second +
+		time.Duration(samples%rate)*time.Second/time.Duration(rate)
+}
@@ -0,0 +1,383 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package utils
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// Backup is a kind of backup of the original file, which writers keep.
+type Backup uint8
+
+const (
+	// NoBackup keeps no copy of the original file.
+	NoBackup Backup = iota
+	// SuffixBackup keeps the original file next to it with ".bak" suffix,
+	// replacing the backup of the previous write.
+	SuffixBackup
+	// ContentBackup keeps the original file in BackupDir, named by SHA-256
+	// of its content and the extension of the file, so every version is kept
+	// and identical versions are stored once.
+	ContentBackup
+)
+
+// WriteOptions are options of ReplaceFile and PatchFile.
+// nil options keep no backup.
+type WriteOptions struct {
+	Backup Backup
+	// BackupDir is the directory of content-addressed backups.
+	// The directory of the file is used, if it is empty.
+	BackupDir string
+}
+
+// journalMagic starts journals of PatchFile.
+const journalMagic = "AIDJRNL1"
+
+// ReplaceFile writes a new version of the file at path into a temporary file
+// in the same directory, and renames it over the original after it is synced,
+// so the file is either the original or the new version if the process dies.
+// The original is read by write, e.g. to copy audio data, and stays intact
+// if anything fails. Permissions, owner and modification time
+// of the original are kept. Callers recover the file with RecoverFile,
+// before they read it to build the new version.
+func ReplaceFile(path string, opts *WriteOptions, write func(w io.Writer, original *os.File) error) (err error) {
+	original, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	defer original.Close()
+	info, err := original.Stat()
+	if err != nil {
+		return errors.Wrap("could not stat file", err)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap("could not create temporary file", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp, original); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		return errors.Wrap("could not set file mode", err)
+	}
+	if err := chown(tmp, info); err != nil {
+		return errors.Wrap("could not set file owner", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Wrap("could not sync temporary file", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap("could not close temporary file", err)
+	}
+	if err := os.Chtimes(tmp.Name(), time.Now(), info.ModTime()); err != nil {
+		return errors.Wrap("could not set file time", err)
+	}
+	// The original inode is not modified, so it may be linked as backup
+	if err := backup(path, original, info, opts, true); err != nil {
+		return err
+	}
+
+	// The original may not be replaced while open on some systems
+	original.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap("could not replace file", err)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// Patch is data, which PatchFile writes at Offset of the file.
+type Patch struct {
+	Offset int64
+	Data   []byte
+}
+
+// PatchFile writes patches into the file at path in place, then truncates
+// or extends it to size, unless size is negative. Before the file is modified,
+// original bytes of patched and truncated regions are saved into a journal
+// next to the file, so RecoverFile restores the original, if the process dies
+// before all patches are written. Modification time of the file is kept.
+// As with ReplaceFile, callers recover the file before they read it.
+func PatchFile(path string, size int64, patches []Patch, opts *WriteOptions) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrap("could not stat file", err)
+	}
+	if err := backup(path, f, info, opts, false); err != nil {
+		return err
+	}
+
+	if err := writeJournal(path, f, info.Size(), size, patches); err != nil {
+		return err
+	}
+	if err := applyPatches(f, size, patches); err != nil {
+		f.Close()
+		RecoverFile(path)
+		return err
+	}
+
+	// The file is complete, so the journal is removed before anything else can fail
+	if err := os.Remove(journalPath(path)); err != nil {
+		// An empty journal is discarded by RecoverFile, not rolled back
+		os.Truncate(journalPath(path), 0)
+		return errors.Wrap("could not remove journal", err)
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	// The contents are written, so a file time, which can't be kept, is not an error
+	chtimes(path, time.Now(), info.ModTime())
+	return nil
+}
+
+// chtimes is replaced in tests to simulate failures.
+var chtimes = os.Chtimes
+
+// applyPatches writes patches into f, truncates it to size,
+// unless size is negative, and syncs it.
+func applyPatches(f *os.File, size int64, patches []Patch) error {
+	for _, p := range patches {
+		if _, err := f.WriteAt(p.Data, p.Offset); err != nil {
+			return errors.Wrap("could not write file", err)
+		}
+	}
+	if size >= 0 {
+		if err := f.Truncate(size); err != nil {
+			return errors.Wrap("could not truncate file", err)
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Wrap("could not sync file", err)
+	}
+	return nil
+}
+
+// RecoverFile rolls back PatchFile, which was interrupted, by its journal.
+// A journal, which was not completely written, is removed, because
+// the file is only modified after the journal is synced.
+// It does nothing, if the file has no journal.
+func RecoverFile(path string) error {
+	b, err := ioutil.ReadFile(journalPath(path))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap("could not read journal", err)
+	}
+
+	if n := len(b) - 4; n >= len(journalMagic)+8 && string(b[:len(journalMagic)]) == journalMagic &&
+		crc32.ChecksumIEEE(b[:n]) == binary.LittleEndian.Uint32(b[n:]) {
+		if err := rollback(path, b[len(journalMagic):n]); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(journalPath(path)); err != nil {
+		return errors.Wrap("could not remove journal", err)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// rollback restores original bytes of journal entries:
+// original size of the file followed by offsets, sizes and bytes.
+func rollback(path string, b []byte) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrap("could not open file", err)
+	}
+	defer f.Close()
+
+	size := int64(binary.LittleEndian.Uint64(b))
+	for b = b[8:]; len(b) >= 16; {
+		offset := int64(binary.LittleEndian.Uint64(b))
+		n := binary.LittleEndian.Uint64(b[8:])
+		b = b[16:]
+		if n > uint64(len(b)) {
+			return errors.New("invalid journal entry")
+		}
+		if _, err := f.WriteAt(b[:n], offset); err != nil {
+			return errors.Wrap("could not restore file", err)
+		}
+		b = b[n:]
+	}
+	if err := f.Truncate(size); err != nil {
+		return errors.Wrap("could not restore file size", err)
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Wrap("could not sync file", err)
+	}
+	return nil
+}
+
+// writeJournal saves original bytes of regions of f,
+// which patches and truncation to size overwrite, and syncs the journal.
+func writeJournal(path string, f *os.File, original, size int64, patches []Patch) (err error) {
+	j, err := os.OpenFile(journalPath(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap("could not create journal", err)
+	}
+	defer func() {
+		if err != nil {
+			j.Close()
+			os.Remove(j.Name())
+		}
+	}()
+
+	bw := bufio.NewWriter(j)
+	crc := crc32.NewIEEE()
+	w := io.MultiWriter(bw, crc)
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(original))
+	if _, err := w.Write(append([]byte(journalMagic), b[:8]...)); err != nil {
+		return errors.Wrap("could not write journal", err)
+	}
+
+	save := func(offset, n int64) error {
+		if offset >= original || n <= 0 {
+			return nil
+		}
+		if offset+n > original {
+			n = original - offset
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(offset))
+		binary.LittleEndian.PutUint64(b[8:], uint64(n))
+		if _, err := w.Write(b[:]); err != nil {
+			return errors.Wrap("could not write journal", err)
+		}
+		if _, err := io.CopyN(w, io.NewSectionReader(f, offset, n), n); err != nil {
+			return errors.Wrap("could not write journal", err)
+		}
+		return nil
+	}
+	for _, p := range patches {
+		if err := save(p.Offset, int64(len(p.Data))); err != nil {
+			return err
+		}
+	}
+	if size >= 0 {
+		if err := save(size, original-size); err != nil {
+			return err
+		}
+	}
+
+	binary.LittleEndian.PutUint32(b[:], crc.Sum32())
+	if _, err := bw.Write(b[:4]); err != nil {
+		return errors.Wrap("could not write journal", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return errors.Wrap("could not write journal", err)
+	}
+	if err := j.Sync(); err != nil {
+		return errors.Wrap("could not sync journal", err)
+	}
+	if err := j.Close(); err != nil {
+		return errors.Wrap("could not close journal", err)
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+func journalPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".journal")
+}
+
+// backup keeps the original file f at path according to opts.
+// If link is set, the original is not modified afterwards,
+// so the backup is a hard link to it, when the file system supports them.
+func backup(path string, f *os.File, info os.FileInfo, opts *WriteOptions, link bool) error {
+	if opts == nil || opts.Backup == NoBackup {
+		return nil
+	}
+
+	var name string
+	switch opts.Backup {
+	case SuffixBackup:
+		name = path + ".bak"
+	case ContentBackup:
+		h := sha256.New()
+		if _, err := io.Copy(h, io.NewSectionReader(f, 0, info.Size())); err != nil {
+			return errors.Wrap("could not hash file", err)
+		}
+		dir := opts.BackupDir
+		if dir == "" {
+			dir = filepath.Dir(path)
+		}
+		name = filepath.Join(dir, hex.EncodeToString(h.Sum(nil))+filepath.Ext(path))
+		// The same content is already kept
+		if _, err := os.Stat(name); err == nil {
+			return nil
+		}
+	default:
+		return errors.New("unknown backup kind")
+	}
+
+	if link {
+		os.Remove(name)
+		if err := os.Link(path, name); err == nil {
+			return syncDir(filepath.Dir(name))
+		}
+	}
+	return copyFile(name, f, info)
+}
+
+// copyFile copies f into a new file at path, which replaces
+// an existing one only after it is completely written.
+func copyFile(path string, f *os.File, info os.FileInfo) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap("could not create backup", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := io.Copy(tmp, io.NewSectionReader(f, 0, info.Size())); err != nil {
+		return errors.Wrap("could not write backup", err)
+	}
+	if err := tmp.Chmod(info.Mode()); err != nil {
+		return errors.Wrap("could not set backup mode", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Wrap("could not sync backup", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap("could not close backup", err)
+	}
+	if err := os.Chtimes(tmp.Name(), time.Now(), info.ModTime()); err != nil {
+		return errors.Wrap("could not set backup time", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap("could not rename backup", err)
+	}
+	return syncDir(filepath.Dir(path))
+}
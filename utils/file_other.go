@@ -0,0 +1,22 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+//go:build windows || plan9
+// +build windows plan9
+
+package utils
+
+import "os"
+
+// chown does nothing, file ownership is not kept on this system.
+func chown(f *os.File, info os.FileInfo) error {
+	return nil
+}
+
+// syncDir does nothing, directories can not be synced on this system.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/audioid/audioid/errors"
+)
+
+const testContent = "0123456789abcdefghij"
+
+func testFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "audioid")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	path := filepath.Join(dir, "test.mp3")
+	if err := ioutil.WriteFile(path, []byte(testContent), 0640); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("%+v", err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	return string(b)
+}
+
+func TestReplaceFile(t *testing.T) {
+	path, cleanup := testFile(t)
+	defer cleanup()
+
+	err := ReplaceFile(path, &WriteOptions{Backup: SuffixBackup}, func(w io.Writer, original *os.File) error {
+		_, err := io.CopyN(w, original, 10)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if s := readFile(t, path); s != testContent[:10] {
+		t.Errorf(`expected file to be "%s", but got %q`, testContent[:10], s)
+	}
+	if s := readFile(t, path+".bak"); s != testContent {
+		t.Errorf(`expected backup to be "%s", but got %q`, testContent, s)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode to be 0640, but got %#o", info.Mode().Perm())
+	}
+}
+
+func TestPatchFile(t *testing.T) {
+	path, cleanup := testFile(t)
+	defer cleanup()
+
+	dir := filepath.Join(filepath.Dir(path), "backup")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	patches := []Patch{{Offset: 2, Data: []byte("XY")}, {Offset: 12, Data: []byte("Z")}}
+	if err := PatchFile(path, 15, patches, &WriteOptions{Backup: ContentBackup, BackupDir: dir}); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if s := readFile(t, path); s != "01XY456789abZde" {
+		t.Errorf(`expected file to be "01XY456789abZde", but got %q`, s)
+	}
+	if _, err := os.Stat(journalPath(path)); !os.IsNotExist(err) {
+		t.Errorf("expected journal to be removed, but got %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(testContent))
+	backup := filepath.Join(dir, hex.EncodeToString(sum[:])+".mp3")
+	if s := readFile(t, backup); s != testContent {
+		t.Errorf(`expected backup to be "%s", but got %q`, testContent, s)
+	}
+}
+
+func TestPatchFileTimeError(t *testing.T) {
+	path, cleanup := testFile(t)
+	defer cleanup()
+
+	chtimes = func(string, time.Time, time.Time) error { return errors.New("chtimes failed") }
+	defer func() { chtimes = os.Chtimes }()
+
+	if err := PatchFile(path, -1, []Patch{{Offset: 0, Data: []byte("XY")}}, nil); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := RecoverFile(path); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if s := readFile(t, path); s != "XY"+testContent[2:] {
+		t.Errorf(`expected file to be "XY%s", but got %q`, testContent[2:], s)
+	}
+}
+
+func TestRecoverFile(t *testing.T) {
+	path, cleanup := testFile(t)
+	defer cleanup()
+
+	// Simulate a crash after the journal is written and the file is partially patched
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	patches := []Patch{{Offset: 4, Data: []byte("XXXX")}, {Offset: 18, Data: []byte("YYYY")}}
+	if err := writeJournal(path, f, int64(len(testContent)), 8, patches); err != nil {
+		f.Close()
+		t.Fatalf("%+v", err)
+	}
+	f.WriteAt(patches[1].Data, patches[1].Offset)
+	f.Truncate(8)
+	f.Close()
+
+	if err := RecoverFile(path); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if s := readFile(t, path); s != testContent {
+		t.Errorf(`expected file to be "%s", but got %q`, testContent, s)
+	}
+	if _, err := os.Stat(journalPath(path)); !os.IsNotExist(err) {
+		t.Errorf("expected journal to be removed, but got %v", err)
+	}
+}
+
+func TestRecoverFileIncomplete(t *testing.T) {
+	path, cleanup := testFile(t)
+	defer cleanup()
+
+	// The file is not modified, before the journal is complete
+	if err := ioutil.WriteFile(journalPath(path), []byte(journalMagic+"\x00\x00"), 0600); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if err := RecoverFile(path); err != nil {
+		t.Fatalf("%+v", err)
+	}
+	if s := readFile(t, path); s != testContent {
+		t.Errorf(`expected file to be "%s", but got %q`, testContent, s)
+	}
+	if _, err := os.Stat(journalPath(path)); !os.IsNotExist(err) {
+		t.Errorf("expected journal to be removed, but got %v", err)
+	}
+}
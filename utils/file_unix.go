@@ -0,0 +1,44 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package utils
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// chown sets owner and group of f to the ones of info.
+// Only the superuser may give files away, so permission errors are ignored,
+// and the file is owned by the writing user then.
+func chown(f *os.File, info os.FileInfo) error {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if err := f.Chown(int(st.Uid), int(st.Gid)); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	return nil
+}
+
+// syncDir syncs the directory, so renamed and removed entries are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap("could not open directory", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return errors.Wrap("could not sync directory", err)
+	}
+	return nil
+}
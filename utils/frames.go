@@ -0,0 +1,121 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package utils
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/audioid/audioid/errors"
+)
+
+// MaxSyncSearch limits how far from the start of the stream
+// the first frame is searched, see FrameScanner.ReadWindow.
+const MaxSyncSearch = 64 * 1024
+
+// FrameScanner finds and counts frames of streams, where each frame
+// starts with a sync byte and its header tells the frame size,
+// e.g. MPEG audio, ADTS and AC-3.
+type FrameScanner struct {
+	// Sync is the first byte of each frame.
+	Sync byte
+	// HeaderSize is the number of bytes, which Parse needs.
+	HeaderSize int
+	// Parse parses the frame header at the start of b
+	// and returns it with the size of the whole frame.
+	Parse func(b []byte) (header interface{}, size int64, err error)
+	// Matches reports whether header belongs to the stream of first.
+	Matches func(first, header interface{}) bool
+}
+
+// ReadWindow reads up to MaxSyncSearch bytes of f from start,
+// where the stream of frames starts, to end, where it ends.
+// It returns nil, if the stream is shorter than a frame header.
+func (s *FrameScanner) ReadWindow(f io.ReadSeeker, start, end int64) ([]byte, error) {
+	n := end - start
+	if n > MaxSyncSearch {
+		n = MaxSyncSearch
+	}
+	if n < int64(s.HeaderSize) {
+		return nil, nil
+	}
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return nil, errors.Wrap("could not seek to audio", err)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return nil, errors.Wrap("could not read audio", err)
+	}
+	return buf, nil
+}
+
+// Find searches buf for a frame header, which is followed
+// by another frame of the same stream, to skip false sync words.
+// streamLen is the size of the whole stream, buf is its beginning.
+// It returns nil header, if there is no such frame.
+func (s *FrameScanner) Find(buf []byte, streamLen int64) (int, interface{}) {
+	for i := 0; i+s.HeaderSize <= len(buf); i++ {
+		if buf[i] != s.Sync {
+			continue
+		}
+		h, size, err := s.Parse(buf[i:])
+		if err != nil {
+			continue
+		}
+
+		next := int64(i) + size
+		if next == streamLen {
+			return i, h
+		}
+		if next > streamLen {
+			continue
+		}
+		if next+int64(s.HeaderSize) > int64(len(buf)) {
+			// Single frame in the search window, nothing to compare with
+			if i == 0 {
+				return i, h
+			}
+			continue
+		}
+		nextHeader, _, err := s.Parse(buf[next:])
+		if err == nil && s.Matches(h, nextHeader) {
+			return i, h
+		}
+	}
+	return 0, nil
+}
+
+// Scan reads frames of f from offset up to end, while they match first,
+// and calls frame for each of them. It returns the offset after
+// the last frame, so frames stop at the first broken or foreign one.
+func (s *FrameScanner) Scan(f io.ReadSeeker, offset, end int64, first interface{}, frame func(header interface{})) (int64, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, errors.Wrap("could not seek to the first frame", err)
+	}
+
+	r := bufio.NewReaderSize(f, 64*1024)
+	pos := offset
+	b := make([]byte, s.HeaderSize)
+	for pos+int64(s.HeaderSize) <= end {
+		if _, err := io.ReadFull(r, b); err != nil {
+			break
+		}
+		h, size, err := s.Parse(b)
+		if err != nil || !s.Matches(first, h) {
+			break
+		}
+		if pos+size > end {
+			break
+		}
+		if _, err := r.Discard(int(size) - s.HeaderSize); err != nil {
+			break
+		}
+		pos += size
+		frame(h)
+	}
+	return pos, nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2019-present Audioid contributors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at https://github.com/audioid/audioid/tree/master/LICENSE
+
+package utils
+
+import "strings"
+
+// DecodeLatin1 converts ISO-8859-1 bytes into a UTF-8 string.
+// Every byte maps to the Unicode code point of the same value.
+func DecodeLatin1(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		sb.WriteRune(rune(c))
+	}
+	return sb.String()
+}
+
+// EncodeLatin1 converts a UTF-8 string into ISO-8859-1 bytes.
+// It reports false if s has characters beyond U+00FF.
+func EncodeLatin1(s string) ([]byte, bool) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return nil, false
+		}
+		b = append(b, byte(r))
+	}
+	return b, true
+}
+
+// SetPair splits "number/total" value of track and disc number fields
+// into comments with given keys. Empty parts are skipped.
+func SetPair(comments map[string]string, numberKey, totalKey, value string) {
+	parts := strings.SplitN(value, "/", 2)
+	if number := strings.TrimSpace(parts[0]); number != "" {
+		comments[numberKey] = number
+	}
+	if len(parts) == 2 {
+		if total := strings.TrimSpace(parts[1]); total != "" {
+			comments[totalKey] = total
+		}
+	}
+}
+
+// Fill sets the field to value, unless the field is already set
+// by a preferred source.
+func Fill(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// TrimFixed trims a fixed-size text field, which legacy tag formats
+// pad with NUL bytes or spaces.
+func TrimFixed(s string) string {
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimRight(s, " ")
+}